@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"connectrpc.com/connect"
+	pb "github.com/AS203038/looking-glass/protobuf/lookingglass/v0"
+	"github.com/AS203038/looking-glass/protobuf/lookingglass/v0/lookingglassconnect"
+)
+
+// handleLookupASN prints the names of the ASNs of the parameter, separated by spaces or commas and optionally prefixed with AS.
+func handleLookupASN(client lookingglassconnect.LookingGlassServiceClient) error {
+	req := &pb.LookupASNRequest{}
+	for _, f := range strings.FieldsFunc(lgRequest.Params, func(r rune) bool { return r == ' ' || r == ',' }) {
+		asn, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(f), "AS"), 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid ASN %q", f)
+		}
+		req.Asns = append(req.Asns, uint32(asn))
+	}
+	res, err := client.LookupASN(ctx, connect.NewRequest(req))
+	if err != nil {
+		return err
+	}
+	if lgRequest.UseJSON {
+		resJSON, _ := json.Marshal(res.Msg)
+		fmt.Println(string(resJSON))
+		os.Exit(0)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ASN\tNAME\tORGANIZATION\tCOUNTRY")
+	for _, as := range res.Msg.GetAsns() {
+		fmt.Fprintf(w, "AS%d\t%s\t%s\t%s\n", as.GetAsn(), as.GetName(), as.GetOrganization(), as.GetCountry())
+	}
+	w.Flush()
+	os.Exit(0)
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"connectrpc.com/connect"
+	pb "github.com/AS203038/looking-glass/protobuf/lookingglass/v0"
+	"github.com/AS203038/looking-glass/protobuf/lookingglass/v0/lookingglassconnect"
+)
+
+// handleBGPMap prints the map of the AS paths to the target of several routers as Graphviz DOT, SVG or JSON node and edge list.
+// Errors of routers are printed to stderr, so the map can be piped into dot.
+func handleBGPMap(client lookingglassconnect.LookingGlassServiceClient) error {
+	req := &pb.BGPMapRequest{
+		Target:    lgRequest.Params,
+		RouterIds: lgRequest.RouterIDs,
+		All:       lgRequest.All,
+	}
+	if len(req.RouterIds) == 0 && !req.All {
+		req.RouterIds = []int64{lgRequest.RouterID}
+	}
+	res, err := client.BGPMap(ctx, connect.NewRequest(req))
+	if err != nil {
+		return err
+	}
+	switch {
+	case lgRequest.UseJSON:
+		res.Msg.Dot, res.Msg.Svg = "", ""
+		resJSON, _ := json.Marshal(res.Msg)
+		fmt.Println(string(resJSON))
+	case lgRequest.UseSVG:
+		fmt.Print(res.Msg.GetSvg())
+	default:
+		fmt.Print(res.Msg.GetDot())
+	}
+	var ids []int64
+	for id := range res.Msg.GetErrors() {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		fmt.Fprintf(os.Stderr, "Error on router %d: %s\n", id, res.Msg.GetErrors()[id])
+	}
+	os.Exit(0)
+	return nil
+}
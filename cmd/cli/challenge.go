@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"connectrpc.com/connect"
+	"github.com/AS203038/looking-glass/pkg/pow"
+)
+
+const (
+	challengeHeader           = "X-LG-Challenge"
+	challengeIssuedHeader     = "X-LG-Challenge-Issued"
+	challengeDifficultyHeader = "X-LG-Challenge-Difficulty"
+)
+
+// challengeSolver solves the challenge the server sends with the error of a request that needs one and retries the request once.
+// The server does not require challenges from clients with a valid token.
+type challengeSolver struct{}
+
+// challengeOf returns the challenge and its difficulty sent with the error, ok is false if the error does not carry one.
+func challengeOf(err error) (challenge string, difficulty int, ok bool) {
+	var cerr *connect.Error
+	if !errors.As(err, &cerr) || cerr.Code() != connect.CodeUnauthenticated {
+		return "", 0, false
+	}
+	challenge = cerr.Meta().Get(challengeIssuedHeader)
+	difficulty, derr := strconv.Atoi(cerr.Meta().Get(challengeDifficultyHeader))
+	return challenge, difficulty, challenge != "" && derr == nil
+}
+
+func solveChallenge(ctx context.Context, challenge string, difficulty int) (string, error) {
+	fmt.Fprintf(os.Stderr, "Solving challenge of difficulty %d\n", difficulty)
+	return pow.Solve(ctx, challenge, difficulty)
+}
+
+func (challengeSolver) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		res, err := next(ctx, req)
+		challenge, difficulty, ok := challengeOf(err)
+		if !ok {
+			return res, err
+		}
+		solution, err := solveChallenge(ctx, challenge, difficulty)
+		if err != nil {
+			return nil, err
+		}
+		req.Header().Set(challengeHeader, solution)
+		return next(ctx, req)
+	}
+}
+
+func (challengeSolver) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		return &challengeConn{StreamingClientConn: next(ctx, spec), ctx: ctx, next: next}
+	}
+}
+
+func (challengeSolver) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
+
+// challengeConn retries a server stream with the solved challenge if its first response is a challenge error.
+type challengeConn struct {
+	connect.StreamingClientConn
+	ctx      context.Context
+	next     connect.StreamingClientFunc
+	msg      any
+	received bool
+}
+
+func (c *challengeConn) Send(msg any) error {
+	c.msg = msg
+	return c.StreamingClientConn.Send(msg)
+}
+
+func (c *challengeConn) Receive(msg any) error {
+	err := c.StreamingClientConn.Receive(msg)
+	if c.received {
+		return err
+	}
+	c.received = true
+	challenge, difficulty, ok := challengeOf(err)
+	if !ok || c.msg == nil {
+		return err
+	}
+	solution, err := solveChallenge(c.ctx, challenge, difficulty)
+	if err != nil {
+		return err
+	}
+	conn := c.next(c.ctx, c.Spec())
+	for k, v := range c.RequestHeader() {
+		conn.RequestHeader()[k] = v
+	}
+	conn.RequestHeader().Set(challengeHeader, solution)
+	c.StreamingClientConn.CloseResponse()
+	c.StreamingClientConn = conn
+	if err := conn.Send(c.msg); err != nil {
+		return err
+	}
+	if err := conn.CloseRequest(); err != nil {
+		return err
+	}
+	return conn.Receive(msg)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"connectrpc.com/connect"
+	pb "github.com/AS203038/looking-glass/protobuf/lookingglass/v0"
+	"github.com/AS203038/looking-glass/protobuf/lookingglass/v0/lookingglassconnect"
+)
+
+// _compareRows are the attributes of the best paths shown side by side, named like in CompareRouteResponse.differences.
+var _compareRows = []struct {
+	name  string
+	title string
+	value func(p *pb.BGPPath) string
+}{
+	{"prefix", "Prefix", func(p *pb.BGPPath) string { return p.GetPrefix() }},
+	{"next_hop", "Next Hop", func(p *pb.BGPPath) string { return p.GetNextHop() }},
+	{"as_path", "AS Path", func(p *pb.BGPPath) string {
+		var aspath []string
+		for _, asn := range p.GetAsPath() {
+			aspath = append(aspath, strconv.FormatUint(uint64(asn), 10))
+		}
+		return strings.Join(aspath, " ")
+	}},
+	{"local_pref", "Local Pref", func(p *pb.BGPPath) string { return strconv.FormatUint(uint64(p.GetLocalPref()), 10) }},
+	{"med", "MED", func(p *pb.BGPPath) string { return strconv.FormatUint(uint64(p.GetMed()), 10) }},
+	{"origin", "Origin", func(p *pb.BGPPath) string { return p.GetOrigin() }},
+	{"communities", "Communities", func(p *pb.BGPPath) string { return strings.Join(p.GetCommunities(), " ") }},
+	{"large_communities", "Large Communities", func(p *pb.BGPPath) string { return strings.Join(p.GetLargeCommunities(), " ") }},
+}
+
+// handleCompareRoute compares the best paths to the target of several routers side by side, differing attributes are marked with *.
+func handleCompareRoute(client lookingglassconnect.LookingGlassServiceClient) error {
+	req := &pb.CompareRouteRequest{
+		Target:    lgRequest.Params,
+		RouterIds: lgRequest.RouterIDs,
+		All:       lgRequest.All,
+	}
+	if len(req.RouterIds) == 0 && !req.All {
+		req.RouterIds = []int64{lgRequest.RouterID}
+	}
+	res, err := client.CompareRoute(ctx, connect.NewRequest(req))
+	if err != nil {
+		return err
+	}
+	if lgRequest.UseJSON {
+		resJSON, _ := json.Marshal(res.Msg)
+		fmt.Println(string(resJSON))
+		os.Exit(0)
+	}
+	rts, err := getRouters(client, 1)
+	if err != nil {
+		return err
+	}
+	names := make(map[int64]string, len(rts))
+	for _, rt := range rts {
+		names[rt.GetId()] = rt.GetName()
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := []string{""}
+	for _, r := range res.Msg.GetRouters() {
+		name := names[r.GetRouterId()]
+		if name == "" {
+			name = strconv.FormatInt(r.GetRouterId(), 10)
+		}
+		header = append(header, name)
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range _compareRows {
+		title := "  " + row.title
+		if slices.Contains(res.Msg.GetDifferences(), row.name) {
+			title = "* " + row.title
+		}
+		cells := []string{title}
+		for _, r := range res.Msg.GetRouters() {
+			switch {
+			case r.GetError() != "":
+				cells = append(cells, "error")
+			case r.GetMissing():
+				cells = append(cells, "no route")
+			default:
+				cells = append(cells, row.value(r.GetBest()))
+			}
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	w.Flush()
+	failed := false
+	for _, r := range res.Msg.GetRouters() {
+		if r.GetError() != "" {
+			fmt.Printf("Error on %s: %s\n", names[r.GetRouterId()], r.GetError())
+			failed = true
+		}
+	}
+	if !failed && len(res.Msg.GetRouters()) > 1 && len(res.Msg.GetDifferences()) == 0 && len(res.Msg.GetMissing()) == 0 {
+		fmt.Println("Best paths are identical")
+	}
+	os.Exit(0)
+	return nil
+}
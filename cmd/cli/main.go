@@ -9,8 +9,10 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"slices"
 	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"connectrpc.com/connect"
@@ -31,9 +33,13 @@ type LookingGlass struct {
 
 type LGRequest struct {
 	RouterID  int64
+	RouterIDs []int64 // RouterIDs queries several routers at once, all healthy ones if empty and All is set.
+	All       bool
 	Operation string
 	Params    string
 	UseJSON   bool
+	UseTable  bool
+	UseSVG    bool
 }
 
 type Return struct {
@@ -41,6 +47,15 @@ type Return struct {
 	Timestamp string `json:"timestamp"`
 }
 
+type QueryReturn struct {
+	RouterID  int64  `json:"router_id"`
+	Name      string `json:"name"`
+	Result    string `json:"result,omitempty"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Duration  string `json:"duration"`
+	Error     string `json:"error,omitempty"`
+}
+
 type RouterReturn struct {
 	ID        int64  `json:"id"`
 	Name      string `json:"name"`
@@ -53,6 +68,8 @@ var (
 	LookingGlassIndexURL = "https://raw.githubusercontent.com/AS203038/looking-glass/main/public_index.yaml"
 	lookingGlass         *LookingGlass
 	lgParam              string
+	lgToken              = os.Getenv("LG_TOKEN")
+	lgRouter             = "1"
 	lgRequest            = &LGRequest{RouterID: 1}
 	ctx                  context.Context
 	cancel               context.CancelFunc
@@ -61,10 +78,13 @@ var (
 func init() {
 	flag.StringVar(&LookingGlassIndexURL, "index", LookingGlassIndexURL, "URL of the Looking Glass index")
 	flag.StringVar(&lgParam, "lg", "", "Looking Glass name/url to query")
-	flag.Int64Var(&lgRequest.RouterID, "router", lgRequest.RouterID, "Router ID")
-	flag.StringVar(&lgRequest.Operation, "op", lgRequest.Operation, "Operation to perform: get_routers, ping, traceroute, bgp_route, bgp_community, bgp_aspath")
-	flag.StringVar(&lgRequest.Params, "param", lgRequest.Params, "Operation parameter")
+	flag.StringVar(&lgToken, "token", lgToken, "API token or JWT, defaults to the LG_TOKEN environment variable")
+	flag.StringVar(&lgRouter, "router", lgRouter, "Router ID, comma separated router IDs or all for all healthy routers")
+	flag.StringVar(&lgRequest.Operation, "op", lgRequest.Operation, "Operation to perform: get_routers, get_operations, ping, traceroute, bgp_summary, bgp_route, bgp_community, bgp_aspath, compare_route, bgp_map, lookup_asn or any operation listed by get_operations")
+	flag.StringVar(&lgRequest.Params, "param", lgRequest.Params, "Operation parameter, key=value pairs separated by spaces for operations listed by get_operations")
 	flag.BoolVar(&lgRequest.UseJSON, "json", lgRequest.UseJSON, "Output in JSON format")
+	flag.BoolVar(&lgRequest.UseTable, "table", lgRequest.UseTable, "Output parsed results as a table where supported (traceroute, bgp_route, bgp_community, bgp_aspath)")
+	flag.BoolVar(&lgRequest.UseSVG, "svg", lgRequest.UseSVG, "Output bgp_map as SVG instead of Graphviz DOT")
 	flag.Parse()
 
 	if flag.NArg() == 2 && (lgRequest.Operation == "" && lgRequest.Params == "") {
@@ -77,6 +97,10 @@ func init() {
 	}
 
 	var err error
+	if err = parseRouters(lgRouter); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(1)
+	}
 	parsedURL, err := url.Parse(lgParam)
 	if err != nil || parsedURL.Scheme == "" {
 		lookingGlass, err = getLookingGlass(lgParam)
@@ -96,11 +120,21 @@ func init() {
 }
 
 func main() {
-	client := lookingglassconnect.NewLookingGlassServiceClient(http.DefaultClient, lookingGlass.URL)
+	client := lookingglassconnect.NewLookingGlassServiceClient(http.DefaultClient, lookingGlass.URL,
+		connect.WithInterceptors(tokenInterceptor(lgToken), challengeSolver{}),
+	)
 	var ret string
 	var ts time.Time
 	var err error
 
+	if (len(lgRequest.RouterIDs) > 0 || lgRequest.All) && !slices.Contains([]string{"get_routers", "get_operations", "compare_route", "bgp_map", "lookup_asn"}, lgRequest.Operation) {
+		if err := handleQuery(client); err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	switch lgRequest.Operation {
 	case "get_routers":
 		err = handleGetRouters(client)
@@ -108,14 +142,24 @@ func main() {
 		ret, ts, err = handlePing(client)
 	case "traceroute":
 		ret, ts, err = handleTraceroute(client)
+	case "bgp_summary":
+		ret, ts, err = handleBGPSummary(client)
 	case "bgp_route":
 		ret, ts, err = handleBGPRoute(client)
 	case "bgp_community":
 		ret, ts, err = handleBGPCommunity(client)
 	case "bgp_aspath":
 		ret, ts, err = handleBGPASPath(client)
+	case "get_operations":
+		err = handleGetOperations(client)
+	case "compare_route":
+		err = handleCompareRoute(client)
+	case "bgp_map":
+		err = handleBGPMap(client)
+	case "lookup_asn":
+		err = handleLookupASN(client)
 	default:
-		err = fmt.Errorf("unknown operation: %s", lgRequest.Operation)
+		ret, ts, err = handleExecute(client)
 	}
 
 	if err != nil {
@@ -199,7 +243,166 @@ func handleGetRouters(client lookingglassconnect.LookingGlassServiceClient) erro
 	return nil
 }
 
+func handleGetOperations(client lookingglassconnect.LookingGlassServiceClient) error {
+	ops, err := client.GetOperations(ctx, connect.NewRequest(&pb.GetOperationsRequest{
+		RouterId: lgRequest.RouterID,
+	}))
+	if err != nil {
+		return err
+	}
+	for _, op := range ops.Msg.GetOperations() {
+		if lgRequest.UseJSON {
+			opJSON, _ := json.Marshal(op)
+			fmt.Println(string(opJSON))
+			continue
+		}
+		var params []string
+		for _, p := range op.GetParams() {
+			param := p.GetName() + "=<" + p.GetType() + ">"
+			if len(p.GetValues()) > 0 {
+				param = p.GetName() + "=<" + strings.Join(p.GetValues(), "|") + ">"
+			}
+			if p.GetOptional() || p.GetDefault() != "" {
+				param = "[" + param + "]"
+			}
+			params = append(params, param)
+		}
+		fmt.Printf("%s: %s %s\n", op.GetName(), op.GetDescription(), strings.Join(params, " "))
+	}
+	os.Exit(0)
+	return nil
+}
+
+// parseRouters sets the router or routers to query from the -router flag.
+func parseRouters(s string) error {
+	if s == "all" {
+		lgRequest.All = true
+		return nil
+	}
+	ids := strings.Split(s, ",")
+	for _, id := range ids {
+		n, err := strconv.ParseInt(strings.TrimSpace(id), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid router ID: %s", id)
+		}
+		lgRequest.RouterID = n
+		if len(ids) > 1 {
+			lgRequest.RouterIDs = append(lgRequest.RouterIDs, n)
+		}
+	}
+	return nil
+}
+
+// parseParams parses the parameter of operations listed by get_operations, a list of key=value pairs.
+func parseParams(s string) (map[string]string, error) {
+	params := make(map[string]string)
+	for _, kv := range strings.Fields(s) {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid parameter, expected key=value: %s", kv)
+		}
+		params[k] = v
+	}
+	return params, nil
+}
+
+// handleExecute runs any operation listed by get_operations, the parameter is a list of key=value pairs.
+func handleExecute(client lookingglassconnect.LookingGlassServiceClient) (string, time.Time, error) {
+	params, err := parseParams(lgRequest.Params)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	res, err := client.Execute(ctx, connect.NewRequest(&pb.ExecuteRequest{
+		RouterId:  lgRequest.RouterID,
+		Operation: lgRequest.Operation,
+		Params:    params,
+	}))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return string(res.Msg.GetResult()), res.Msg.Timestamp.AsTime(), nil
+}
+
+// _queryOperations maps the operations with a dedicated RPC to their name and the name of their parameter.
+var _queryOperations = map[string][2]string{
+	"ping":          {"ping", "target"},
+	"traceroute":    {"traceroute", "target"},
+	"bgp_summary":   {"bgp.summary", ""},
+	"bgp_route":     {"bgp.route", "target"},
+	"bgp_community": {"bgp.community", "community"},
+	"bgp_aspath":    {"bgp.aspath", "pattern"},
+}
+
+// handleQuery runs the operation on several routers and prints the result of each router as it arrives.
+func handleQuery(client lookingglassconnect.LookingGlassServiceClient) error {
+	op, params := lgRequest.Operation, map[string]string{}
+	if q, ok := _queryOperations[op]; ok {
+		op = q[0]
+		if q[1] != "" {
+			params[q[1]] = lgRequest.Params
+		}
+	} else {
+		var err error
+		if params, err = parseParams(lgRequest.Params); err != nil {
+			return err
+		}
+	}
+	rts, err := getRouters(client, 1)
+	if err != nil {
+		return err
+	}
+	names := make(map[int64]string, len(rts))
+	for _, rt := range rts {
+		names[rt.GetId()] = rt.GetName()
+	}
+	stream, err := client.Query(ctx, connect.NewRequest(&pb.QueryRequest{
+		Operation: op,
+		Params:    params,
+		RouterIds: lgRequest.RouterIDs,
+		All:       lgRequest.All,
+	}))
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	for stream.Receive() {
+		res := stream.Msg()
+		name := names[res.GetRouterId()]
+		duration := res.GetDuration().AsDuration().Round(time.Millisecond)
+		if lgRequest.UseJSON {
+			ret := &QueryReturn{
+				RouterID: res.GetRouterId(),
+				Name:     name,
+				Result:   string(res.GetResult()),
+				Duration: duration.String(),
+				Error:    res.GetError(),
+			}
+			if res.GetTimestamp() != nil {
+				ret.Timestamp = res.GetTimestamp().AsTime().Format(time.RFC3339)
+			}
+			retJSON, _ := json.Marshal(ret)
+			fmt.Println(string(retJSON))
+			continue
+		}
+		fmt.Printf("=== %d: %s (%s)\n", res.GetRouterId(), name, duration)
+		if res.GetError() != "" {
+			fmt.Printf("Error: %s\n", res.GetError())
+			continue
+		}
+		if lgRequest.UseTable && len(res.GetPaths()) > 0 {
+			fmt.Print(formatPaths(res.GetPaths()))
+		} else {
+			fmt.Println(strings.TrimRight(string(res.GetResult()), "\n"))
+		}
+		fmt.Println(res.GetTimestamp().AsTime().Format(time.RFC3339))
+	}
+	return stream.Err()
+}
+
 func handlePing(client lookingglassconnect.LookingGlassServiceClient) (string, time.Time, error) {
+	if !lgRequest.UseJSON {
+		return handleStreamPing(client)
+	}
 	ping, err := client.Ping(ctx, connect.NewRequest(&pb.PingRequest{
 		RouterId: lgRequest.RouterID,
 		Target:   lgRequest.Params,
@@ -211,6 +414,9 @@ func handlePing(client lookingglassconnect.LookingGlassServiceClient) (string, t
 }
 
 func handleTraceroute(client lookingglassconnect.LookingGlassServiceClient) (string, time.Time, error) {
+	if !lgRequest.UseJSON && !lgRequest.UseTable {
+		return handleStreamTraceroute(client)
+	}
 	traceroute, err := client.Traceroute(ctx, connect.NewRequest(&pb.TracerouteRequest{
 		RouterId: lgRequest.RouterID,
 		Target:   lgRequest.Params,
@@ -218,9 +424,134 @@ func handleTraceroute(client lookingglassconnect.LookingGlassServiceClient) (str
 	if err != nil {
 		return "", time.Time{}, err
 	}
+	if lgRequest.UseTable && len(traceroute.Msg.GetHops()) > 0 {
+		return formatHops(traceroute.Msg.GetHops()), traceroute.Msg.Timestamp.AsTime(), nil
+	}
 	return string(traceroute.Msg.GetResult()), traceroute.Msg.Timestamp.AsTime(), nil
 }
 
+// formatHops renders parsed traceroute hops as a table with one row per responding address.
+func formatHops(hops []*pb.Hop) string {
+	var buf strings.Builder
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HOP\tADDRESS\tHOSTNAME\tAS\tRTT\tMPLS\tMTU")
+	for _, hop := range hops {
+		infos := make(map[string]*pb.HopInfo, len(hop.GetInfo()))
+		for _, info := range hop.GetInfo() {
+			infos[info.GetAddress()] = info
+		}
+		var labels []string
+		for _, l := range hop.GetMpls() {
+			labels = append(labels, strconv.FormatUint(uint64(l.GetLabel()), 10))
+		}
+		mtu := ""
+		if hop.GetMtu() != 0 {
+			mtu = strconv.FormatUint(uint64(hop.GetMtu()), 10)
+		}
+		// Group the probes by address, timeouts are listed on their own
+		type row struct {
+			hostname string
+			rtts     []string
+		}
+		var order []string
+		rows := make(map[string]*row)
+		for _, p := range hop.GetProbes() {
+			addr := p.GetAddress()
+			if p.GetTimeout() {
+				addr = "*"
+			}
+			r, ok := rows[addr]
+			if !ok {
+				r = &row{hostname: p.GetHostname()}
+				if r.hostname == "" {
+					r.hostname = infos[addr].GetPtr()
+				}
+				rows[addr] = r
+				order = append(order, addr)
+			}
+			if !p.GetTimeout() {
+				r.rtts = append(r.rtts, fmt.Sprintf("%.3fms%s", float64(p.GetRtt().AsDuration().Microseconds())/1000, p.GetAnnotation()))
+			}
+		}
+		for i, addr := range order {
+			num := ""
+			if i == 0 {
+				num = strconv.FormatUint(uint64(hop.GetNumber()), 10)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", num, addr, rows[addr].hostname, formatHopInfo(infos[addr]), strings.Join(rows[addr].rtts, " "), strings.Join(labels, "/"), mtu)
+			labels, mtu = nil, ""
+		}
+	}
+	w.Flush()
+	return buf.String()
+}
+
+// formatHopInfo returns the origin AS and IXP of an address, e.g. "AS6695 (IXP DE-CIX Frankfurt)".
+func formatHopInfo(info *pb.HopInfo) string {
+	var ret []string
+	if info.GetAsn() != 0 {
+		ret = append(ret, fmt.Sprintf("AS%d", info.GetAsn()))
+	}
+	if info.GetIxp() {
+		ret = append(ret, strings.TrimSpace("(IXP "+info.GetIxpName())+")")
+	}
+	return strings.Join(ret, " ")
+}
+
+// handleStreamPing prints the ping output as it arrives, the returned result is always empty.
+func handleStreamPing(client lookingglassconnect.LookingGlassServiceClient) (string, time.Time, error) {
+	stream, err := client.StreamPing(ctx, connect.NewRequest(&pb.PingRequest{
+		RouterId: lgRequest.RouterID,
+		Target:   lgRequest.Params,
+	}))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer stream.Close()
+	var ts time.Time
+	for stream.Receive() {
+		if pos := stream.Msg().GetQueuePosition(); pos > 0 {
+			fmt.Fprintf(os.Stderr, "Queued at position %d\n", pos)
+			continue
+		}
+		fmt.Print(string(stream.Msg().GetResult()))
+		ts = stream.Msg().GetTimestamp().AsTime()
+	}
+	return "", ts, stream.Err()
+}
+
+// handleStreamTraceroute prints the traceroute output as it arrives, the returned result is always empty.
+func handleStreamTraceroute(client lookingglassconnect.LookingGlassServiceClient) (string, time.Time, error) {
+	stream, err := client.StreamTraceroute(ctx, connect.NewRequest(&pb.TracerouteRequest{
+		RouterId: lgRequest.RouterID,
+		Target:   lgRequest.Params,
+	}))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer stream.Close()
+	var ts time.Time
+	for stream.Receive() {
+		if pos := stream.Msg().GetQueuePosition(); pos > 0 {
+			fmt.Fprintf(os.Stderr, "Queued at position %d\n", pos)
+			continue
+		}
+		fmt.Print(string(stream.Msg().GetResult()))
+		ts = stream.Msg().GetTimestamp().AsTime()
+	}
+	return "", ts, stream.Err()
+}
+
+func handleBGPSummary(client lookingglassconnect.LookingGlassServiceClient) (string, time.Time, error) {
+	bgpSummary, err := client.BGPSummary(ctx, connect.NewRequest(&pb.BGPSummaryRequest{
+		RouterId: lgRequest.RouterID,
+	}))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return string(bgpSummary.Msg.GetResult()), bgpSummary.Msg.Timestamp.AsTime(), nil
+}
+
 func handleBGPRoute(client lookingglassconnect.LookingGlassServiceClient) (string, time.Time, error) {
 	bgpRoute, err := client.BGPRoute(ctx, connect.NewRequest(&pb.BGPRouteRequest{
 		RouterId: lgRequest.RouterID,
@@ -229,6 +560,9 @@ func handleBGPRoute(client lookingglassconnect.LookingGlassServiceClient) (strin
 	if err != nil {
 		return "", time.Time{}, err
 	}
+	if lgRequest.UseTable && len(bgpRoute.Msg.GetPaths()) > 0 {
+		return formatPaths(bgpRoute.Msg.GetPaths()), bgpRoute.Msg.Timestamp.AsTime(), nil
+	}
 	return string(bgpRoute.Msg.GetResult()), bgpRoute.Msg.Timestamp.AsTime(), nil
 }
 
@@ -255,6 +589,9 @@ func handleBGPCommunity(client lookingglassconnect.LookingGlassServiceClient) (s
 	if err != nil {
 		return "", time.Time{}, err
 	}
+	if lgRequest.UseTable && len(bgpCommunity.Msg.GetPaths()) > 0 {
+		return formatPaths(bgpCommunity.Msg.GetPaths()), bgpCommunity.Msg.Timestamp.AsTime(), nil
+	}
 	return string(bgpCommunity.Msg.GetResult()), bgpCommunity.Msg.Timestamp.AsTime(), nil
 }
 
@@ -266,9 +603,58 @@ func handleBGPASPath(client lookingglassconnect.LookingGlassServiceClient) (stri
 	if err != nil {
 		return "", time.Time{}, err
 	}
+	if lgRequest.UseTable && len(bgpASPath.Msg.GetPaths()) > 0 {
+		return formatPaths(bgpASPath.Msg.GetPaths()), bgpASPath.Msg.Timestamp.AsTime(), nil
+	}
 	return string(bgpASPath.Msg.GetResult()), bgpASPath.Msg.Timestamp.AsTime(), nil
 }
 
+// formatPaths renders parsed BGP paths as a table, the best path is marked with >.
+// The RPKI state is only shown if paths were validated, followed by why invalid paths are invalid.
+func formatPaths(paths []*pb.BGPPath) string {
+	var buf strings.Builder
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	rpki := slices.ContainsFunc(paths, func(p *pb.BGPPath) bool { return p.GetRpki() != nil })
+	header := []string{"", "PREFIX", "NEXT HOP", "AS PATH", "LP", "MED", "ORIGIN"}
+	if rpki {
+		header = append(header, "RPKI")
+	}
+	fmt.Fprintln(w, strings.Join(append(header, "COMMUNITIES"), "\t"))
+	var invalid []string
+	for _, p := range paths {
+		best := ""
+		if p.GetBest() {
+			best = ">"
+		}
+		var aspath []string
+		for _, asn := range p.GetAsPath() {
+			aspath = append(aspath, strconv.FormatUint(uint64(asn), 10))
+		}
+		row := []string{
+			best,
+			p.GetPrefix(),
+			p.GetNextHop(),
+			strings.Join(aspath, " "),
+			strconv.FormatUint(uint64(p.GetLocalPref()), 10),
+			strconv.FormatUint(uint64(p.GetMed()), 10),
+			p.GetOrigin(),
+		}
+		if rpki {
+			row = append(row, p.GetRpki().GetState())
+			if v := p.GetRpki(); v.GetState() == "Invalid" && !slices.Contains(invalid, v.GetDescription()) {
+				invalid = append(invalid, v.GetDescription())
+			}
+		}
+		row = append(row, strings.Join(append(p.GetCommunities(), p.GetLargeCommunities()...), " "))
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+	for _, d := range invalid {
+		fmt.Fprintf(&buf, "RPKI Invalid: %s\n", d)
+	}
+	return buf.String()
+}
+
 func getRouters(client lookingglassconnect.LookingGlassServiceClient, page uint32) ([]*pb.Router, error) {
 	var ret []*pb.Router
 	rts, err := client.GetRouters(ctx, connect.NewRequest(&pb.GetRoutersRequest{
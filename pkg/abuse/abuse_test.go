@@ -0,0 +1,190 @@
+package abuse
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/utils"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// testStore is a store under test, advance lets time pass for it.
+type testStore struct {
+	name    string
+	store   Store
+	advance func(time.Duration)
+}
+
+// testStores returns a memory store and a Redis store backed by miniredis, which runs the Lua script.
+func testStores(t *testing.T) []testStore {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return []testStore{
+		{"memory", NewMemory(ctx), time.Sleep},
+		{"redis", NewRedis(client), func(d time.Duration) {
+			time.Sleep(d)
+			// miniredis only expires keys when told so
+			mr.FastForward(d)
+		}},
+	}
+}
+
+func TestStoreRecord(t *testing.T) {
+	ctx := context.Background()
+	const window = 100 * time.Millisecond
+	for _, s := range testStores(t) {
+		for i := 0; i < 2; i++ {
+			if ok, _, err := s.store.Record(ctx, "192.0.2.0/24", 2, window); !ok || err != nil {
+				t.Fatalf("%s: probe #%d = %t, %v, want it recorded", s.name, i, ok, err)
+			}
+			if i == 0 {
+				s.advance(window / 2)
+			}
+		}
+		ok, retry, err := s.store.Record(ctx, "192.0.2.0/24", 2, window)
+		if ok || err != nil {
+			t.Errorf("%s: probe over the limit = %t, %v, want it denied", s.name, ok, err)
+		}
+		// The oldest probe leaves the window first
+		if retry <= 0 || retry > window/2 {
+			t.Errorf("%s: retry after %s, want up to %s", s.name, retry, window/2)
+		}
+		if ok, _, _ := s.store.Record(ctx, "198.51.100.0/24", 2, window); !ok {
+			t.Errorf("%s: probe of another destination denied", s.name)
+		}
+
+		// The window slides, only the second probe is left in it
+		s.advance(window/2 + 10*time.Millisecond)
+		if ok, _, _ := s.store.Record(ctx, "192.0.2.0/24", 2, window); !ok {
+			t.Errorf("%s: probe after the oldest left the window denied", s.name)
+		}
+		if ok, _, _ := s.store.Record(ctx, "192.0.2.0/24", 2, window); ok {
+			t.Errorf("%s: probe within the sliding window allowed", s.name)
+		}
+	}
+}
+
+func TestStoreOffend(t *testing.T) {
+	ctx := context.Background()
+	const window = 50 * time.Millisecond
+	for _, s := range testStores(t) {
+		for want := 1; want <= 3; want++ {
+			if n, err := s.store.Offend(ctx, "192.0.2.1", window); n != want || err != nil {
+				t.Errorf("%s: Offend = %d, %v, want %d", s.name, n, err, want)
+			}
+		}
+		if n, _ := s.store.Offend(ctx, "192.0.2.2", window); n != 1 {
+			t.Errorf("%s: Offend of another client = %d, want 1", s.name, n)
+		}
+		s.advance(window + 10*time.Millisecond)
+		if n, _ := s.store.Offend(ctx, "192.0.2.1", window); n != 1 {
+			t.Errorf("%s: Offend after the window = %d, want 1", s.name, n)
+		}
+	}
+}
+
+func TestStoreBlock(t *testing.T) {
+	ctx := context.Background()
+	const d = 50 * time.Millisecond
+	for _, s := range testStores(t) {
+		if left, err := s.store.Blocked(ctx, "192.0.2.1"); left != 0 || err != nil {
+			t.Errorf("%s: Blocked before blocking = %s, %v", s.name, left, err)
+		}
+		if err := s.store.Block(ctx, "192.0.2.1", d); err != nil {
+			t.Fatalf("%s: Block: %v", s.name, err)
+		}
+		if left, _ := s.store.Blocked(ctx, "192.0.2.1"); left <= 0 || left > d {
+			t.Errorf("%s: Blocked = %s, want up to %s", s.name, left, d)
+		}
+		if left, _ := s.store.Blocked(ctx, "192.0.2.2"); left != 0 {
+			t.Errorf("%s: Blocked of another client = %s", s.name, left)
+		}
+		s.advance(d + 10*time.Millisecond)
+		if left, _ := s.store.Blocked(ctx, "192.0.2.1"); left != 0 {
+			t.Errorf("%s: Blocked after the block expired = %s", s.name, left)
+		}
+	}
+}
+
+func TestGuardProbe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g := &Guard{
+		store:         NewMemory(ctx),
+		ops:           []string{"ping", "traceroute"},
+		limit:         2,
+		window:        time.Minute,
+		len4:          24,
+		len6:          48,
+		blockAfter:    2,
+		blockWindow:   time.Minute,
+		blockDuration: time.Minute,
+	}
+	tests := []struct {
+		name   string
+		op     string
+		client string
+		target string
+		want   error
+	}{
+		{"first probe", "ping", "203.0.113.1", "192.0.2.1", nil},
+		{"same /24", "traceroute", "203.0.113.2", "192.0.2.200", nil},
+		{"limit of the /24 reached", "ping", "203.0.113.1", "192.0.2.7", errs.TargetRateLimited},
+		{"other operations are not counted", "bgp.route", "203.0.113.1", "192.0.2.7", nil},
+		{"other /24", "ping", "203.0.113.3", "192.0.2.255", errs.TargetRateLimited},
+		{"next /24", "ping", "203.0.113.3", "192.0.3.1", nil},
+		{"second offense blocks", "ping", "203.0.113.1", "192.0.2.8", errs.TargetRateLimited},
+		{"blocked client", "ping", "203.0.113.1", "198.51.100.1", errs.ClientBlocked},
+		{"IPv6 /48", "ping", "2001:db8:ffff::1", "2001:db8:1:1::1", nil},
+		{"IPv6 same /48", "ping", "2001:db8:ffff::1", "2001:db8:1:ffff::1", nil},
+		{"IPv6 limit of the /48 reached", "ping", "2001:db8:ffff::1", "2001:db8:1::2", errs.TargetRateLimited},
+		{"IPv6 second offense blocks", "ping", "2001:db8:ffff::2", "2001:db8:1::3", errs.TargetRateLimited},
+		{"IPv6 clients are blocked by their /64", "ping", "2001:db8:ffff::3", "2001:db8:2::1", errs.ClientBlocked},
+		{"IPv6 other /64", "ping", "2001:db8:fffe::1", "2001:db8:2::1", nil},
+	}
+	for _, tt := range tests {
+		target, err := utils.NewIPNET(tt.target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		retry, err := g.Probe(context.Background(), tt.op, "fra1", tt.client, target)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: Probe = %v, want %v", tt.name, err, tt.want)
+		}
+		if err != nil && retry <= 0 {
+			t.Errorf("%s: Probe denied without retry time", tt.name)
+		}
+	}
+}
+
+func TestGuardNoBlock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g := &Guard{
+		store:      NewMemory(ctx),
+		ops:        []string{"ping"},
+		limit:      1,
+		window:     time.Minute,
+		len4:       24,
+		len6:       48,
+		blockAfter: -1,
+	}
+	target, _ := utils.NewIPNET("192.0.2.1")
+	for i := 0; i < 10; i++ {
+		_, err := g.Probe(context.Background(), "ping", "fra1", "203.0.113.1", target)
+		if want := errs.TargetRateLimited; i == 0 && err != nil || i > 0 && !errors.Is(err, want) {
+			t.Errorf("probe #%d = %v", i, err)
+		}
+	}
+	var disabled *Guard
+	if _, err := disabled.Probe(context.Background(), "ping", "fra1", "203.0.113.1", target); err != nil {
+		t.Errorf("Probe of disabled guard = %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package abuse
+
+import (
+	"context"
+	"log"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/ratelimit"
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+const (
+	defaultLimit         = 30
+	defaultWindow        = time.Minute
+	defaultPrefixLength4 = 24
+	defaultPrefixLength6 = 48
+	defaultBlockAfter    = 5
+	defaultBlockWindow   = 10 * time.Minute
+	defaultBlockDuration = 15 * time.Minute
+)
+
+var defaultOperations = []string{"ping", "traceroute"}
+
+// Guard limits the probes towards a destination prefix across all routers and blocks clients that keep exceeding the limit.
+type Guard struct {
+	store         Store
+	ops           []string
+	limit         int
+	window        time.Duration
+	len4          int
+	len6          int
+	blockAfter    int
+	blockWindow   time.Duration
+	blockDuration time.Duration
+	log           bool
+}
+
+// FromConfig returns the configured guard, or nil if it is disabled.
+func FromConfig(ctx context.Context, cfg *utils.Config) *Guard {
+	c := cfg.Abuse
+	if !c.Enabled {
+		return nil
+	}
+	g := &Guard{
+		ops:           c.Operations,
+		limit:         c.Limit,
+		window:        utils.ParseDuration(c.Window, defaultWindow),
+		len4:          c.PrefixLength.IPv4,
+		len6:          c.PrefixLength.IPv6,
+		blockAfter:    c.BlockAfter,
+		blockWindow:   utils.ParseDuration(c.BlockWindow, defaultBlockWindow),
+		blockDuration: utils.ParseDuration(c.BlockDuration, defaultBlockDuration),
+		log:           c.Log,
+	}
+	if len(g.ops) == 0 {
+		g.ops = defaultOperations
+	}
+	if g.limit <= 0 {
+		g.limit = defaultLimit
+	}
+	if g.window <= 0 {
+		g.window = defaultWindow
+	}
+	if g.blockWindow <= 0 {
+		g.blockWindow = defaultBlockWindow
+	}
+	if g.blockDuration <= 0 {
+		g.blockDuration = defaultBlockDuration
+	}
+	if g.len4 <= 0 || g.len4 > 32 {
+		g.len4 = defaultPrefixLength4
+	}
+	if g.len6 <= 0 || g.len6 > 128 {
+		g.len6 = defaultPrefixLength6
+	}
+	if g.blockAfter == 0 {
+		g.blockAfter = defaultBlockAfter
+	}
+	if c.Redis {
+		client, err := utils.NewRedisClient(cfg.Redis)
+		if err != nil {
+			log.Println("ERROR: Failed to configure Redis:", err, "keeping abuse protection state in memory")
+		} else {
+			g.store = NewRedis(client)
+		}
+	}
+	if g.store == nil {
+		g.store = NewMemory(ctx)
+	}
+	log.Printf("NOTICE: Limiting %v to %d probes per %s per /%d (IPv4) and /%d (IPv6) destination", g.ops, g.limit, g.window, g.len4, g.len6)
+	return g
+}
+
+// Probe records a probe of the target by the client if neither the destination prefix reached its limit nor the client is blocked.
+// Otherwise it returns errs.TargetRateLimited or errs.ClientBlocked and how long until the client may retry.
+// State that cannot be read or written does not deny probes.
+func (g *Guard) Probe(ctx context.Context, op string, router string, client string, target *utils.IPNet) (time.Duration, error) {
+	if g == nil || !slices.Contains(g.ops, op) {
+		return 0, nil
+	}
+	key := client
+	if ip := net.ParseIP(client); ip != nil {
+		key = ratelimit.ClientKey(ip)
+	}
+	if d, err := g.store.Blocked(ctx, key); err != nil {
+		log.Println("ERROR: Failed to check abuse protection block:", err)
+	} else if d > 0 {
+		return d, errs.ClientBlocked
+	}
+	dst := g.destination(target)
+	ok, retry, err := g.store.Record(ctx, dst, g.limit, g.window)
+	if err != nil {
+		log.Println("ERROR: Failed to record probe for abuse protection:", err)
+	}
+	if ok {
+		if g.log {
+			log.Printf("NOTICE: %s of %s by %s on %s", op, target.IP, client, router)
+		}
+		return 0, nil
+	}
+	log.Printf("WARNING: Denied %s of %s by %s on %s, %s reached %d probes per %s", op, target.IP, client, router, dst, g.limit, g.window)
+	if g.blockAfter < 0 {
+		return retry, errs.TargetRateLimited
+	}
+	n, err := g.store.Offend(ctx, key, g.blockWindow)
+	if err != nil {
+		log.Println("ERROR: Failed to record offense for abuse protection:", err)
+		return retry, errs.TargetRateLimited
+	}
+	if n >= g.blockAfter {
+		if err := g.store.Block(ctx, key, g.blockDuration); err != nil {
+			log.Println("ERROR: Failed to block client for abuse protection:", err)
+		} else {
+			log.Printf("WARNING: Blocked %s for %s after %d denied probes within %s", key, g.blockDuration, n, g.blockWindow)
+		}
+	}
+	return retry, errs.TargetRateLimited
+}
+
+// destination returns the prefix probes of the target are counted for.
+func (g *Guard) destination(target *utils.IPNet) string {
+	ip := target.ToIP()
+	bits, length := 128, g.len6
+	if ip4 := ip.To4(); ip4 != nil && target.IsIPv4() {
+		ip, bits, length = ip4, 32, g.len4
+	}
+	mask := net.CIDRMask(length, bits)
+	return (&net.IPNet{IP: ip.Mask(mask), Mask: mask}).String()
+}
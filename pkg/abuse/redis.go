@@ -0,0 +1,75 @@
+package abuse
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisPrefix = "lgabuse|"
+
+// _record trims the sorted set of probes to the window and adds the probe if the limit is not reached.
+// It returns 1 if the probe was recorded, otherwise the negated milliseconds until the oldest probe leaves the window.
+var _record = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+if limit > 0 and redis.call('ZCARD', KEYS[1]) >= limit then
+	local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+	return -math.max(1, tonumber(oldest[2]) + window - now)
+end
+redis.call('ZADD', KEYS[1], now, ARGV[4])
+redis.call('PEXPIRE', KEYS[1], window)
+return 1
+`)
+
+// Redis keeps the state in Redis so it is shared between replicas.
+type Redis struct {
+	client redis.UniversalClient
+}
+
+func NewRedis(client redis.UniversalClient) *Redis {
+	return &Redis{client: client}
+}
+
+func (r *Redis) add(ctx context.Context, key string, limit int, window time.Duration) (int64, error) {
+	now := time.Now().UnixMilli()
+	member := strconv.FormatInt(now, 10) + "-" + strconv.FormatInt(rand.Int63(), 36)
+	return _record.Run(ctx, r.client, []string{key}, now, window.Milliseconds(), limit, member).Int64()
+}
+
+func (r *Redis) Record(ctx context.Context, dst string, limit int, window time.Duration) (bool, time.Duration, error) {
+	ret, err := r.add(ctx, redisPrefix+"dst|"+dst, limit, window)
+	if err != nil {
+		return true, 0, err
+	}
+	if ret < 0 {
+		return false, time.Duration(-ret) * time.Millisecond, nil
+	}
+	return true, 0, nil
+}
+
+func (r *Redis) Offend(ctx context.Context, client string, window time.Duration) (int, error) {
+	key := redisPrefix + "offense|" + client
+	if _, err := r.add(ctx, key, 0, window); err != nil {
+		return 0, err
+	}
+	n, err := r.client.ZCard(ctx, key).Result()
+	return int(n), err
+}
+
+func (r *Redis) Block(ctx context.Context, client string, d time.Duration) error {
+	return r.client.Set(ctx, redisPrefix+"block|"+client, 1, d).Err()
+}
+
+func (r *Redis) Blocked(ctx context.Context, client string) (time.Duration, error) {
+	d, err := r.client.PTTL(ctx, redisPrefix+"block|"+client).Result()
+	if err != nil || d < 0 {
+		return 0, err
+	}
+	return d, nil
+}
@@ -0,0 +1,133 @@
+package abuse
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store keeps the probes of destinations and the offenses and blocks of clients.
+type Store interface {
+	// Record records a probe of the destination unless limit probes were recorded within the window.
+	// Otherwise it returns false and the time until the oldest probe leaves the window.
+	Record(ctx context.Context, dst string, limit int, window time.Duration) (bool, time.Duration, error)
+	// Offend records an offense of the client and returns the number of offenses within the window.
+	Offend(ctx context.Context, client string, window time.Duration) (int, error)
+	// Block blocks the client for the duration.
+	Block(ctx context.Context, client string, d time.Duration) error
+	// Blocked returns how long the client remains blocked, 0 if it is not blocked.
+	Blocked(ctx context.Context, client string) (time.Duration, error)
+}
+
+const sweepInterval = time.Minute
+
+// Memory keeps the state in memory, it is not shared between replicas.
+type Memory struct {
+	mu       sync.Mutex
+	probes   map[string]*window
+	offenses map[string]*window
+	blocks   map[string]time.Time
+}
+
+// window holds the events within a sliding window, the oldest first.
+type window struct {
+	events  []time.Time
+	expires time.Time
+}
+
+// add removes the events that left the window and adds an event if there are less than limit.
+func (w *window) add(now time.Time, limit int, d time.Duration) bool {
+	i := 0
+	for i < len(w.events) && !w.events[i].After(now.Add(-d)) {
+		i++
+	}
+	w.events = w.events[i:]
+	if limit > 0 && len(w.events) >= limit {
+		return false
+	}
+	w.events = append(w.events, now)
+	w.expires = now.Add(d)
+	return true
+}
+
+// NewMemory returns an in-memory store, expired state is removed until the context is done.
+func NewMemory(ctx context.Context) *Memory {
+	m := &Memory{
+		probes:   make(map[string]*window),
+		offenses: make(map[string]*window),
+		blocks:   make(map[string]time.Time),
+	}
+	go m.sweep(ctx)
+	return m
+}
+
+func (m *Memory) Record(ctx context.Context, dst string, limit int, d time.Duration) (bool, time.Duration, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w, ok := m.probes[dst]
+	if !ok {
+		w = &window{}
+		m.probes[dst] = w
+	}
+	if !w.add(now, limit, d) {
+		return false, w.events[0].Add(d).Sub(now), nil
+	}
+	return true, 0, nil
+}
+
+func (m *Memory) Offend(ctx context.Context, client string, d time.Duration) (int, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w, ok := m.offenses[client]
+	if !ok {
+		w = &window{}
+		m.offenses[client] = w
+	}
+	w.add(now, 0, d)
+	return len(w.events), nil
+}
+
+func (m *Memory) Block(ctx context.Context, client string, d time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.blocks[client] = time.Now().Add(d)
+	return nil
+}
+
+func (m *Memory) Blocked(ctx context.Context, client string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	until, ok := m.blocks[client]
+	if !ok {
+		return 0, nil
+	}
+	return max(0, time.Until(until)), nil
+}
+
+func (m *Memory) sweep(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.mu.Lock()
+			for _, ws := range []map[string]*window{m.probes, m.offenses} {
+				for k, w := range ws {
+					if now.After(w.expires) {
+						delete(ws, k)
+					}
+				}
+			}
+			for k, until := range m.blocks {
+				if now.After(until) {
+					delete(m.blocks, k)
+				}
+			}
+			m.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"slices"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+// Authenticator authenticates bearer tokens, either API tokens or JWTs of an OpenID Connect issuer.
+type Authenticator struct {
+	tokens    *Tokens
+	oidc      *OIDC
+	anonymous []string
+}
+
+// FromConfig returns the configured authenticator.
+func FromConfig(ctx context.Context, cfg utils.AuthConfig) *Authenticator {
+	a := &Authenticator{
+		tokens:    NewTokens(ctx, cfg),
+		anonymous: cfg.Anonymous.Operations,
+	}
+	oidc, err := NewOIDC(ctx, cfg.OIDC)
+	if err != nil {
+		log.Println("ERROR: Invalid OIDC configuration:", err, "rejecting JWTs")
+	}
+	a.oidc = oidc
+	if a.anonymous != nil {
+		log.Printf("NOTICE: Anonymous requests may only run %v", a.anonymous)
+	}
+	return a
+}
+
+// Authenticate returns the principal of the bearer token.
+func (a *Authenticator) Authenticate(ctx context.Context, bearer string) (*Principal, error) {
+	if a.oidc != nil && IsJWT(bearer) {
+		return a.oidc.Authenticate(ctx, bearer)
+	}
+	if a.tokens == nil {
+		return nil, errs.TokenInvalid
+	}
+	return a.tokens.Authenticate(bearer)
+}
+
+// AnonymousAllows returns whether anonymous requests may run the operation, all are allowed unless configured otherwise.
+func (a *Authenticator) AnonymousAllows(op string) bool {
+	return a.anonymous == nil || slices.Contains(a.anonymous, op)
+}
@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	jwksTimeout    = 10 * time.Second
+	jwksMaxSize    = 1 << 20
+	jwksMinRefresh = time.Minute
+)
+
+// jwks is a JSON Web Key Set read from a file or URL.
+// It is refreshed periodically and, at most once per jwksMinRefresh, when a token names an unknown key.
+type jwks struct {
+	source string
+	client *http.Client
+
+	mu      sync.RWMutex
+	keys    map[string]crypto.PublicKey // keys by ID
+	unnamed []crypto.PublicKey          // keys without ID
+	loaded  time.Time
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func newJWKS(ctx context.Context, source string, refresh time.Duration) *jwks {
+	k := &jwks{source: source, client: &http.Client{Timeout: jwksTimeout}}
+	if err := k.load(ctx); err != nil {
+		log.Println("ERROR: Failed to load JWKS:", err)
+	}
+	go k.refresh(ctx, refresh)
+	return k
+}
+
+// key returns the key with the ID, or all keys without ID if kid is empty.
+func (k *jwks) key(ctx context.Context, kid string) []crypto.PublicKey {
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	unnamed := k.unnamed
+	k.mu.RUnlock()
+	if kid == "" {
+		return unnamed
+	}
+	if ok {
+		return []crypto.PublicKey{key}
+	}
+	k.mu.Lock()
+	stale := time.Since(k.loaded) > jwksMinRefresh
+	if stale {
+		// Only one of concurrent requests reloads the keys
+		k.loaded = time.Now()
+	}
+	k.mu.Unlock()
+	if !stale {
+		return nil
+	}
+	// The issuer may have rotated its keys
+	if err := k.load(ctx); err != nil {
+		log.Println("ERROR: Failed to load JWKS:", err)
+		return nil
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if key, ok := k.keys[kid]; ok {
+		return []crypto.PublicKey{key}
+	}
+	return nil
+}
+
+func (k *jwks) refresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := k.load(ctx); err != nil {
+				log.Println("ERROR: Failed to refresh JWKS:", err)
+			}
+		}
+	}
+}
+
+// load replaces the keys with those of the source, the previous keys are kept if it cannot be read.
+func (k *jwks) load(ctx context.Context) error {
+	k.mu.Lock()
+	k.loaded = time.Now()
+	k.mu.Unlock()
+	b, err := k.read(ctx)
+	if err != nil {
+		return err
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return fmt.Errorf("%s: %w", k.source, err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	var unnamed []crypto.PublicKey
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			log.Printf("WARNING: Ignoring key %q of JWKS %s: %v", jwk.Kid, k.source, err)
+			continue
+		}
+		if jwk.Kid == "" {
+			unnamed = append(unnamed, key)
+		} else {
+			keys[jwk.Kid] = key
+		}
+	}
+	if len(keys)+len(unnamed) == 0 {
+		return fmt.Errorf("%s: no signing keys", k.source)
+	}
+	k.mu.Lock()
+	k.keys = keys
+	k.unnamed = unnamed
+	k.mu.Unlock()
+	return nil
+}
+
+func (k *jwks) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(k.source, "https://") && !strings.HasPrefix(k.source, "http://") {
+		return os.ReadFile(k.source)
+	}
+	return fetch(ctx, k.client, k.source)
+}
+
+// fetch returns the body of a successful GET request.
+func fetch(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, res.Status)
+	}
+	return io.ReadAll(io.LimitReader(res.Body, jwksMaxSize))
+}
+
+func (j *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeInt(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(j.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		var point ecdh.Curve
+		switch j.Crv {
+		case "P-256":
+			curve, point = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, point = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, point = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", j.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, errors.New("invalid EC point")
+		}
+		// Rejects points that are not on the curve
+		if _, err := point.NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", j.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", j.Kty)
+	}
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("missing key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,301 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+const (
+	defaultJWKSRefresh = time.Hour
+	defaultLeeway      = time.Minute
+	defaultRolesClaim  = "roles"
+	defaultNameClaim   = "sub"
+)
+
+// OIDC authenticates JWTs signed by the keys of an OpenID Connect issuer.
+// The roles of a token map to the operations and routers its bearer may use.
+type OIDC struct {
+	issuer     string
+	audience   []string
+	keys       *jwks
+	rolesClaim []string
+	nameClaim  string
+	leeway     time.Duration
+	roles      map[string]utils.RoleConfig
+}
+
+// _algorithms maps the supported signature algorithms to their hash.
+var _algorithms = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"PS256": crypto.SHA256,
+	"PS384": crypto.SHA384,
+	"PS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+	"EdDSA": 0,
+}
+
+// _curveBits maps the ECDSA algorithms to the size of their curve.
+var _curveBits = map[string]int{
+	"ES256": 256,
+	"ES384": 384,
+	"ES512": 521,
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// NewOIDC returns the configured issuer, or nil if it is disabled.
+// Without JWKS the keys are discovered from the OpenID configuration of the issuer.
+func NewOIDC(ctx context.Context, cfg utils.OIDCConfig) (*OIDC, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.Issuer == "" {
+		return nil, errors.New("issuer required")
+	}
+	if len(cfg.Audience) == 0 {
+		return nil, errors.New("audience required")
+	}
+	o := &OIDC{
+		issuer:     cfg.Issuer,
+		audience:   cfg.Audience,
+		rolesClaim: strings.Split(cfg.RolesClaim, "."),
+		nameClaim:  cfg.NameClaim,
+		leeway:     utils.ParseDuration(cfg.Leeway, defaultLeeway),
+		roles:      cfg.Roles,
+	}
+	if cfg.RolesClaim == "" {
+		o.rolesClaim = []string{defaultRolesClaim}
+	}
+	if o.nameClaim == "" {
+		o.nameClaim = defaultNameClaim
+	}
+	source := cfg.JWKS
+	if source == "" {
+		var err error
+		if source, err = discover(ctx, cfg.Issuer); err != nil {
+			return nil, fmt.Errorf("failed to discover JWKS: %w", err)
+		}
+	}
+	refresh := utils.ParseDuration(cfg.JWKSRefresh, defaultJWKSRefresh)
+	if refresh == 0 {
+		refresh = defaultJWKSRefresh
+	}
+	o.keys = newJWKS(ctx, source, refresh)
+	log.Printf("NOTICE: Accepting JWTs of %s for %v with keys of %s", o.issuer, o.audience, source)
+	return o, nil
+}
+
+// discover returns the JWKS URL from the OpenID configuration of the issuer.
+func discover(ctx context.Context, issuer string) (string, error) {
+	b, err := fetch(ctx, &http.Client{Timeout: jwksTimeout}, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration")
+	if err != nil {
+		return "", err
+	}
+	var c struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return "", err
+	}
+	if c.JWKSURI == "" {
+		return "", errors.New("no jwks_uri")
+	}
+	return c.JWKSURI, nil
+}
+
+// IsJWT returns whether the bearer token looks like a JWT.
+func IsJWT(bearer string) bool {
+	return strings.Count(bearer, ".") == 2
+}
+
+// Authenticate verifies the JWT and returns the principal of its roles.
+func (o *OIDC) Authenticate(ctx context.Context, bearer string) (*Principal, error) {
+	claims, err := o.verify(ctx, bearer)
+	if err != nil {
+		return nil, err
+	}
+	name, _ := claims[o.nameClaim].(string)
+	p := &Principal{Name: name}
+	var matched, allOperations, allRouters bool
+	for _, role := range o.rolesOf(claims) {
+		r, ok := o.roles[role]
+		if !ok {
+			continue
+		}
+		matched = true
+		// A role without operations or routers grants all of them
+		allOperations = allOperations || len(r.Operations) == 0
+		allRouters = allRouters || len(r.Routers) == 0
+		p.Operations = union(p.Operations, r.Operations)
+		p.Routers = union(p.Routers, r.Routers)
+		p.Hidden = p.Hidden || r.Hidden
+	}
+	if !matched {
+		return nil, errs.RoleMissing
+	}
+	if allOperations {
+		p.Operations = nil
+	}
+	if allRouters {
+		p.Routers = nil
+	}
+	return p, nil
+}
+
+// verify checks the signature, issuer, audience and lifetime of the JWT and returns its claims.
+func (o *OIDC) verify(ctx context.Context, bearer string) (map[string]any, error) {
+	parts := strings.Split(bearer, ".")
+	if len(parts) != 3 {
+		return nil, errs.TokenInvalid
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errs.TokenInvalid
+	}
+	hash, ok := _algorithms[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", errs.TokenInvalid, header.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errs.TokenInvalid
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range o.keys.key(ctx, header.Kid) {
+		if verifySignature(header.Alg, hash, key, signed, sig) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("%w: bad signature", errs.TokenInvalid)
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, errs.TokenInvalid
+	}
+	if iss, _ := claims["iss"].(string); iss != o.issuer {
+		return nil, fmt.Errorf("%w: wrong issuer", errs.TokenInvalid)
+	}
+	if !slices.ContainsFunc(stringsOf(claims["aud"]), func(aud string) bool { return slices.Contains(o.audience, aud) }) {
+		return nil, fmt.Errorf("%w: wrong audience", errs.TokenInvalid)
+	}
+	now := time.Now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("%w: no expiry", errs.TokenInvalid)
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(o.leeway)) {
+		return nil, errs.TokenExpired
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(o.leeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, fmt.Errorf("%w: not yet valid", errs.TokenInvalid)
+	}
+	return claims, nil
+}
+
+func verifySignature(alg string, hash crypto.Hash, key crypto.PublicKey, signed []byte, sig []byte) bool {
+	var digest []byte
+	if hash != 0 {
+		h := hash.New()
+		h.Write(signed)
+		digest = h.Sum(nil)
+	}
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			return rsa.VerifyPKCS1v15(k, hash, digest, sig) == nil
+		case "PS":
+			return rsa.VerifyPSS(k, hash, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		}
+	case *ecdsa.PublicKey:
+		bits := k.Curve.Params().BitSize
+		size := (bits + 7) / 8
+		if bits != _curveBits[alg] || len(sig) != 2*size {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		return ecdsa.Verify(k, digest, r, s)
+	case ed25519.PublicKey:
+		return alg == "EdDSA" && ed25519.Verify(k, signed, sig)
+	}
+	return false
+}
+
+// rolesOf returns the roles in the claim, which may be nested in objects like realm_access.roles.
+func (o *OIDC) rolesOf(claims map[string]any) []string {
+	var v any = claims
+	for _, name := range o.rolesClaim {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[name]
+	}
+	return stringsOf(v)
+}
+
+// stringsOf returns a claim that is either a string or a list of strings as list.
+func stringsOf(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var ret []string
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				ret = append(ret, s)
+			}
+		}
+		return ret
+	}
+	return nil
+}
+
+func decodeSegment(s string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func union(a []string, b []string) []string {
+	for _, s := range b {
+		if !slices.Contains(a, s) {
+			a = append(a, s)
+		}
+	}
+	sort.Strings(a)
+	return a
+}
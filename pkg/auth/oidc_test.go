@@ -0,0 +1,317 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+const testIssuer = "https://sso.example.net/realms/noc"
+
+// testKeys are the keys of the test issuer, generated once since RSA keys are slow to generate.
+var testKeys = sync.OnceValue(func() map[string]crypto.Signer {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return map[string]crypto.Signer{"rsa": rsaKey, "other": otherKey, "ec": ecKey, "ed": edKey}
+})
+
+// jwkOf returns the public JWK of the key.
+func jwkOf(kid string, key crypto.Signer) map[string]string {
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch k := key.Public().(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "kid": kid, "use": "sig", "n": b64(k.N.Bytes()), "e": b64(big.NewInt(int64(k.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		return map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": b64(k.X.FillBytes(make([]byte, size))), "y": b64(k.Y.FillBytes(make([]byte, size)))}
+	case ed25519.PublicKey:
+		return map[string]string{"kty": "OKP", "kid": kid, "crv": "Ed25519", "x": b64(k)}
+	}
+	panic("unsupported key")
+}
+
+// sign returns a JWT of the claims signed with the key.
+func sign(t *testing.T, alg string, kid string, key crypto.Signer, claims map[string]any) string {
+	t.Helper()
+	b64 := base64.RawURLEncoding.EncodeToString
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+	var sig []byte
+	var err error
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		h := crypto.SHA256.New()
+		h.Write([]byte(signed))
+		if strings.HasPrefix(alg, "PS") {
+			sig, err = rsa.SignPSS(rand.Reader, k, crypto.SHA256, h.Sum(nil), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		} else {
+			sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, h.Sum(nil))
+		}
+	case *ecdsa.PrivateKey:
+		h := crypto.SHA256.New()
+		h.Write([]byte(signed))
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, h.Sum(nil))
+		if err == nil {
+			sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(k, []byte(signed))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + b64(sig)
+}
+
+// jwksServer serves the public keys of the named test keys and counts the requests.
+type jwksServer struct {
+	*httptest.Server
+	requests atomic.Int32
+	mu       sync.Mutex
+	kids     []string
+}
+
+func newJWKSServer(t *testing.T, kids ...string) *jwksServer {
+	s := &jwksServer{kids: kids}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		var keys []map[string]string
+		for _, kid := range s.kids {
+			keys = append(keys, jwkOf(kid, testKeys()[kid]))
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) serve(kids ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.kids = kids
+}
+
+func newTestOIDC(t *testing.T, source string, cfg utils.OIDCConfig) *OIDC {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	cfg.Enabled = true
+	cfg.Issuer = testIssuer
+	cfg.Audience = []string{"looking-glass"}
+	cfg.JWKS = source
+	o, err := NewOIDC(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func TestOIDCVerify(t *testing.T) {
+	s := newJWKSServer(t, "rsa", "ec", "ed")
+	o := newTestOIDC(t, s.URL, utils.OIDCConfig{})
+	keys := testKeys()
+	now := time.Now()
+	valid := func() map[string]any {
+		return map[string]any{
+			"iss": testIssuer,
+			"aud": "looking-glass",
+			"sub": "alice",
+			"exp": now.Add(5 * time.Minute).Unix(),
+			"iat": now.Unix(),
+		}
+	}
+	with := func(name string, value any) map[string]any {
+		c := valid()
+		if value == nil {
+			delete(c, name)
+		} else {
+			c[name] = value
+		}
+		return c
+	}
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"RS256", sign(t, "RS256", "rsa", keys["rsa"], valid()), nil},
+		{"PS256", sign(t, "PS256", "rsa", keys["rsa"], valid()), nil},
+		{"ES256", sign(t, "ES256", "ec", keys["ec"], valid()), nil},
+		{"EdDSA", sign(t, "EdDSA", "ed", keys["ed"], valid()), nil},
+		{"audience list", sign(t, "RS256", "rsa", keys["rsa"], with("aud", []string{"account", "looking-glass"})), nil},
+		{"expired within leeway", sign(t, "RS256", "rsa", keys["rsa"], with("exp", now.Add(-30*time.Second).Unix())), nil},
+		{"not before within leeway", sign(t, "RS256", "rsa", keys["rsa"], with("nbf", now.Add(30*time.Second).Unix())), nil},
+		{"signed by another key", sign(t, "RS256", "rsa", keys["other"], valid()), errs.TokenInvalid},
+		{"algorithm of another key type", sign(t, "ES256", "rsa", keys["rsa"], valid()), errs.TokenInvalid},
+		{"EdDSA with the key ID of an RSA key", sign(t, "EdDSA", "rsa", keys["ed"], valid()), errs.TokenInvalid},
+		{"wrong issuer", sign(t, "RS256", "rsa", keys["rsa"], with("iss", "https://evil.example.net")), errs.TokenInvalid},
+		{"wrong audience", sign(t, "RS256", "rsa", keys["rsa"], with("aud", "account")), errs.TokenInvalid},
+		{"no audience", sign(t, "RS256", "rsa", keys["rsa"], with("aud", nil)), errs.TokenInvalid},
+		{"expired", sign(t, "RS256", "rsa", keys["rsa"], with("exp", now.Add(-2*time.Minute).Unix())), errs.TokenExpired},
+		{"no expiry", sign(t, "RS256", "rsa", keys["rsa"], with("exp", nil)), errs.TokenInvalid},
+		{"not yet valid", sign(t, "RS256", "rsa", keys["rsa"], with("nbf", now.Add(2*time.Minute).Unix())), errs.TokenInvalid},
+		{"malformed", "not.a.jwt", errs.TokenInvalid},
+	}
+	for _, tt := range tests {
+		_, err := o.verify(context.Background(), tt.token)
+		if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: verify = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestOIDCVerifyForged(t *testing.T) {
+	s := newJWKSServer(t, "rsa")
+	o := newTestOIDC(t, s.URL, utils.OIDCConfig{})
+	b64 := base64.RawURLEncoding.EncodeToString
+	token := sign(t, "RS256", "rsa", testKeys()["rsa"], map[string]any{
+		"iss": testIssuer,
+		"aud": "looking-glass",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	})
+	parts := strings.Split(token, ".")
+	payload := b64([]byte(`{"iss":"` + testIssuer + `","aud":"looking-glass","exp":9999999999,"roles":["admin"]}`))
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"changed claims", parts[0] + "." + payload + "." + parts[2]},
+		{"alg none", b64([]byte(`{"alg":"none","kid":"rsa"}`)) + "." + parts[1] + "."},
+		{"alg HS256", b64([]byte(`{"alg":"HS256","kid":"rsa"}`)) + "." + parts[1] + "." + parts[2]},
+		{"no signature", parts[0] + "." + parts[1] + "."},
+	}
+	for _, tt := range tests {
+		if _, err := o.verify(context.Background(), tt.token); !errors.Is(err, errs.TokenInvalid) {
+			t.Errorf("%s: verify = %v, want %v", tt.name, err, errs.TokenInvalid)
+		}
+	}
+}
+
+func TestOIDCRoles(t *testing.T) {
+	s := newJWKSServer(t, "rsa")
+	roles := map[string]utils.RoleConfig{
+		"noc":   {Operations: []string{"traceroute", "ping"}, Routers: []string{"fra1"}},
+		"peer":  {Operations: []string{"bgp.route"}, Routers: []string{"ams1", "fra1"}},
+		"ops":   {Routers: []string{"lab1"}, Hidden: true},
+		"admin": {},
+	}
+	tests := []struct {
+		name   string
+		claim  string
+		claims map[string]any
+		want   *Principal
+		err    error
+	}{
+		{"single role", "", map[string]any{"roles": []string{"noc"}}, &Principal{Name: "alice", Operations: []string{"ping", "traceroute"}, Routers: []string{"fra1"}}, nil},
+		{"role as string", "", map[string]any{"roles": "noc"}, &Principal{Name: "alice", Operations: []string{"ping", "traceroute"}, Routers: []string{"fra1"}}, nil},
+		{"union of roles", "", map[string]any{"roles": []string{"noc", "peer", "unknown"}}, &Principal{Name: "alice", Operations: []string{"bgp.route", "ping", "traceroute"}, Routers: []string{"ams1", "fra1"}}, nil},
+		{"role without operations grants all", "", map[string]any{"roles": []string{"noc", "ops"}}, &Principal{Name: "alice", Routers: []string{"fra1", "lab1"}, Hidden: true}, nil},
+		{"role without restrictions", "", map[string]any{"roles": []string{"admin", "noc"}}, &Principal{Name: "alice"}, nil},
+		{"nested claim", "realm_access.roles", map[string]any{"realm_access": map[string]any{"roles": []string{"peer"}}}, &Principal{Name: "alice", Operations: []string{"bgp.route"}, Routers: []string{"ams1", "fra1"}}, nil},
+		{"nested claim missing", "realm_access.roles", map[string]any{"roles": []string{"admin"}}, nil, errs.RoleMissing},
+		{"unknown role", "", map[string]any{"roles": []string{"guest"}}, nil, errs.RoleMissing},
+		{"no roles", "", map[string]any{}, nil, errs.RoleMissing},
+	}
+	for _, tt := range tests {
+		o := newTestOIDC(t, s.URL, utils.OIDCConfig{RolesClaim: tt.claim, Roles: roles})
+		claims := map[string]any{
+			"iss": testIssuer,
+			"aud": "looking-glass",
+			"sub": "alice",
+			"exp": time.Now().Add(time.Minute).Unix(),
+		}
+		for k, v := range tt.claims {
+			claims[k] = v
+		}
+		p, err := o.Authenticate(context.Background(), sign(t, "RS256", "rsa", testKeys()["rsa"], claims))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: Authenticate = %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if !reflect.DeepEqual(p, tt.want) {
+			t.Errorf("%s: Authenticate = %+v, want %+v", tt.name, p, tt.want)
+		}
+	}
+}
+
+func TestJWKSRefreshThrottle(t *testing.T) {
+	s := newJWKSServer(t, "rsa")
+	o := newTestOIDC(t, s.URL, utils.OIDCConfig{})
+	claims := map[string]any{"iss": testIssuer, "aud": "looking-glass", "exp": time.Now().Add(time.Minute).Unix()}
+	if n := s.requests.Load(); n != 1 {
+		t.Fatalf("%d JWKS requests after start, want 1", n)
+	}
+
+	// An unknown key is only looked up if the keys were not loaded within jwksMinRefresh
+	token := sign(t, "ES256", "ec", testKeys()["ec"], claims)
+	if _, err := o.verify(context.Background(), token); !errors.Is(err, errs.TokenInvalid) {
+		t.Errorf("verify with unknown key = %v, want %v", err, errs.TokenInvalid)
+	}
+	if n := s.requests.Load(); n != 1 {
+		t.Errorf("%d JWKS requests after an unknown key right after loading, want 1", n)
+	}
+
+	// The issuer rotated its keys
+	s.serve("ec")
+	o.keys.mu.Lock()
+	o.keys.loaded = time.Now().Add(-2 * jwksMinRefresh)
+	o.keys.mu.Unlock()
+	for i := 0; i < 3; i++ {
+		if _, err := o.verify(context.Background(), token); err != nil {
+			t.Errorf("verify with rotated key #%d = %v", i, err)
+		}
+	}
+	if n := s.requests.Load(); n != 2 {
+		t.Errorf("%d JWKS requests after the rotation, want 2", n)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := o.verify(context.Background(), sign(t, "EdDSA", "ed", testKeys()["ed"], claims)); !errors.Is(err, errs.TokenInvalid) {
+			t.Errorf("verify with unknown key #%d = %v, want %v", i, err, errs.TokenInvalid)
+		}
+	}
+	if n := s.requests.Load(); n != 2 {
+		t.Errorf("%d JWKS requests after repeated unknown keys, want 2", n)
+	}
+
+	// Keys of the previous set are gone
+	if _, err := o.verify(context.Background(), sign(t, "RS256", "rsa", testKeys()["rsa"], claims)); !errors.Is(err, errs.TokenInvalid) {
+		t.Errorf("verify with removed key = %v, want %v", err, errs.TokenInvalid)
+	}
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/ratelimit"
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+// Principal is the identity a request is authorized as, requests without one are anonymous.
+// Anonymous requests may run all operations on all routers that are not hidden.
+type Principal struct {
+	Name       string
+	Operations []string // Operations the principal may run, all if empty.
+	Routers    []string // Routers the principal may use, all if empty.
+	Hidden     bool     // Hidden is whether hidden routers are visible.
+
+	// Limiter replaces the rate limits of the client, unless nil. Unlimited disables them.
+	Limiter   *ratelimit.Limiter
+	Unlimited bool
+
+	quota *quota
+}
+
+// AllowsOperation returns whether the principal may run the operation, p may be nil.
+func (p *Principal) AllowsOperation(op string) bool {
+	return p == nil || len(p.Operations) == 0 || slices.Contains(p.Operations, op)
+}
+
+// AllowsRouter returns whether the principal may use the router, p may be nil.
+func (p *Principal) AllowsRouter(cfg *utils.RouterConfig) bool {
+	if cfg.Hidden && (p == nil || !p.Hidden) {
+		return false
+	}
+	return p == nil || len(p.Routers) == 0 || slices.Contains(p.Routers, cfg.Name)
+}
+
+// Use counts an operation run on n routers against the quota of the principal.
+// It returns errs.QuotaExceeded and the time until the quota resets if too little of it is left.
+func (p *Principal) Use(n int) (time.Duration, error) {
+	if p == nil || p.quota == nil {
+		return 0, nil
+	}
+	return p.quota.use(n)
+}
+
+// quota allows limit operations per period, the period starts with the first operation.
+type quota struct {
+	limit  int
+	period time.Duration
+
+	mu    sync.Mutex
+	used  int
+	reset time.Time
+}
+
+func (q *quota) use(n int) (time.Duration, error) {
+	now := time.Now()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if now.After(q.reset) {
+		q.used = 0
+		q.reset = now.Add(q.period)
+	}
+	if q.used+n > q.limit {
+		return q.reset.Sub(now), errs.QuotaExceeded
+	}
+	q.used += n
+	return 0, nil
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the principal.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal of the request, nil if it is anonymous.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
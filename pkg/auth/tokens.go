@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/ratelimit"
+	"github.com/AS203038/looking-glass/pkg/utils"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	defaultQuotaPeriod   = 24 * time.Hour
+	defaultRateInterval  = time.Minute
+	tokenFileCheckPeriod = 30 * time.Second
+)
+
+// Tokens authenticates API tokens from the configuration and a token file.
+// The token file is reloaded when it changes, quotas and rate limits of unchanged tokens are kept.
+type Tokens struct {
+	file string
+
+	mu     sync.RWMutex
+	tokens map[string]*token // tokens by SHA-256 hash
+	static map[string]*token
+	mtime  time.Time
+	cancel context.CancelFunc
+}
+
+type token struct {
+	principal *Principal
+	expires   time.Time
+}
+
+// tokenFile is the format of the token file, the same as the auth section of the configuration.
+type tokenFile struct {
+	Tokens []utils.TokenConfig `yaml:"tokens"`
+}
+
+// NewTokens returns the configured tokens, or nil if there are none.
+func NewTokens(ctx context.Context, cfg utils.AuthConfig) *Tokens {
+	if len(cfg.Tokens) == 0 && cfg.TokenFile == "" {
+		return nil
+	}
+	t := &Tokens{file: cfg.TokenFile}
+	static, err := newTokens(ctx, cfg.Tokens)
+	if err != nil {
+		log.Println("ERROR: Invalid token:", err, "ignoring tokens of the configuration")
+	}
+	t.static = static
+	t.tokens = static
+	if t.file != "" {
+		t.reload(ctx)
+		go t.watch(ctx)
+	}
+	log.Printf("NOTICE: Loaded %d API tokens", len(t.tokens))
+	return t
+}
+
+// Authenticate returns the principal of the bearer token.
+func (t *Tokens) Authenticate(bearer string) (*Principal, error) {
+	sum := sha256.Sum256([]byte(bearer))
+	t.mu.RLock()
+	tok, ok := t.tokens[hex.EncodeToString(sum[:])]
+	t.mu.RUnlock()
+	if !ok {
+		return nil, errs.TokenInvalid
+	}
+	if !tok.expires.IsZero() && time.Now().After(tok.expires) {
+		return nil, errs.TokenExpired
+	}
+	return tok.principal, nil
+}
+
+func (t *Tokens) watch(ctx context.Context) {
+	ticker := time.NewTicker(tokenFileCheckPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.reload(ctx)
+		}
+	}
+}
+
+// reload loads the token file if it changed, the previous tokens are kept if it is invalid.
+func (t *Tokens) reload(ctx context.Context) {
+	fi, err := os.Stat(t.file)
+	if err != nil {
+		log.Println("ERROR: Failed to read token file:", err)
+		return
+	}
+	if fi.ModTime().Equal(t.mtime) {
+		return
+	}
+	t.mtime = fi.ModTime()
+	b, err := os.ReadFile(t.file)
+	if err != nil {
+		log.Println("ERROR: Failed to read token file:", err)
+		return
+	}
+	var tf tokenFile
+	if err := yaml.Unmarshal(b, &tf); err != nil {
+		log.Println("ERROR: Failed to parse token file:", err)
+		return
+	}
+	fctx, cancel := context.WithCancel(ctx)
+	loaded, err := newTokens(fctx, tf.Tokens)
+	if err != nil {
+		cancel()
+		log.Println("ERROR: Invalid token in token file:", err)
+		return
+	}
+	tokens := make(map[string]*token, len(t.static)+len(loaded))
+	for h, tok := range loaded {
+		tokens[h] = tok
+	}
+	for h, tok := range t.static {
+		tokens[h] = tok
+	}
+
+	t.mu.Lock()
+	for h, tok := range loaded {
+		old, ok := t.tokens[h]
+		if !ok {
+			continue
+		}
+		if old.principal.quota != nil && tok.principal.quota != nil {
+			old.principal.quota.mu.Lock()
+			tok.principal.quota.used = old.principal.quota.used
+			tok.principal.quota.reset = old.principal.quota.reset
+			old.principal.quota.mu.Unlock()
+		}
+		if old.principal.Limiter != nil && tok.principal.Limiter != nil {
+			tok.principal.Limiter.CopyFrom(old.principal.Limiter)
+		}
+	}
+	t.tokens = tokens
+	prev := t.cancel
+	t.cancel = cancel
+	t.mu.Unlock()
+	if prev != nil {
+		prev()
+	}
+	log.Printf("NOTICE: Loaded %d API tokens from %s", len(loaded), t.file)
+}
+
+func newTokens(ctx context.Context, cfgs []utils.TokenConfig) (map[string]*token, error) {
+	tokens := make(map[string]*token, len(cfgs))
+	for _, c := range cfgs {
+		h, tok, err := newToken(ctx, c)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.Name, err)
+		}
+		tokens[h] = tok
+	}
+	return tokens, nil
+}
+
+// newToken returns the token and its SHA-256 hash, either given or computed from the plain token.
+func newToken(ctx context.Context, c utils.TokenConfig) (string, *token, error) {
+	h := strings.ToLower(c.SHA256)
+	if c.Token != "" {
+		sum := sha256.Sum256([]byte(c.Token))
+		h = hex.EncodeToString(sum[:])
+	}
+	if b, err := hex.DecodeString(h); err != nil || len(b) != sha256.Size {
+		return "", nil, errors.New("token or sha256 required")
+	}
+	tok := &token{principal: &Principal{
+		Name:       c.Name,
+		Operations: c.Operations,
+		Routers:    c.Routers,
+		Hidden:     c.Hidden,
+	}}
+	if c.Expires != "" {
+		exp, err := time.Parse(time.RFC3339, c.Expires)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid expiry: %w", err)
+		}
+		tok.expires = exp
+	}
+	if c.Quota > 0 {
+		period := utils.ParseDuration(c.QuotaPeriod, defaultQuotaPeriod)
+		if period == 0 {
+			period = defaultQuotaPeriod
+		}
+		tok.principal.quota = &quota{limit: c.Quota, period: period}
+	}
+	if rl := c.RateLimit; rl != nil {
+		if rl.Rate <= 0 {
+			tok.principal.Unlimited = true
+		} else {
+			interval := utils.ParseDuration(rl.Interval, defaultRateInterval)
+			if interval == 0 {
+				interval = defaultRateInterval
+			}
+			tok.principal.Limiter = ratelimit.New(ctx, rl.Rate, interval, rl.Burst)
+		}
+	}
+	return h, tok, nil
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+func TestTokensAuthenticate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sum := sha256.Sum256([]byte("hashed-secret"))
+	tokens := NewTokens(ctx, utils.AuthConfig{Tokens: []utils.TokenConfig{
+		{Name: "plain", Token: "plain-secret"},
+		{Name: "hashed", SHA256: hex.EncodeToString(sum[:])},
+		{Name: "expired", Token: "expired-secret", Expires: "2020-01-01T00:00:00Z"},
+		{Name: "expiring", Token: "expiring-secret", Expires: time.Now().Add(time.Hour).Format(time.RFC3339)},
+	}})
+	tests := []struct {
+		bearer string
+		name   string
+		err    error
+	}{
+		{"plain-secret", "plain", nil},
+		{"hashed-secret", "hashed", nil},
+		{"expiring-secret", "expiring", nil},
+		{"expired-secret", "", errs.TokenExpired},
+		{"unknown-secret", "", errs.TokenInvalid},
+		{hex.EncodeToString(sum[:]), "", errs.TokenInvalid},
+		{"", "", errs.TokenInvalid},
+	}
+	for _, tt := range tests {
+		p, err := tokens.Authenticate(tt.bearer)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Authenticate(%q) = %v, want %v", tt.bearer, err, tt.err)
+			continue
+		}
+		if err == nil && p.Name != tt.name {
+			t.Errorf("Authenticate(%q) = %s, want %s", tt.bearer, p.Name, tt.name)
+		}
+	}
+}
+
+func TestNewTokenInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  utils.TokenConfig
+	}{
+		{"no secret", utils.TokenConfig{Name: "none"}},
+		{"short hash", utils.TokenConfig{Name: "short", SHA256: "abcdef"}},
+		{"invalid expiry", utils.TokenConfig{Name: "expiry", Token: "secret", Expires: "tomorrow"}},
+	}
+	for _, tt := range tests {
+		if _, _, err := newToken(context.Background(), tt.cfg); err == nil {
+			t.Errorf("%s: newToken succeeded", tt.name)
+		}
+	}
+}
+
+func TestPrincipalScopes(t *testing.T) {
+	p := &Principal{Operations: []string{"ping", "bgp.route"}, Routers: []string{"fra1"}}
+	tests := []struct {
+		principal *Principal
+		op        string
+		router    *utils.RouterConfig
+		opOK      bool
+		routerOK  bool
+	}{
+		{p, "ping", &utils.RouterConfig{Name: "fra1"}, true, true},
+		{p, "traceroute", &utils.RouterConfig{Name: "ams1"}, false, false},
+		{p, "bgp.route", &utils.RouterConfig{Name: "fra1", Hidden: true}, true, false},
+		{&Principal{Hidden: true}, "traceroute", &utils.RouterConfig{Name: "lab1", Hidden: true}, true, true},
+		{&Principal{Routers: []string{"fra1"}, Hidden: true}, "traceroute", &utils.RouterConfig{Name: "lab1", Hidden: true}, true, false},
+		{nil, "traceroute", &utils.RouterConfig{Name: "ams1"}, true, true},
+		{nil, "traceroute", &utils.RouterConfig{Name: "lab1", Hidden: true}, true, false},
+	}
+	for _, tt := range tests {
+		if got := tt.principal.AllowsOperation(tt.op); got != tt.opOK {
+			t.Errorf("%+v: AllowsOperation(%s) = %t, want %t", tt.principal, tt.op, got, tt.opOK)
+		}
+		if got := tt.principal.AllowsRouter(tt.router); got != tt.routerOK {
+			t.Errorf("%+v: AllowsRouter(%s) = %t, want %t", tt.principal, tt.router.Name, got, tt.routerOK)
+		}
+	}
+}
+
+func TestPrincipalUse(t *testing.T) {
+	tests := []struct {
+		name  string
+		quota *quota
+		n     []int
+		want  []error
+	}{
+		{"within quota", &quota{limit: 5, period: time.Hour}, []int{1, 2, 2}, []error{nil, nil, nil}},
+		{"exceeded", &quota{limit: 3, period: time.Hour}, []int{2, 2, 1, 1}, []error{nil, errs.QuotaExceeded, nil, errs.QuotaExceeded}},
+		{"fan-out larger than quota", &quota{limit: 3, period: time.Hour}, []int{4, 3}, []error{errs.QuotaExceeded, nil}},
+		{"unlimited", nil, []int{100, 100}, []error{nil, nil}},
+	}
+	for _, tt := range tests {
+		p := &Principal{quota: tt.quota}
+		for i, n := range tt.n {
+			wait, err := p.Use(n)
+			if !errors.Is(err, tt.want[i]) {
+				t.Errorf("%s: Use(%d) #%d = %v, want %v", tt.name, n, i, err, tt.want[i])
+			}
+			if err != nil && (wait <= 0 || wait > time.Hour) {
+				t.Errorf("%s: Use(%d) #%d waits %s, want up to an hour", tt.name, n, i, wait)
+			}
+		}
+	}
+	var anonymous *Principal
+	if _, err := anonymous.Use(1000); err != nil {
+		t.Errorf("Use of anonymous principal = %v", err)
+	}
+
+	// The quota resets once the period that started with the first operation is over
+	p := &Principal{quota: &quota{limit: 1, period: 20 * time.Millisecond}}
+	p.Use(1)
+	if _, err := p.Use(1); !errors.Is(err, errs.QuotaExceeded) {
+		t.Errorf("Use within period = %v, want %v", err, errs.QuotaExceeded)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, err := p.Use(1); err != nil {
+		t.Errorf("Use after period = %v", err)
+	}
+}
+
+func TestTokensReload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	file := filepath.Join(t.TempDir(), "tokens.yaml")
+	write := func(content string, mtime time.Time) {
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		// The file is only reloaded if its modification time changed
+		if err := os.Chtimes(file, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	write(`tokens:
+  - name: noc
+    token: noc-secret
+    quota: 3
+    ratelimit:
+      rate: 2
+      interval: 1h
+  - name: old
+    token: old-secret
+`, now.Add(-time.Hour))
+	tokens := NewTokens(ctx, utils.AuthConfig{
+		Tokens:    []utils.TokenConfig{{Name: "static", Token: "static-secret"}},
+		TokenFile: file,
+	})
+
+	noc, err := tokens.Authenticate("noc-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := noc.Use(2); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if ok, _ := noc.Limiter.Allow("192.0.2.1"); !ok {
+			t.Fatalf("request #%d of the token denied", i)
+		}
+	}
+
+	write(`tokens:
+  - name: noc
+    token: noc-secret
+    quota: 3
+    ratelimit:
+      rate: 2
+      interval: 1h
+  - name: new
+    token: new-secret
+`, now)
+	tokens.reload(ctx)
+	reloaded, err := tokens.Authenticate("noc-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded == noc {
+		t.Fatal("token was not reloaded")
+	}
+	if _, err := reloaded.Use(2); !errors.Is(err, errs.QuotaExceeded) {
+		t.Errorf("Use after reload = %v, want the quota to be carried over", err)
+	}
+	if ok, _ := reloaded.Limiter.Allow("192.0.2.1"); ok {
+		t.Error("rate limit was reset by the reload")
+	}
+	if ok, _ := reloaded.Limiter.Allow("192.0.2.2"); !ok {
+		t.Error("rate limit of another client denied after reload")
+	}
+	for bearer, want := range map[string]error{"new-secret": nil, "static-secret": nil, "old-secret": errs.TokenInvalid} {
+		if _, err := tokens.Authenticate(bearer); !errors.Is(err, want) {
+			t.Errorf("Authenticate(%s) after reload = %v, want %v", bearer, err, want)
+		}
+	}
+
+	// An invalid file keeps the previous tokens
+	write("tokens:\n  - name: broken\n", now.Add(time.Hour))
+	tokens.reload(ctx)
+	if p, err := tokens.Authenticate("noc-secret"); err != nil || p != reloaded {
+		t.Errorf("Authenticate after invalid reload = %v, %v, want the previous token", p, err)
+	}
+}
@@ -0,0 +1,66 @@
+package bgpmap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Fill colors by node type, origin ASes are highlighted
+const (
+	colorRouter = "#cfe2ff"
+	colorAS     = "#ffffff"
+	colorOrigin = "#d1e7dd"
+	colorPrefix = "#fff3cd"
+	colorBest   = "#dc3545"
+	colorEdge   = "#6c757d"
+)
+
+func (n *Node) fill() string {
+	switch {
+	case n.Type == NodeRouter:
+		return colorRouter
+	case n.Type == NodePrefix:
+		return colorPrefix
+	case n.Origin:
+		return colorOrigin
+	}
+	return colorAS
+}
+
+// DOT renders the graph in the Graphviz DOT language, best paths are drawn bold and red.
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph bgpmap {\n")
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=box, style=\"rounded,filled\", fontname=\"sans-serif\"];\n")
+	b.WriteString("\tedge [fontname=\"sans-serif\", fontsize=10];\n")
+	for _, n := range g.Nodes {
+		shape := ""
+		if n.Type == NodePrefix {
+			shape = ", shape=note"
+		}
+		label := n.Label
+		if n.Name != "" {
+			label += "\n" + n.Name
+		}
+		fmt.Fprintf(&b, "\t%s [label=%s, fillcolor=%q%s];\n", quote(n.ID), quote(label), n.fill(), shape)
+	}
+	for _, e := range g.Edges {
+		attrs := []string{"color=" + quote(colorEdge)}
+		if e.Best {
+			attrs = []string{"color=" + quote(colorBest), "penwidth=2"}
+		}
+		if e.Prepends > 0 {
+			attrs = append(attrs, fmt.Sprintf("label=\"+%d\"", e.Prepends))
+		}
+		attrs = append(attrs, "tooltip="+quote(strings.Join(e.Routers, ", ")))
+		fmt.Fprintf(&b, "\t%s -> %s [%s];\n", quote(e.From), quote(e.To), strings.Join(attrs, ", "))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// quote returns s as DOT string.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
@@ -0,0 +1,32 @@
+package bgpmap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDOT(t *testing.T) {
+	g := &Graph{
+		Nodes: []*Node{
+			{ID: `router:lab "1"`, Label: `lab "1"`, Type: NodeRouter},
+			{ID: "as:65001", Label: "AS65001", Type: NodeAS, Origin: true, Name: `EXAMPLE\NET`},
+			{ID: "prefix:192.0.2.0/24", Label: "192.0.2.0/24", Type: NodePrefix},
+		},
+		Edges: []*Edge{
+			{From: `router:lab "1"`, To: "as:65001", Routers: []string{`lab "1"`}, Best: true, Prepends: 2},
+			{From: "as:65001", To: "prefix:192.0.2.0/24", Routers: []string{`lab "1"`, "fra1"}},
+		},
+	}
+	dot := g.DOT()
+	for _, want := range []string{
+		`"router:lab \"1\"" [label="lab \"1\"", fillcolor="#cfe2ff"];`,
+		`"as:65001" [label="AS65001\nEXAMPLE\\NET", fillcolor="#d1e7dd"];`,
+		`"prefix:192.0.2.0/24" [label="192.0.2.0/24", fillcolor="#fff3cd", shape=note];`,
+		`"router:lab \"1\"" -> "as:65001" [color="#dc3545", penwidth=2, label="+2", tooltip="lab \"1\""];`,
+		`"as:65001" -> "prefix:192.0.2.0/24" [color="#6c757d", tooltip="lab \"1\", fra1"];`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT lacks %s:\n%s", want, dot)
+		}
+	}
+}
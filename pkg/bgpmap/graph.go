@@ -0,0 +1,128 @@
+package bgpmap
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+// Node types
+const (
+	NodeRouter = "router"
+	NodeAS     = "as"
+	NodePrefix = "prefix"
+)
+
+// Graph is the directed graph of the AS paths towards a target as seen from routers.
+// Paths lead from the router over the ASNs to the origin AS and the prefix, prepends are collapsed.
+type Graph struct {
+	Nodes []*Node `json:"nodes"`
+	Edges []*Edge `json:"edges"`
+}
+
+type Node struct {
+	ID     string `json:"id"`
+	Label  string `json:"label"`
+	Type   string `json:"type"`
+	Origin bool   `json:"origin,omitempty"` // Origin is whether the AS originates a prefix.
+	Name   string `json:"name,omitempty"`   // Name is the name of the AS, if known.
+}
+
+type Edge struct {
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Routers  []string `json:"routers"`            // Routers is the names of the routers whose paths use the edge.
+	Best     bool     `json:"best,omitempty"`     // Best is whether a best path uses the edge.
+	Prepends int      `json:"prepends,omitempty"` // Prepends is the most times the AS the edge leads to was prepended.
+}
+
+// Build returns the graph of the paths of each router, identical graphs are built regardless of the order of paths.
+// ASes are named by name, which may be nil.
+func Build(routes map[string][]*utils.BGPPath, name func(asn uint32) string) *Graph {
+	nodes := make(map[string]*Node)
+	edges := make(map[[2]string]*Edge)
+	node := func(id, label, typ string) *Node {
+		n, ok := nodes[id]
+		if !ok {
+			n = &Node{ID: id, Label: label, Type: typ}
+			nodes[id] = n
+		}
+		return n
+	}
+	edge := func(from, to, router string, best bool, prepends int) {
+		e, ok := edges[[2]string{from, to}]
+		if !ok {
+			e = &Edge{From: from, To: to}
+			edges[[2]string{from, to}] = e
+		}
+		if i := sort.SearchStrings(e.Routers, router); i == len(e.Routers) || e.Routers[i] != router {
+			e.Routers = append(e.Routers, "")
+			copy(e.Routers[i+1:], e.Routers[i:])
+			e.Routers[i] = router
+		}
+		e.Best = e.Best || best
+		e.Prepends = max(e.Prepends, prepends)
+	}
+
+	for router, paths := range routes {
+		from := node("router:"+router, router, NodeRouter).ID
+		for _, p := range paths {
+			prev := from
+			for i := 0; i < len(p.ASPath); {
+				asn := p.ASPath[i]
+				// Collapse prepends into one hop
+				n := 1
+				for i+n < len(p.ASPath) && p.ASPath[i+n] == asn {
+					n++
+				}
+				i += n
+				as := node("as:"+strconv.FormatUint(uint64(asn), 10), "AS"+strconv.FormatUint(uint64(asn), 10), NodeAS)
+				if name != nil && as.Name == "" {
+					as.Name = name(asn)
+				}
+				edge(prev, as.ID, router, p.Best, n-1)
+				prev = as.ID
+			}
+			if prev != from {
+				nodes[prev].Origin = true
+			}
+			if p.Prefix != "" {
+				edge(prev, node("prefix:"+p.Prefix, p.Prefix, NodePrefix).ID, router, p.Best, 0)
+			}
+		}
+	}
+
+	g := &Graph{}
+	for _, n := range nodes {
+		g.Nodes = append(g.Nodes, n)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		a, b := g.Nodes[i], g.Nodes[j]
+		if a.Type != b.Type {
+			return typeOrder(a.Type) < typeOrder(b.Type)
+		}
+		return a.ID < b.ID
+	})
+	for _, e := range edges {
+		g.Edges = append(g.Edges, e)
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	return g
+}
+
+func typeOrder(t string) int {
+	switch t {
+	case NodeRouter:
+		return 0
+	case NodeAS:
+		return 1
+	}
+	return 2
+}
@@ -0,0 +1,78 @@
+package bgpmap
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+func TestBuild(t *testing.T) {
+	routes := map[string][]*utils.BGPPath{
+		"fra1": {
+			{Prefix: "192.0.2.0/24", ASPath: []uint32{65001, 65001, 65001, 65003}, Best: true},
+			{Prefix: "192.0.2.0/24", ASPath: []uint32{65002, 65003, 65003}},
+		},
+		"ams1": {
+			{Prefix: "192.0.2.0/24", ASPath: []uint32{65002, 65003}, Best: true},
+		},
+	}
+	names := map[uint32]string{65003: "ORIGIN-AS"}
+	g := Build(routes, func(asn uint32) string { return names[asn] })
+
+	var nodes []string
+	for _, n := range g.Nodes {
+		nodes = append(nodes, n.ID)
+	}
+	if want := []string{"router:ams1", "router:fra1", "as:65001", "as:65002", "as:65003", "prefix:192.0.2.0/24"}; !reflect.DeepEqual(nodes, want) {
+		t.Errorf("nodes = %q, want %q", nodes, want)
+	}
+	for _, n := range g.Nodes {
+		if origin := n.ID == "as:65003"; n.Origin != origin {
+			t.Errorf("%s: origin = %t, want %t", n.ID, n.Origin, origin)
+		}
+		if want := names[65003]; n.ID == "as:65003" && n.Name != want {
+			t.Errorf("%s: name = %q, want %q", n.ID, n.Name, want)
+		}
+	}
+
+	want := []*Edge{
+		{From: "as:65001", To: "as:65003", Routers: []string{"fra1"}, Best: true},
+		{From: "as:65002", To: "as:65003", Routers: []string{"ams1", "fra1"}, Best: true, Prepends: 1},
+		{From: "as:65003", To: "prefix:192.0.2.0/24", Routers: []string{"ams1", "fra1"}, Best: true},
+		{From: "router:ams1", To: "as:65002", Routers: []string{"ams1"}, Best: true},
+		{From: "router:fra1", To: "as:65001", Routers: []string{"fra1"}, Best: true, Prepends: 2},
+		{From: "router:fra1", To: "as:65002", Routers: []string{"fra1"}},
+	}
+	if len(g.Edges) != len(want) {
+		t.Fatalf("%d edges, want %d", len(g.Edges), len(want))
+	}
+	for i, e := range g.Edges {
+		if !reflect.DeepEqual(e, want[i]) {
+			t.Errorf("edge #%d = %+v, want %+v", i, e, want[i])
+		}
+	}
+}
+
+func TestBuildOrder(t *testing.T) {
+	a := &utils.BGPPath{Prefix: "2001:db8::/32", ASPath: []uint32{65001, 65002}, Best: true}
+	b := &utils.BGPPath{Prefix: "2001:db8::/32", ASPath: []uint32{65003, 65002}}
+	g1 := Build(map[string][]*utils.BGPPath{"fra1": {a, b}, "ams1": {b}}, nil)
+	g2 := Build(map[string][]*utils.BGPPath{"ams1": {b}, "fra1": {b, a}}, nil)
+	if !reflect.DeepEqual(g1, g2) {
+		t.Errorf("graphs of paths in other order differ:\n%+v\n%+v", g1, g2)
+	}
+}
+
+func TestBuildLocal(t *testing.T) {
+	// Locally originated routes have an empty AS path
+	g := Build(map[string][]*utils.BGPPath{"fra1": {{Prefix: "192.0.2.0/24", Best: true}}}, nil)
+	if len(g.Edges) != 1 || g.Edges[0].From != "router:fra1" || g.Edges[0].To != "prefix:192.0.2.0/24" {
+		t.Errorf("edges = %+v, want the router to lead to the prefix", g.Edges)
+	}
+	for _, n := range g.Nodes {
+		if n.Origin {
+			t.Errorf("%s is marked as origin", n.ID)
+		}
+	}
+}
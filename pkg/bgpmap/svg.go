@@ -0,0 +1,174 @@
+package bgpmap
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+)
+
+// Layout of the SVG, nodes are placed in columns by their distance from the routers
+const (
+	nodeWidth   = 140
+	nodeHeight  = 32
+	columnWidth = 200
+	rowHeight   = 56
+	margin      = 20
+)
+
+type point struct {
+	x, y int
+}
+
+// layout returns the position of the top left corner of each node and the size of the drawing.
+// Routers are placed in the first and prefixes in the last column, the ASes by their longest
+// distance from a router. Nodes in a column are ordered by the mean row of their predecessors.
+func (g *Graph) layout() (map[string]point, int, int) {
+	preds := make(map[string][]string)
+	for _, e := range g.Edges {
+		preds[e.To] = append(preds[e.To], e.From)
+	}
+
+	// Longest path layering, bounded as paths of different routers may form cycles
+	rank := make(map[string]int)
+	for _, n := range g.Nodes {
+		rank[n.ID] = 0
+	}
+	for i := 0; i < len(g.Nodes); i++ {
+		changed := false
+		for _, e := range g.Edges {
+			if rank[e.To] < rank[e.From]+1 {
+				rank[e.To] = rank[e.From] + 1
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	last := 0
+	for _, r := range rank {
+		last = max(last, r)
+	}
+	for _, n := range g.Nodes {
+		if n.Type == NodePrefix {
+			rank[n.ID] = last
+		}
+	}
+
+	columns := make([][]string, last+1)
+	for _, n := range g.Nodes {
+		columns[rank[n.ID]] = append(columns[rank[n.ID]], n.ID)
+	}
+	row := make(map[string]float64)
+	for c, col := range columns {
+		if c > 0 {
+			center := make(map[string]float64, len(col))
+			for _, id := range col {
+				var sum float64
+				var count int
+				for _, from := range preds[id] {
+					if r, ok := row[from]; ok && rank[from] < c {
+						sum += r
+						count++
+					}
+				}
+				if count > 0 {
+					center[id] = sum / float64(count)
+				}
+			}
+			sort.SliceStable(col, func(i, j int) bool { return center[col[i]] < center[col[j]] })
+		}
+		for i, id := range col {
+			row[id] = float64(i)
+		}
+	}
+
+	rows := 0
+	for _, col := range columns {
+		rows = max(rows, len(col))
+	}
+	height := 2*margin + rows*rowHeight - (rowHeight - nodeHeight)
+	pos := make(map[string]point, len(g.Nodes))
+	for c, col := range columns {
+		// Center shorter columns vertically
+		offset := (rows - len(col)) * rowHeight / 2
+		for i, id := range col {
+			pos[id] = point{margin + c*columnWidth, margin + offset + i*rowHeight}
+		}
+	}
+	width := 2*margin + len(columns)*columnWidth - (columnWidth - nodeWidth)
+	return pos, width, height
+}
+
+// SVG renders the graph as standalone SVG image, best paths are drawn bold and red.
+func (g *Graph) SVG() string {
+	pos, width, height := g.layout()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", width, height, width, height)
+	b.WriteString("<defs>\n")
+	for _, m := range []struct{ id, color string }{{"arrow", colorEdge}, {"arrow-best", colorBest}} {
+		fmt.Fprintf(&b, `<marker id="%s" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto-start-reverse"><path d="M 0 0 L 10 5 L 0 10 z" fill="%s"/></marker>`+"\n", m.id, m.color)
+	}
+	b.WriteString("</defs>\n")
+
+	// Best edges are drawn last to be on top
+	edges := make([]*Edge, len(g.Edges))
+	copy(edges, g.Edges)
+	sort.SliceStable(edges, func(i, j int) bool { return !edges[i].Best && edges[j].Best })
+	for _, e := range edges {
+		from, to := pos[e.From], pos[e.To]
+		x1, y1 := from.x+nodeWidth, from.y+nodeHeight/2
+		x2, y2 := to.x, to.y+nodeHeight/2
+		if to.x <= from.x {
+			// Edges within or back to a column leave and enter from the bottom
+			x1, y1 = from.x+nodeWidth/2, from.y+nodeHeight
+			x2, y2 = to.x+nodeWidth/2, to.y+nodeHeight
+		}
+		dx := max((x2-x1)/2, rowHeight)
+		color, stroke, marker := colorEdge, 1, "arrow"
+		if e.Best {
+			color, stroke, marker = colorBest, 2, "arrow-best"
+		}
+		d := fmt.Sprintf("M %d %d C %d %d %d %d %d %d", x1, y1, x1+dx, y1, x2-dx, y2, x2, y2)
+		if to.x <= from.x {
+			d = fmt.Sprintf("M %d %d C %d %d %d %d %d %d", x1, y1, x1, y1+rowHeight, x2, y2+rowHeight, x2, y2)
+		}
+		fmt.Fprintf(&b, `<path d="%s" fill="none" stroke="%s" stroke-width="%d" marker-end="url(#%s)"><title>%s</title></path>`+"\n", d, color, stroke, marker, html.EscapeString(strings.Join(e.Routers, ", ")))
+		if e.Prepends > 0 {
+			fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end" font-size="10" fill="%s">+%d</text>`+"\n", x2-4, y2-4, color, e.Prepends)
+		}
+	}
+
+	for _, n := range g.Nodes {
+		p := pos[n.ID]
+		rx := 6
+		if n.Type == NodePrefix {
+			rx = 0
+		}
+		title := n.ID
+		if n.Name != "" {
+			title += " " + n.Name
+		}
+		fmt.Fprintf(&b, `<g><title>%s</title><rect x="%d" y="%d" width="%d" height="%d" rx="%d" fill="%s" stroke="#495057"/>`, html.EscapeString(title), p.x, p.y, nodeWidth, nodeHeight, rx, n.fill())
+		if n.Name == "" {
+			fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle" dominant-baseline="central">%s</text></g>`+"\n", p.x+nodeWidth/2, p.y+nodeHeight/2, html.EscapeString(truncate(n.Label, 20)))
+			continue
+		}
+		// The name goes below the label in smaller font
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle" dominant-baseline="central">%s</text>`, p.x+nodeWidth/2, p.y+nodeHeight/2-7, html.EscapeString(truncate(n.Label, 20)))
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle" dominant-baseline="central" font-size="9">%s</text></g>`+"\n", p.x+nodeWidth/2, p.y+nodeHeight/2+8, html.EscapeString(truncate(n.Name, 24)))
+	}
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// truncate shortens labels that do not fit into a node.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
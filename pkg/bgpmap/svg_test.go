@@ -0,0 +1,82 @@
+package bgpmap
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSVG(t *testing.T) {
+	g := &Graph{
+		Nodes: []*Node{
+			{ID: "router:<script>", Label: "<script>", Type: NodeRouter},
+			{ID: "as:65001", Label: "AS65001", Type: NodeAS, Origin: true, Name: `A&B "Networks"`},
+			{ID: "prefix:192.0.2.0/24", Label: "192.0.2.0/24", Type: NodePrefix},
+		},
+		Edges: []*Edge{
+			{From: "router:<script>", To: "as:65001", Routers: []string{"<script>"}, Best: true, Prepends: 1},
+			{From: "as:65001", To: "prefix:192.0.2.0/24", Routers: []string{"<script>"}},
+		},
+	}
+	svg := g.SVG()
+	if strings.Contains(svg, "<script>") {
+		t.Errorf("SVG contains unescaped label:\n%s", svg)
+	}
+
+	// The SVG must be well-formed with the labels as text
+	var texts []string
+	var best, paths int
+	d := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatalf("invalid SVG: %s\n%s", err, svg)
+		}
+		switch tok := tok.(type) {
+		case xml.CharData:
+			texts = append(texts, string(tok))
+		case xml.StartElement:
+			if tok.Name.Local != "path" {
+				continue
+			}
+			for _, a := range tok.Attr {
+				if a.Name.Local == "marker-end" {
+					paths++
+					if a.Value == "url(#arrow-best)" {
+						best++
+					}
+				}
+			}
+		}
+	}
+	text := strings.Join(texts, "|")
+	for _, want := range []string{"<script>", `A&B "Networks"`, "as:65001 A&B \"Networks\"", "+1"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("SVG lacks text %q: %s", want, text)
+		}
+	}
+	if paths != 2 || best != 1 {
+		t.Errorf("SVG has %d edges, %d of them best, want 2 and 1", paths, best)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"AS65001", 20, "AS65001"},
+		{"2001:db8:ffff:ffff::/64", 20, "2001:db8:ffff:ffff:…"},
+		{"ÄÖÜäöü", 4, "ÄÖÜ…"},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
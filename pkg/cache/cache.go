@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/metrics"
+)
+
+// Store persists cache entries, implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the value stored under key or errs.CacheMiss.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores the value under key for the given time.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Purge removes all entries whose key matches and returns how many were removed.
+	Purge(ctx context.Context, match func(key string) bool) (int, error)
+}
+
+// Entry is the raw output of an operation together with the time it was executed.
+type Entry struct {
+	Result    []string  `json:"result"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Cache caches the output of operations and coalesces concurrent identical operations.
+// Without a store only coalescing is done.
+type Cache struct {
+	store Store
+	ttl   time.Duration
+	ttls  map[string]time.Duration
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// call is an operation in progress that other requests for the same key wait for.
+type call struct {
+	done    chan struct{}
+	entry   *Entry
+	err     error
+	aborted bool // aborted is set if the request running the operation went away.
+}
+
+// New returns a cache backed by store, ttls overrides the default ttl per operation.
+// Operations with a ttl of 0 are only coalesced.
+func New(store Store, ttl time.Duration, ttls map[string]time.Duration) *Cache {
+	return &Cache{
+		store: store,
+		ttl:   ttl,
+		ttls:  ttls,
+		calls: make(map[string]*call),
+	}
+}
+
+// key builds a cache key from the router, the operation and its normalized parameters.
+func key(router string, op string, params []string) string {
+	return strings.Join(append([]string{"lg", router, op}, params...), "|")
+}
+
+// TTL returns how long results of the operation are cached.
+func (c *Cache) TTL(op string) time.Duration {
+	if ttl, ok := c.ttls[op]; ok {
+		return ttl
+	}
+	return c.ttl
+}
+
+// Do returns the cached result of the operation or runs fn, concurrent calls for the same operation share a single run of fn.
+// Operations are identified by router, name and normalized parameters.
+// The returned bool is true if the entry was served from the store.
+// Errors are never cached, if the request that ran fn went away the others run it again.
+func (c *Cache) Do(ctx context.Context, router string, op string, params []string, fn func(context.Context) ([]string, error)) (*Entry, bool, error) {
+	k := key(router, op, params)
+	ttl := c.TTL(op)
+	for {
+		if e := c.get(ctx, k, ttl); e != nil {
+			return e, true, nil
+		}
+		c.mu.Lock()
+		cl, ok := c.calls[k]
+		if !ok {
+			cl = &call{done: make(chan struct{})}
+			c.calls[k] = cl
+			c.mu.Unlock()
+			c.run(ctx, k, ttl, cl, fn)
+			return cl.entry, false, cl.err
+		}
+		c.mu.Unlock()
+		metrics.CacheRequests.WithLabelValues("coalesced").Inc()
+		select {
+		case <-cl.done:
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+		if cl.aborted && ctx.Err() == nil {
+			continue
+		}
+		return cl.entry, false, cl.err
+	}
+}
+
+// Purge removes the cached results of a router and operation, empty values match everything.
+func (c *Cache) Purge(ctx context.Context, router string, op string) (int, error) {
+	if c.store == nil {
+		return 0, nil
+	}
+	return c.store.Purge(ctx, func(k string) bool {
+		parts := strings.SplitN(k, "|", 4)
+		if len(parts) < 3 || parts[0] != "lg" {
+			return false
+		}
+		return (router == "" || parts[1] == router) && (op == "" || parts[2] == op)
+	})
+}
+
+// run executes fn for the waiting call and stores the result before releasing the key.
+func (c *Cache) run(ctx context.Context, k string, ttl time.Duration, cl *call, fn func(context.Context) ([]string, error)) {
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, k)
+		c.mu.Unlock()
+		close(cl.done)
+	}()
+	ret, err := fn(ctx)
+	if err != nil {
+		cl.err = err
+		cl.aborted = ctx.Err() != nil
+		return
+	}
+	cl.entry = &Entry{Result: ret, Timestamp: time.Now()}
+	if c.store == nil || ttl <= 0 {
+		return
+	}
+	b, err := json.Marshal(cl.entry)
+	if err != nil {
+		log.Println("ERROR: Failed to marshal cache entry:", err)
+		return
+	}
+	if err := c.store.Set(context.WithoutCancel(ctx), k, b, ttl); err != nil {
+		log.Println("ERROR: Failed to cache response:", err)
+	}
+}
+
+// get returns the stored entry for key or nil.
+func (c *Cache) get(ctx context.Context, k string, ttl time.Duration) *Entry {
+	if c.store == nil || ttl <= 0 {
+		return nil
+	}
+	b, err := c.store.Get(ctx, k)
+	if err != nil {
+		if err != errs.CacheMiss {
+			log.Println("WARNING: Failed to read cache:", err)
+		}
+		metrics.CacheRequests.WithLabelValues("miss").Inc()
+		return nil
+	}
+	var e Entry
+	if err := json.Unmarshal(b, &e); err != nil {
+		log.Println("WARNING: Failed to unmarshal cache entry:", err)
+		metrics.CacheRequests.WithLabelValues("miss").Inc()
+		return nil
+	}
+	metrics.CacheRequests.WithLabelValues("hit").Inc()
+	return &e
+}
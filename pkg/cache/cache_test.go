@@ -0,0 +1,161 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+)
+
+func TestDoCoalesces(t *testing.T) {
+	c := New(NewMemory(10), time.Minute, nil)
+	var runs atomic.Int32
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	fn := func(ctx context.Context) ([]string, error) {
+		if runs.Add(1) == 1 {
+			close(started)
+		}
+		<-unblock
+		return []string{"output"}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([][]string, 8)
+	do := func(i int) {
+		defer wg.Done()
+		e, _, err := c.Do(context.Background(), "router", "bgp.route", []string{"192.0.2.0/24"}, fn)
+		if err != nil {
+			t.Errorf("Do: %s", err)
+			return
+		}
+		results[i] = e.Result
+	}
+	wg.Add(len(results))
+	go do(0)
+	<-started
+	for i := 1; i < len(results); i++ {
+		go do(i)
+	}
+	close(unblock)
+	wg.Wait()
+
+	if n := runs.Load(); n != 1 {
+		t.Errorf("fn ran %d times, want 1", n)
+	}
+	for i, r := range results {
+		if !slices.Equal(r, []string{"output"}) {
+			t.Errorf("call %d = %q, want %q", i, r, []string{"output"})
+		}
+	}
+	e, cached, err := c.Do(context.Background(), "router", "bgp.route", []string{"192.0.2.0/24"}, fn)
+	if err != nil || !cached || !slices.Equal(e.Result, []string{"output"}) {
+		t.Errorf("Do after the run = %v, %t, %v, want the cached output", e, cached, err)
+	}
+	if _, cached, _ := c.Do(context.Background(), "router", "bgp.route", []string{"198.51.100.0/24"}, fn); cached {
+		t.Error("Do with other parameters was served from the cache")
+	}
+}
+
+func TestDoTTL(t *testing.T) {
+	c := New(NewMemory(10), time.Minute, map[string]time.Duration{"ping": 0, "bgp.summary": 10 * time.Millisecond})
+	var runs int
+	fn := func(ctx context.Context) ([]string, error) {
+		runs++
+		return []string{"output"}, nil
+	}
+	tests := []struct {
+		op     string
+		sleep  time.Duration
+		cached bool
+	}{
+		{"bgp.route", 0, true},
+		{"ping", 0, false},
+		{"bgp.summary", 0, true},
+		{"bgp.summary", 20 * time.Millisecond, false},
+	}
+	for _, tt := range tests {
+		c.Do(context.Background(), "router", tt.op, nil, fn)
+		time.Sleep(tt.sleep)
+		runs = 0
+		_, cached, err := c.Do(context.Background(), "router", tt.op, nil, fn)
+		if err != nil {
+			t.Errorf("%s: %s", tt.op, err)
+			continue
+		}
+		if cached != tt.cached || (runs == 0) != tt.cached {
+			t.Errorf("%s after %s: cached = %t with %d runs, want %t", tt.op, tt.sleep, cached, runs, tt.cached)
+		}
+		c.Purge(context.Background(), "", "")
+	}
+}
+
+func TestDoErrors(t *testing.T) {
+	c := New(NewMemory(10), time.Minute, nil)
+	failure := errors.New("router unreachable")
+	if _, _, err := c.Do(context.Background(), "router", "ping", nil, func(ctx context.Context) ([]string, error) {
+		return nil, failure
+	}); err != failure {
+		t.Errorf("Do = %v, want %v", err, failure)
+	}
+	e, cached, err := c.Do(context.Background(), "router", "ping", nil, func(ctx context.Context) ([]string, error) {
+		return []string{"output"}, nil
+	})
+	if err != nil || cached || !slices.Equal(e.Result, []string{"output"}) {
+		t.Errorf("Do after an error = %v, %t, %v, want a new run", e, cached, err)
+	}
+}
+
+func TestDoAborted(t *testing.T) {
+	c := New(nil, 0, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	go c.Do(ctx, "router", "ping", nil, func(ctx context.Context) ([]string, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	<-started
+
+	// The waiting request runs the operation itself once the first one went away
+	done := make(chan struct{})
+	var e *Entry
+	var err error
+	go func() {
+		defer close(done)
+		e, _, err = c.Do(context.Background(), "router", "ping", nil, func(ctx context.Context) ([]string, error) {
+			return []string{"output"}, nil
+		})
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Do did not return after the first request went away")
+	}
+	if err != nil || !slices.Equal(e.Result, []string{"output"}) {
+		t.Errorf("Do = %v, %v, want the output of its own run", e, err)
+	}
+}
+
+func TestMemoryEviction(t *testing.T) {
+	m := NewMemory(2)
+	ctx := context.Background()
+	m.Set(ctx, "a", []byte("a"), time.Minute)
+	m.Set(ctx, "b", []byte("b"), time.Minute)
+	m.Get(ctx, "a")
+	m.Set(ctx, "c", []byte("c"), time.Minute)
+	for _, k := range []string{"a", "c"} {
+		if _, err := m.Get(ctx, k); err != nil {
+			t.Errorf("Get(%s) = %v, want the entry", k, err)
+		}
+	}
+	if _, err := m.Get(ctx, "b"); !errors.Is(err, errs.CacheMiss) {
+		t.Errorf("Get of the least recently used entry = %v, want %v", err, errs.CacheMiss)
+	}
+}
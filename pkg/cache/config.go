@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+const (
+	defaultTTL        = 60 * time.Second
+	defaultMaxEntries = 1000
+)
+
+// FromConfig returns the cache selected by the configuration.
+// Without a cache section Redis is used if enabled, the cache only coalesces requests if no backend is usable.
+func FromConfig(ctx context.Context, cfg *utils.Config) *Cache {
+	backend := cfg.Cache.Backend
+	if backend == "" {
+		backend = "none"
+		if cfg.Redis.Enabled {
+			backend = "redis"
+		}
+	}
+	ttl := utils.ParseDuration(cfg.Cache.TTL, utils.ParseDuration(cfg.Redis.TTL, defaultTTL))
+	ttls := make(map[string]time.Duration)
+	for op, t := range cfg.Cache.Operations {
+		ttls[op] = utils.ParseDuration(t, ttl)
+	}
+
+	var store Store
+	switch backend {
+	case "none":
+	case "memory":
+		max := cfg.Cache.MaxEntries
+		if max <= 0 {
+			max = defaultMaxEntries
+		}
+		log.Printf("NOTICE: Using in-memory cache with up to %d entries", max)
+		store = NewMemory(max)
+	case "filesystem":
+		fs, err := NewFilesystem(ctx, cfg.Cache.Path)
+		if err != nil {
+			log.Println("ERROR: Failed to create cache directory:", err, "disabling cache")
+			break
+		}
+		log.Println("NOTICE: Using filesystem cache at", cfg.Cache.Path)
+		store = fs
+	case "redis":
+		client, err := utils.NewRedisClient(cfg.Redis)
+		if err != nil {
+			log.Println("ERROR: Failed to configure Redis:", err, "disabling Redis cache")
+			break
+		}
+		store = NewRedis(client)
+	default:
+		log.Printf("ERROR: Unknown cache backend %s, disabling cache", backend)
+	}
+	return New(store, ttl, ttls)
+}
@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+)
+
+const filesystemSweepInterval = 5 * time.Minute
+
+// Filesystem stores every entry in its own file below a directory, expired files are removed periodically.
+type Filesystem struct {
+	dir string
+}
+
+type filesystemEntry struct {
+	Key     string    `json:"key"`
+	Value   []byte    `json:"value"`
+	Expires time.Time `json:"expires"`
+}
+
+func NewFilesystem(ctx context.Context, dir string) (*Filesystem, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	fs := &Filesystem{dir: dir}
+	go fs.sweep(ctx)
+	return fs, nil
+}
+
+// path returns the file of the key, keys are hashed as they may contain any character.
+func (fs *Filesystem) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(fs.dir, hex.EncodeToString(sum[:]))
+}
+
+func (fs *Filesystem) read(path string) (*filesystemEntry, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var e filesystemEntry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (fs *Filesystem) Get(ctx context.Context, key string) ([]byte, error) {
+	p := fs.path(key)
+	e, err := fs.read(p)
+	if os.IsNotExist(err) {
+		return nil, errs.CacheMiss
+	} else if err != nil {
+		return nil, err
+	}
+	if e.Key != key || time.Now().After(e.Expires) {
+		return nil, errs.CacheMiss
+	}
+	return e.Value, nil
+}
+
+func (fs *Filesystem) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	b, err := json.Marshal(&filesystemEntry{Key: key, Value: value, Expires: time.Now().Add(ttl)})
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first so readers never see partial entries
+	tmp, err := os.CreateTemp(fs.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), fs.path(key))
+}
+
+func (fs *Filesystem) Purge(ctx context.Context, match func(key string) bool) (int, error) {
+	return fs.remove(func(e *filesystemEntry) bool {
+		return match(e.Key)
+	})
+}
+
+// remove deletes all entries for which del returns true, unreadable entries are deleted as well.
+func (fs *Filesystem) remove(del func(e *filesystemEntry) bool) (int, error) {
+	files, err := os.ReadDir(fs.dir)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, f := range files {
+		if f.IsDir() || f.Name()[0] == '.' {
+			continue
+		}
+		p := filepath.Join(fs.dir, f.Name())
+		e, err := fs.read(p)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil || del(e) {
+			if os.Remove(p) == nil {
+				n++
+			}
+		}
+	}
+	return n, nil
+}
+
+// sweep removes expired entries until the context is done.
+func (fs *Filesystem) sweep(ctx context.Context) {
+	ticker := time.NewTicker(filesystemSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			if _, err := fs.remove(func(e *filesystemEntry) bool { return now.After(e.Expires) }); err != nil {
+				log.Println("WARNING: Failed to sweep filesystem cache:", err)
+			}
+		}
+	}
+}
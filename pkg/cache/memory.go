@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+)
+
+// Memory is a bounded in-memory store that evicts the least recently used entry once full.
+type Memory struct {
+	mu      sync.Mutex
+	max     int
+	order   *list.List // order holds the keys, most recently used first.
+	entries map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+func NewMemory(maxEntries int) *Memory {
+	return &Memory{
+		max:     maxEntries,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.entries[key]
+	if !ok {
+		return nil, errs.CacheMiss
+	}
+	e := el.Value.(*memoryEntry)
+	if time.Now().After(e.expires) {
+		m.order.Remove(el)
+		delete(m.entries, key)
+		return nil, errs.CacheMiss
+	}
+	m.order.MoveToFront(el)
+	return e.value, nil
+}
+
+func (m *Memory) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := &memoryEntry{key: key, value: value, expires: time.Now().Add(ttl)}
+	if el, ok := m.entries[key]; ok {
+		el.Value = e
+		m.order.MoveToFront(el)
+		return nil
+	}
+	m.entries[key] = m.order.PushFront(e)
+	for m.max > 0 && m.order.Len() > m.max {
+		el := m.order.Back()
+		m.order.Remove(el)
+		delete(m.entries, el.Value.(*memoryEntry).key)
+	}
+	return nil
+}
+
+func (m *Memory) Purge(ctx context.Context, match func(key string) bool) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for key, el := range m.entries {
+		if match(key) {
+			m.order.Remove(el)
+			delete(m.entries, key)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis stores entries in Redis, either a single server, a Sentinel managed setup or a Cluster.
+type Redis struct {
+	client redis.UniversalClient
+}
+
+func NewRedis(client redis.UniversalClient) *Redis {
+	return &Redis{client: client}
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	b, err := r.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, errs.CacheMiss
+	}
+	return b, err
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (r *Redis) Purge(ctx context.Context, match func(key string) bool) (int, error) {
+	if cc, ok := r.client.(*redis.ClusterClient); ok {
+		// Keys are spread over the masters, each has to be scanned on its own
+		var n int
+		var mu sync.Mutex
+		err := cc.ForEachMaster(ctx, func(ctx context.Context, c *redis.Client) error {
+			m, err := purge(ctx, c, match)
+			mu.Lock()
+			n += m
+			mu.Unlock()
+			return err
+		})
+		return n, err
+	}
+	return purge(ctx, r.client, match)
+}
+
+// purge scans a single server for cache keys and deletes the matching ones.
+func purge(ctx context.Context, c redis.Cmdable, match func(key string) bool) (int, error) {
+	n := 0
+	iter := c.Scan(ctx, 0, "lg|*", 1000).Iterator()
+	for iter.Next(ctx) {
+		if !match(iter.Val()) {
+			continue
+		}
+		if err := c.Del(ctx, iter.Val()).Err(); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, iter.Err()
+}
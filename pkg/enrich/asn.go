@@ -0,0 +1,73 @@
+package enrich
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+type asnEntry struct {
+	asn  uint32
+	name string
+}
+
+// parseASN parses an IP-to-ASN dataset, either ip2asn TSV with lines of
+//
+//	range_start	range_end	AS_number	country_code	AS_description
+//
+// or a prefix table as derived from MRT dumps, e.g. by pyasn or bgpdump, with lines of
+//
+//	prefix	ASN
+//
+// Lines starting with # or ; are comments, unrouted ranges of AS 0 and AS sets are skipped.
+func parseASN(b []byte) (*table[asnEntry], error) {
+	t := newTable[asnEntry]()
+	s := bufio.NewScanner(bytes.NewReader(b))
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.Count(line, "\t") >= 2 {
+			// ip2asn, the description may contain spaces
+			f := strings.SplitN(line, "\t", 5)
+			start, err1 := netip.ParseAddr(f[0])
+			end, err2 := netip.ParseAddr(f[1])
+			asn, err3 := strconv.ParseUint(strings.TrimPrefix(f[2], "AS"), 10, 32)
+			if err1 != nil || err2 != nil || err3 != nil || asn == 0 {
+				continue
+			}
+			e := asnEntry{asn: uint32(asn)}
+			if len(f) == 5 && f[4] != "Not routed" {
+				e.name = f[4]
+			}
+			t.addRange(start, end, e)
+			continue
+		}
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			continue
+		}
+		p, err := netip.ParsePrefix(f[0])
+		if err != nil {
+			continue
+		}
+		asn, err := strconv.ParseUint(strings.TrimPrefix(f[1], "AS"), 10, 32)
+		if err != nil || asn == 0 {
+			continue
+		}
+		t.addPrefix(p, asnEntry{asn: uint32(asn)})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if t.len() == 0 {
+		return nil, errors.New("no entries")
+	}
+	t.sort()
+	return t, nil
+}
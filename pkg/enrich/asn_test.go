@@ -0,0 +1,51 @@
+package enrich
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestParseASN(t *testing.T) {
+	ip2asn := "# ip2asn-combined.tsv\n" +
+		"1.0.0.0\t1.0.0.255\t13335\tUS\tCLOUDFLARENET\n" +
+		"1.0.1.0\t1.0.3.255\t0\tNone\tNot routed\n" +
+		"1.0.4.0\t1.0.7.255\t38803\tAU\tWPL-AS-AP Wirefreebroadband Pty Ltd\n" +
+		"1.0.8.0\t1.0.8.255\t64496\tNone\tNot routed\n" +
+		"invalid\t1.0.9.255\t64497\tUS\tINVALID\n" +
+		"2001:db8::\t2001:db8:ffff:ffff:ffff:ffff:ffff:ffff\tAS64498\tDE\tEXAMPLE-V6\n"
+	prefixes := "; pyasn\n" +
+		"192.0.2.0/24\t64499\n" +
+		"198.51.100.0/24 AS64500\n" +
+		"203.0.113.0/24\t{64501,64502}\n" +
+		"10.0.0.0/8\t0\n"
+	tbl, err := parseASN([]byte(ip2asn + prefixes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		addr string
+		asn  uint32
+		name string
+	}{
+		{"1.0.0.1", 13335, "CLOUDFLARENET"},
+		{"1.0.2.1", 0, ""},
+		{"1.0.7.255", 38803, "WPL-AS-AP Wirefreebroadband Pty Ltd"},
+		{"1.0.8.1", 64496, ""},
+		{"1.0.9.1", 0, ""},
+		{"2001:db8:1::1", 64498, "EXAMPLE-V6"},
+		{"192.0.2.1", 64499, ""},
+		{"198.51.100.1", 64500, ""},
+		{"203.0.113.1", 0, ""},
+		{"10.0.0.1", 0, ""},
+	}
+	for _, tt := range tests {
+		e, ok := tbl.lookup(netip.MustParseAddr(tt.addr))
+		if ok != (tt.asn != 0) || e.asn != tt.asn || e.name != tt.name {
+			t.Errorf("lookup(%s) = %+v, %t, want AS%d %q", tt.addr, e, ok, tt.asn, tt.name)
+		}
+	}
+
+	if _, err := parseASN([]byte("# only comments\n1.0.1.0\t1.0.3.255\t0\tNone\tNot routed\n")); err == nil {
+		t.Error("parseASN of a dataset without entries succeeded")
+	}
+}
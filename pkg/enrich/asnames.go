@@ -0,0 +1,145 @@
+package enrich
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ASInfo is the name and organisation of an AS.
+type ASInfo struct {
+	ASN     uint32
+	Name    string
+	Org     string
+	Country string
+}
+
+// parseASNames parses a PeeringDB JSON dump or a CAIDA AS-to-organisation dataset.
+func parseASNames(b []byte) (map[uint32]*ASInfo, error) {
+	var names map[uint32]*ASInfo
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '{' && json.Valid(b) {
+		var err error
+		if names, err = parsePeeringDBNets(b); err != nil {
+			return nil, err
+		}
+	} else {
+		names = parseAS2Org(b)
+	}
+	if len(names) == 0 {
+		return nil, errors.New("no ASes")
+	}
+	return names, nil
+}
+
+// parsePeeringDBNets parses the networks of a full PeeringDB dump with the net and org objects
+// or of the response of the net API endpoint, which lacks the organisations.
+func parsePeeringDBNets(b []byte) (map[uint32]*ASInfo, error) {
+	type net struct {
+		ASN   uint32 `json:"asn"`
+		Name  string `json:"name"`
+		OrgID int    `json:"org_id"`
+	}
+	var pdb struct {
+		Net struct {
+			Data []net `json:"data"`
+		} `json:"net"`
+		Org struct {
+			Data []struct {
+				ID      int    `json:"id"`
+				Name    string `json:"name"`
+				Country string `json:"country"`
+			} `json:"data"`
+		} `json:"org"`
+		Data []net `json:"data"`
+	}
+	if err := json.Unmarshal(b, &pdb); err != nil {
+		return nil, err
+	}
+	orgs := make(map[int]*ASInfo, len(pdb.Org.Data))
+	for _, org := range pdb.Org.Data {
+		orgs[org.ID] = &ASInfo{Org: org.Name, Country: org.Country}
+	}
+	names := make(map[uint32]*ASInfo)
+	for _, n := range append(pdb.Net.Data, pdb.Data...) {
+		if n.ASN == 0 {
+			continue
+		}
+		info := &ASInfo{ASN: n.ASN, Name: n.Name}
+		if org, ok := orgs[n.OrgID]; ok {
+			info.Org, info.Country = org.Org, org.Country
+		}
+		names[n.ASN] = info
+	}
+	return names, nil
+}
+
+// as2orgJSON is a line of the JSON lines format of the CAIDA AS-to-organisation dataset.
+type as2orgJSON struct {
+	Type    string `json:"type"`
+	ASN     string `json:"asn"`
+	Name    string `json:"name"`
+	OrgID   string `json:"organizationId"`
+	Country string `json:"country"`
+}
+
+// parseAS2Org parses the CAIDA AS-to-organisation dataset, either in the pipe separated format with
+// sections of organisations and ASes described by their "# format:" comments, or as JSON lines.
+func parseAS2Org(b []byte) map[uint32]*ASInfo {
+	type as struct {
+		name  string
+		orgID string
+	}
+	ases := make(map[uint32]as)
+	orgs := make(map[string]*ASInfo)
+	var format []string
+	s := bufio.NewScanner(bytes.NewReader(b))
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "# format:"):
+			format = strings.Split(strings.TrimPrefix(line, "# format:"), "|")
+		case line[0] == '#':
+		case line[0] == '{':
+			var v as2orgJSON
+			if err := json.Unmarshal([]byte(line), &v); err != nil {
+				continue
+			}
+			switch v.Type {
+			case "ASN":
+				if asn, err := strconv.ParseUint(v.ASN, 10, 32); err == nil {
+					ases[uint32(asn)] = as{v.Name, v.OrgID}
+				}
+			case "Organization":
+				orgs[v.OrgID] = &ASInfo{Org: v.Name, Country: v.Country}
+			}
+		default:
+			fields := make(map[string]string, len(format))
+			for i, f := range strings.Split(line, "|") {
+				if i < len(format) {
+					fields[format[i]] = f
+				}
+			}
+			if _, ok := fields["aut"]; ok {
+				if asn, err := strconv.ParseUint(fields["aut"], 10, 32); err == nil {
+					ases[uint32(asn)] = as{fields["aut_name"], fields["org_id"]}
+				}
+			} else if id, ok := fields["org_id"]; ok {
+				orgs[id] = &ASInfo{Org: fields["org_name"], Country: fields["country"]}
+			}
+		}
+	}
+	names := make(map[uint32]*ASInfo, len(ases))
+	for asn, a := range ases {
+		info := &ASInfo{ASN: asn, Name: a.name}
+		if org, ok := orgs[a.orgID]; ok {
+			info.Org, info.Country = org.Org, org.Country
+		}
+		names[asn] = info
+	}
+	return names
+}
@@ -0,0 +1,56 @@
+package enrich
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseASNames(t *testing.T) {
+	tests := []struct {
+		file string
+		want map[uint32]ASInfo
+	}{
+		{"as2org.txt", map[uint32]ASInfo{
+			64496: {ASN: 64496, Name: "EXAMPLE-NET", Org: "Example Networks, Inc.", Country: "US"},
+			64497: {ASN: 64497, Name: "DOC-AS", Org: "Documentation B.V.", Country: "NL"},
+			64498: {ASN: 64498, Name: "ORPHAN"},
+		}},
+		{"as2org.jsonl", map[uint32]ASInfo{
+			64496: {ASN: 64496, Name: "EXAMPLE-NET", Org: "Example Networks, Inc.", Country: "US"},
+			64498: {ASN: 64498, Name: "ORPHAN"},
+		}},
+		{"peeringdb.json", map[uint32]ASInfo{
+			64496: {ASN: 64496, Name: "Example Net", Org: "Example Networks, Inc.", Country: "US"},
+			64497: {ASN: 64497, Name: "Documentation Network", Org: "Documentation B.V.", Country: "NL"},
+			64498: {ASN: 64498, Name: "API Net"},
+		}},
+	}
+	for _, tt := range tests {
+		b, err := os.ReadFile(filepath.Join("testdata", tt.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		names, err := parseASNames(b)
+		if err != nil {
+			t.Errorf("%s: %s", tt.file, err)
+			continue
+		}
+		got := make(map[uint32]ASInfo, len(names))
+		for asn, info := range names {
+			got[asn] = *info
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseASNames = %+v, want %+v", tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestParseASNamesEmpty(t *testing.T) {
+	for _, b := range []string{"", "# format:aut|changed|aut_name|org_id|opaque_id|source\n", `{"net": {"data": []}}`} {
+		if _, err := parseASNames([]byte(b)); err == nil {
+			t.Errorf("parseASNames(%q) succeeded", b)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package enrich
+
+import (
+	"context"
+	"log"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+const (
+	defaultReload = 5 * time.Minute
+	maxLookups    = 8 // maxLookups bounds the concurrent PTR lookups of one Resolve
+)
+
+// Enricher annotates addresses of traceroute hops with their reverse DNS name, origin ASN and IXP
+// and ASes with their name and organisation. Local datasets and cached PTR records are looked up
+// without blocking, uncached PTR records are resolved by Resolve.
+type Enricher struct {
+	ptr   *ptrCache
+	asn   *utils.Dataset[*table[asnEntry]]
+	ixp   *utils.Dataset[*table[string]]
+	names *utils.Dataset[map[uint32]*ASInfo]
+}
+
+// Info is what is known about an address.
+type Info struct {
+	Address string
+	PTR     string
+	ASN     uint32
+	ASName  string
+	IXP     bool
+	IXPName string
+}
+
+// FromConfig returns the configured enricher, or nil if enrichment is disabled.
+func FromConfig(ctx context.Context, cfg utils.EnrichmentConfig) *Enricher {
+	if !cfg.Enabled {
+		return nil
+	}
+	e := &Enricher{}
+	if cfg.DNS.Enabled {
+		e.ptr = newPTRCache(cfg.DNS)
+		resolver := cfg.DNS.Resolver
+		if resolver == "" {
+			resolver = "system resolver"
+		}
+		log.Printf("NOTICE: Resolving traceroute hops with %s", resolver)
+	}
+	if cfg.ASN.File != "" {
+		e.asn = utils.NewDataset(ctx, "IP-to-ASN dataset", cfg.ASN.File, utils.ParseDuration(cfg.ASN.Reload, defaultReload), parseASN)
+	}
+	if cfg.IXP.File != "" {
+		e.ixp = utils.NewDataset(ctx, "PeeringDB IX prefixes", cfg.IXP.File, utils.ParseDuration(cfg.IXP.Reload, defaultReload), parseIXP)
+	}
+	if cfg.ASNames.File != "" {
+		e.names = utils.NewDataset(ctx, "AS names", cfg.ASNames.File, utils.ParseDuration(cfg.ASNames.Reload, defaultReload), parseASNames)
+	}
+	return e
+}
+
+// Lookup returns what is known about the address without blocking, the PTR record only if it is cached.
+func (e *Enricher) Lookup(addr string) *Info {
+	info := &Info{Address: addr}
+	a, err := netip.ParseAddr(addr)
+	if err != nil {
+		return info
+	}
+	if e.ptr != nil {
+		info.PTR, _ = e.ptr.cached(addr)
+	}
+	if e.asn != nil {
+		if v, ok := e.asn.Get().lookup(a); ok {
+			info.ASN, info.ASName = v.asn, v.name
+		}
+	}
+	if info.ASN != 0 && info.ASName == "" {
+		if as := e.AS(info.ASN); as != nil {
+			info.ASName = as.Name
+		}
+	}
+	if e.ixp != nil {
+		info.IXPName, info.IXP = e.ixp.Get().lookup(a)
+	}
+	return info
+}
+
+// HasASNames returns whether AS names are configured.
+func (e *Enricher) HasASNames() bool {
+	return e != nil && e.names != nil
+}
+
+// AS returns the name and organisation of the AS, or nil if it is unknown.
+func (e *Enricher) AS(asn uint32) *ASInfo {
+	if e.names == nil {
+		return nil
+	}
+	return e.names.Get()[asn]
+}
+
+// Unresolved returns the addresses whose PTR records are not cached.
+func (e *Enricher) Unresolved(addrs []string) []string {
+	if e.ptr == nil {
+		return nil
+	}
+	var ret []string
+	for _, addr := range addrs {
+		if _, err := netip.ParseAddr(addr); err != nil {
+			continue
+		}
+		if _, ok := e.ptr.cached(addr); !ok {
+			ret = append(ret, addr)
+		}
+	}
+	return ret
+}
+
+// Resolve looks up the PTR records of the addresses into the cache, each lookup is bounded by the DNS timeout.
+func (e *Enricher) Resolve(ctx context.Context, addrs []string) {
+	if e.ptr == nil {
+		return
+	}
+	sem := make(chan struct{}, maxLookups)
+	var wg sync.WaitGroup
+	for _, addr := range addrs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(addr string) {
+			defer wg.Done()
+			e.ptr.lookup(ctx, addr)
+			<-sem
+		}(addr)
+	}
+	wg.Wait()
+}
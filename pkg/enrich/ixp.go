@@ -0,0 +1,62 @@
+package enrich
+
+import (
+	"encoding/json"
+	"errors"
+	"net/netip"
+)
+
+// peeringDB is a PeeringDB JSON dump, either a full dump with the ix, ixlan and ixpfx objects
+// or the response of the ixpfx API endpoint, which lacks the names of the IXPs.
+type peeringDB struct {
+	IX struct {
+		Data []struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		} `json:"data"`
+	} `json:"ix"`
+	IXLan struct {
+		Data []struct {
+			ID   int `json:"id"`
+			IXID int `json:"ix_id"`
+		} `json:"data"`
+	} `json:"ixlan"`
+	IXPfx struct {
+		Data []peeringDBPrefix `json:"data"`
+	} `json:"ixpfx"`
+	Data []peeringDBPrefix `json:"data"`
+}
+
+type peeringDBPrefix struct {
+	IXLanID int    `json:"ixlan_id"`
+	Prefix  string `json:"prefix"`
+}
+
+// parseIXP parses the IX LAN prefixes of a PeeringDB dump into a table of the IXP names.
+func parseIXP(b []byte) (*table[string], error) {
+	var pdb peeringDB
+	if err := json.Unmarshal(b, &pdb); err != nil {
+		return nil, err
+	}
+	names := make(map[int]string, len(pdb.IX.Data))
+	for _, ix := range pdb.IX.Data {
+		names[ix.ID] = ix.Name
+	}
+	lans := make(map[int]string, len(pdb.IXLan.Data))
+	for _, lan := range pdb.IXLan.Data {
+		lans[lan.ID] = names[lan.IXID]
+	}
+	t := newTable[string]()
+	for _, pfx := range append(pdb.IXPfx.Data, pdb.Data...) {
+		p, err := netip.ParsePrefix(pfx.Prefix)
+		if err != nil {
+			continue
+		}
+		t.addPrefix(p, lans[pfx.IXLanID])
+	}
+	if t.len() == 0 {
+		return nil, errors.New("no IX LAN prefixes")
+	}
+	t.sort()
+	return t, nil
+}
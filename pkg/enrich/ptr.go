@@ -0,0 +1,137 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+const (
+	defaultDNSTimeout  = time.Second
+	defaultCacheTTL    = time.Hour
+	defaultNegativeTTL = 5 * time.Minute
+	defaultCacheSize   = 10000
+)
+
+// ptrCache resolves and caches the reverse DNS names of addresses, concurrent lookups of an address are coalesced.
+type ptrCache struct {
+	resolver    *net.Resolver
+	timeout     time.Duration
+	ttl         time.Duration
+	negativeTTL time.Duration
+	size        int
+
+	mu      sync.Mutex
+	entries map[string]ptrEntry
+	pending map[string]chan struct{}
+}
+
+type ptrEntry struct {
+	name    string // name is empty if the address has no PTR record
+	expires time.Time
+}
+
+func newPTRCache(cfg utils.DNSConfig) *ptrCache {
+	c := &ptrCache{
+		resolver:    net.DefaultResolver,
+		timeout:     utils.ParseDuration(cfg.Timeout, defaultDNSTimeout),
+		ttl:         utils.ParseDuration(cfg.CacheTTL, defaultCacheTTL),
+		negativeTTL: utils.ParseDuration(cfg.NegativeTTL, defaultNegativeTTL),
+		size:        cfg.CacheSize,
+		entries:     make(map[string]ptrEntry),
+		pending:     make(map[string]chan struct{}),
+	}
+	if c.timeout <= 0 {
+		c.timeout = defaultDNSTimeout
+	}
+	if c.size <= 0 {
+		c.size = defaultCacheSize
+	}
+	if cfg.Resolver != "" {
+		server := cfg.Resolver
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		c.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, server)
+			},
+		}
+	}
+	return c
+}
+
+// cached returns the cached name of the address, ok is false if it has to be resolved.
+func (c *ptrCache) cached(addr string) (name string, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[addr]
+	if !ok || time.Now().After(e.expires) {
+		return "", false
+	}
+	return e.name, true
+}
+
+// lookup returns the name of the address, resolving it if it is not cached.
+// Failed lookups are cached for the negative TTL, errors other than a missing record are not cached.
+func (c *ptrCache) lookup(ctx context.Context, addr string) string {
+	if name, ok := c.cached(addr); ok {
+		return name
+	}
+	c.mu.Lock()
+	if wait, ok := c.pending[addr]; ok {
+		c.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+		}
+		name, _ := c.cached(addr)
+		return name
+	}
+	done := make(chan struct{})
+	c.pending[addr] = done
+	c.mu.Unlock()
+
+	lctx, cancel := context.WithTimeout(ctx, c.timeout)
+	names, err := c.resolver.LookupAddr(lctx, addr)
+	cancel()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, addr)
+	defer close(done)
+	var dnsErr *net.DNSError
+	switch {
+	case err == nil && len(names) > 0:
+		c.store(addr, ptrEntry{name: strings.TrimSuffix(names[0], "."), expires: time.Now().Add(c.ttl)})
+	case err == nil || (errors.As(err, &dnsErr) && dnsErr.IsNotFound):
+		c.store(addr, ptrEntry{expires: time.Now().Add(c.negativeTTL)})
+	}
+	return c.entries[addr].name
+}
+
+// store caches the entry, expired or arbitrary entries are evicted when the cache is full.
+func (c *ptrCache) store(addr string, e ptrEntry) {
+	if len(c.entries) >= c.size {
+		now := time.Now()
+		for a, old := range c.entries {
+			if now.After(old.expires) {
+				delete(c.entries, a)
+			}
+		}
+		for a := range c.entries {
+			if len(c.entries) < c.size {
+				break
+			}
+			delete(c.entries, a)
+		}
+	}
+	c.entries[addr] = e
+}
@@ -0,0 +1,180 @@
+package enrich
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/utils"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsServer answers PTR queries for the reverse names it knows, unknown names do not exist.
+// Queries for names of failing are answered with a server failure.
+type dnsServer struct {
+	names   map[string]string
+	failing map[string]bool
+	hold    chan struct{} // hold delays answers until it is closed
+	queries atomic.Int32
+}
+
+// resolver starts the server and returns a resolver using it.
+func (s *dnsServer) resolver(t *testing.T) *net.Resolver {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			s.queries.Add(1)
+			go s.answer(conn, addr, append([]byte(nil), buf[:n]...))
+		}
+	}()
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "udp", conn.LocalAddr().String())
+		},
+	}
+}
+
+func (s *dnsServer) answer(conn net.PacketConn, addr net.Addr, query []byte) {
+	var p dnsmessage.Parser
+	h, err := p.Start(query)
+	if err != nil {
+		return
+	}
+	q, err := p.Question()
+	if err != nil {
+		return
+	}
+	if s.hold != nil {
+		<-s.hold
+	}
+	res := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: h.ID, Response: true, RecursionAvailable: true, RCode: dnsmessage.RCodeNameError},
+		Questions: []dnsmessage.Question{q},
+	}
+	if s.failing[q.Name.String()] {
+		res.RCode = dnsmessage.RCodeServerFailure
+	} else if name, ok := s.names[q.Name.String()]; ok && q.Type == dnsmessage.TypePTR {
+		res.RCode = dnsmessage.RCodeSuccess
+		res.Answers = []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET, TTL: 3600},
+			Body:   &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName(name)},
+		}}
+	}
+	b, err := res.Pack()
+	if err != nil {
+		return
+	}
+	conn.WriteTo(b, addr)
+}
+
+func TestPTRLookup(t *testing.T) {
+	s := &dnsServer{
+		names:   map[string]string{"1.2.0.192.in-addr.arpa.": "router.example.net."},
+		failing: map[string]bool{"3.2.0.192.in-addr.arpa.": true},
+	}
+	c := newPTRCache(utils.DNSConfig{NegativeTTL: "50ms"})
+	c.resolver = s.resolver(t)
+	ctx := context.Background()
+
+	if name := c.lookup(ctx, "192.0.2.1"); name != "router.example.net" {
+		t.Errorf("lookup = %q, want router.example.net", name)
+	}
+	if name, ok := c.cached("192.0.2.1"); !ok || name != "router.example.net" {
+		t.Errorf("cached = %q, %t, want the name", name, ok)
+	}
+	if name := c.lookup(ctx, "192.0.2.1"); name != "router.example.net" || s.queries.Load() != 1 {
+		t.Errorf("second lookup = %q with %d queries, want the cached name", name, s.queries.Load())
+	}
+
+	// Missing records are cached for the negative TTL
+	s.queries.Store(0)
+	if name := c.lookup(ctx, "192.0.2.2"); name != "" {
+		t.Errorf("lookup without record = %q", name)
+	}
+	if _, ok := c.cached("192.0.2.2"); !ok {
+		t.Error("missing record was not cached")
+	}
+	c.lookup(ctx, "192.0.2.2")
+	if n := s.queries.Load(); n != 1 {
+		t.Errorf("%d queries within the negative TTL, want 1", n)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := c.cached("192.0.2.2"); ok {
+		t.Error("missing record still cached after the negative TTL")
+	}
+	c.lookup(ctx, "192.0.2.2")
+	if n := s.queries.Load(); n != 2 {
+		t.Errorf("%d queries after the negative TTL, want 2", n)
+	}
+
+	// Other errors are not cached
+	if name := c.lookup(ctx, "192.0.2.3"); name != "" {
+		t.Errorf("failed lookup = %q", name)
+	}
+	if _, ok := c.cached("192.0.2.3"); ok {
+		t.Error("failed lookup was cached")
+	}
+}
+
+func TestPTRCoalesce(t *testing.T) {
+	s := &dnsServer{
+		names: map[string]string{"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.": "router.example.net."},
+		hold:  make(chan struct{}),
+	}
+	c := newPTRCache(utils.DNSConfig{})
+	c.resolver = s.resolver(t)
+
+	var wg sync.WaitGroup
+	names := make([]string, 8)
+	lookup := func(i int) {
+		defer wg.Done()
+		names[i] = c.lookup(context.Background(), "2001:db8::1")
+	}
+	wg.Add(len(names))
+	go lookup(0)
+	for s.queries.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 1; i < len(names); i++ {
+		go lookup(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(s.hold)
+	wg.Wait()
+
+	if n := s.queries.Load(); n != 1 {
+		t.Errorf("%d queries, want 1", n)
+	}
+	for i, name := range names {
+		if name != "router.example.net" {
+			t.Errorf("lookup #%d = %q, want router.example.net", i, name)
+		}
+	}
+}
+
+func TestPTRStoreEvicts(t *testing.T) {
+	c := newPTRCache(utils.DNSConfig{CacheSize: 2})
+	c.store("192.0.2.1", ptrEntry{name: "expired", expires: time.Now().Add(-time.Second)})
+	c.store("192.0.2.2", ptrEntry{name: "valid", expires: time.Now().Add(time.Hour)})
+	c.store("192.0.2.3", ptrEntry{name: "new", expires: time.Now().Add(time.Hour)})
+	if len(c.entries) != 2 {
+		t.Errorf("%d entries, want 2", len(c.entries))
+	}
+	if _, ok := c.entries["192.0.2.1"]; ok {
+		t.Error("expired entry was not evicted first")
+	}
+}
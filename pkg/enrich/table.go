@@ -0,0 +1,70 @@
+package enrich
+
+import (
+	"net/netip"
+	"slices"
+	"sort"
+)
+
+// table maps addresses to the value of the longest prefix or the range containing them.
+type table[T any] struct {
+	prefixes map[netip.Prefix]T
+	bits     []int // bits holds the prefix lengths in use, longest first
+	ranges   []addrRange[T]
+}
+
+// addrRange is an inclusive range of addresses, ranges must not overlap.
+type addrRange[T any] struct {
+	start netip.Addr
+	end   netip.Addr
+	value T
+}
+
+func newTable[T any]() *table[T] {
+	return &table[T]{prefixes: make(map[netip.Prefix]T)}
+}
+
+func (t *table[T]) addPrefix(p netip.Prefix, v T) {
+	p = p.Masked()
+	if _, ok := t.prefixes[p]; !ok && !slices.Contains(t.bits, p.Bits()) {
+		t.bits = append(t.bits, p.Bits())
+	}
+	t.prefixes[p] = v
+}
+
+func (t *table[T]) addRange(start, end netip.Addr, v T) {
+	t.ranges = append(t.ranges, addrRange[T]{start.Unmap(), end.Unmap(), v})
+}
+
+// sort prepares the table for lookups after adding all entries.
+func (t *table[T]) sort() {
+	sort.Sort(sort.Reverse(sort.IntSlice(t.bits)))
+	sort.Slice(t.ranges, func(i, j int) bool { return t.ranges[i].start.Less(t.ranges[j].start) })
+}
+
+func (t *table[T]) len() int {
+	return len(t.prefixes) + len(t.ranges)
+}
+
+func (t *table[T]) lookup(a netip.Addr) (T, bool) {
+	var zero T
+	if t == nil {
+		return zero, false
+	}
+	a = a.Unmap()
+	for _, bits := range t.bits {
+		p, err := a.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		if v, ok := t.prefixes[p]; ok {
+			return v, true
+		}
+	}
+	// The last range starting at or before the address
+	i := sort.Search(len(t.ranges), func(i int) bool { return a.Less(t.ranges[i].start) }) - 1
+	if i >= 0 && t.ranges[i].start.BitLen() == a.BitLen() && !t.ranges[i].end.Less(a) {
+		return t.ranges[i].value, true
+	}
+	return zero, false
+}
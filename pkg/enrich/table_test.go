@@ -0,0 +1,86 @@
+package enrich
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestTableLookupPrefix(t *testing.T) {
+	tbl := newTable[string]()
+	for p, v := range map[string]string{
+		"192.0.2.0/24":    "v4 /24",
+		"192.0.2.200/25":  "v4 /25", // added unmasked
+		"192.0.2.1/32":    "v4 host",
+		"2001:db8::/32":   "v6 /32",
+		"2001:db8:1::/48": "v6 /48",
+		"::/0":            "v6 default",
+	} {
+		tbl.addPrefix(netip.MustParsePrefix(p), v)
+	}
+	tbl.sort()
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"192.0.2.1", "v4 host"},
+		{"192.0.2.2", "v4 /24"},
+		{"192.0.2.127", "v4 /24"},
+		{"192.0.2.128", "v4 /25"},
+		{"::ffff:192.0.2.255", "v4 /25"},
+		{"2001:db8:1::1", "v6 /48"},
+		{"2001:db8:2::1", "v6 /32"},
+		{"2001:db9::1", "v6 default"},
+		// IPv4 addresses do not match the IPv6 default route
+		{"198.51.100.1", ""},
+		// An IPv6 address with the bits of an IPv4 prefix does not match it
+		{"c000:200::", "v6 default"},
+	}
+	for _, tt := range tests {
+		got, ok := tbl.lookup(netip.MustParseAddr(tt.addr))
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("lookup(%s) = %q, %t, want %q", tt.addr, got, ok, tt.want)
+		}
+	}
+}
+
+func TestTableLookupRange(t *testing.T) {
+	tbl := newTable[string]()
+	// Ranges are added out of order
+	tbl.addRange(netip.MustParseAddr("2001:db8::"), netip.MustParseAddr("2001:db8::ffff"), "v6")
+	tbl.addRange(netip.MustParseAddr("198.51.100.0"), netip.MustParseAddr("198.51.100.127"), "second")
+	tbl.addRange(netip.MustParseAddr("192.0.2.0"), netip.MustParseAddr("192.0.2.255"), "first")
+	tbl.addRange(netip.MustParseAddr("192.0.3.0"), netip.MustParseAddr("192.0.3.0"), "single")
+	tbl.sort()
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"0.0.0.0", ""},
+		{"192.0.1.255", ""},
+		{"192.0.2.0", "first"},
+		{"192.0.2.255", "first"},
+		{"::ffff:192.0.2.7", "first"},
+		{"192.0.3.0", "single"},
+		{"192.0.3.1", ""},
+		{"198.51.100.127", "second"},
+		{"198.51.100.128", ""},
+		{"255.255.255.255", ""},
+		// IPv6 addresses sort after IPv4 ones, the last IPv4 range must not contain them
+		{"::", ""},
+		{"2001:db7:ffff:ffff:ffff:ffff:ffff:ffff", ""},
+		{"2001:db8::", "v6"},
+		{"2001:db8::ffff", "v6"},
+		{"2001:db8::1:0", ""},
+	}
+	for _, tt := range tests {
+		got, ok := tbl.lookup(netip.MustParseAddr(tt.addr))
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("lookup(%s) = %q, %t, want %q", tt.addr, got, ok, tt.want)
+		}
+	}
+
+	var empty *table[string]
+	if _, ok := empty.lookup(netip.MustParseAddr("192.0.2.1")); ok {
+		t.Error("lookup in nil table succeeded")
+	}
+}
@@ -0,0 +1,10 @@
+package errs
+
+import (
+	"errors"
+)
+
+var (
+	TargetRateLimited = errors.New("too many probes towards target")
+	ClientBlocked     = errors.New("client temporarily blocked")
+)
@@ -0,0 +1,10 @@
+package errs
+
+import (
+	"errors"
+)
+
+var (
+	ASNamesUnavailable = errors.New("AS names unavailable")
+	TooManyASNs        = errors.New("too many ASNs")
+)
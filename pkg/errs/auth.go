@@ -0,0 +1,15 @@
+package errs
+
+import (
+	"errors"
+)
+
+var (
+	TokenInvalid       = errors.New("token invalid")
+	TokenExpired       = errors.New("token expired")
+	QuotaExceeded      = errors.New("token quota exceeded")
+	OperationForbidden = errors.New("operation not permitted")
+	RouterForbidden    = errors.New("router not permitted")
+	AuthRequired       = errors.New("authentication required")
+	RoleMissing        = errors.New("no permitted role")
+)
@@ -0,0 +1,9 @@
+package errs
+
+import (
+	"errors"
+)
+
+var (
+	CacheMiss = errors.New("cache miss")
+)
@@ -0,0 +1,10 @@
+package errs
+
+import (
+	"errors"
+)
+
+var (
+	ChallengeRequired = errors.New("challenge solution required")
+	ChallengeInvalid  = errors.New("challenge solution invalid")
+)
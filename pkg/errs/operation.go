@@ -0,0 +1,12 @@
+package errs
+
+import (
+	"errors"
+)
+
+var (
+	OperationInvalid = errors.New("operation invalid")
+	ParamUnknown     = errors.New("parameter unknown")
+	ParamMissing     = errors.New("parameter missing")
+	ParamInvalid     = errors.New("parameter invalid")
+)
@@ -0,0 +1,10 @@
+package errs
+
+import (
+	"errors"
+)
+
+var (
+	ParserUnknown   = errors.New("parser unknown")
+	OutputMalformed = errors.New("output malformed")
+)
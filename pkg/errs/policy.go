@@ -0,0 +1,9 @@
+package errs
+
+import (
+	"errors"
+)
+
+var (
+	TargetDenied = errors.New("target denied")
+)
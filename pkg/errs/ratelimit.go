@@ -0,0 +1,9 @@
+package errs
+
+import (
+	"errors"
+)
+
+var (
+	RateLimited = errors.New("rate limit exceeded")
+)
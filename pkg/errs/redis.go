@@ -0,0 +1,9 @@
+package errs
+
+import (
+	"errors"
+)
+
+var (
+	RedisAddrsAmbiguous = errors.New("several Redis addresses need master_name for Sentinel or cluster for Redis Cluster")
+)
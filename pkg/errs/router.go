@@ -8,4 +8,8 @@ var (
 	UnknownRouter     = errors.New("router unknown")
 	RouterUnavailable = errors.New("router unavailable")
 	OperationUnknown  = errors.New("operation unknown")
+	QueueFull         = errors.New("router queue full")
+	NoRouters         = errors.New("no matching router")
+	TooManyRouters    = errors.New("too many routers")
+	NoBestPath        = errors.New("no best path")
 )
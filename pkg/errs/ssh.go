@@ -8,4 +8,8 @@ var (
 	AuthFailed       = errors.New("authentication error")
 	ExecFailed       = errors.New("execution error")
 	ConnectionFailed = errors.New("connection error")
+	SessionRefused   = errors.New("session refused")
+	HostKeyMismatch  = errors.New("host key mismatch")
+	HostKeyUnknown   = errors.New("host key unknown")
+	HostKeyInvalid   = errors.New("host key invalid")
 )
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/AS203038/looking-glass/pkg/http/grpc"
+	pb "github.com/AS203038/looking-glass/protobuf/lookingglass/v0"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// _connectStatus maps errors of the API to HTTP status codes.
+// Errors without code are those of invalid targets.
+var _connectStatus = map[connect.Code]int{
+	connect.CodeUnknown:            http.StatusBadRequest,
+	connect.CodeInvalidArgument:    http.StatusBadRequest,
+	connect.CodeUnauthenticated:    http.StatusUnauthorized,
+	connect.CodePermissionDenied:   http.StatusForbidden,
+	connect.CodeNotFound:           http.StatusNotFound,
+	connect.CodeResourceExhausted:  http.StatusTooManyRequests,
+	connect.CodeFailedPrecondition: http.StatusPreconditionFailed,
+	connect.CodeUnavailable:        http.StatusServiceUnavailable,
+	connect.CodeInternal:           http.StatusInternalServerError,
+}
+
+// bgpmapHandler serves the BGP map of the API as SVG, DOT or JSON for clients without JavaScript.
+// The request runs in-process with the checks of the API, so it is authenticated, rate limited and challenged like BGPMap.
+//
+//	GET /bgpmap?target=192.0.2.0/24&router=1,2&format=svg
+//
+// Routers are selected by the router, location or all parameters, the format is one of svg (default), dot or json.
+func bgpmapHandler(bgpmap grpc.LocalBGPMap) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		q := r.URL.Query()
+		msg := &pb.BGPMapRequest{
+			Target:   q.Get("target"),
+			Location: q.Get("location"),
+		}
+		msg.All, _ = strconv.ParseBool(q.Get("all"))
+		for _, v := range q["router"] {
+			for _, s := range strings.Split(v, ",") {
+				id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+				if err != nil {
+					http.Error(w, "invalid router "+strconv.Quote(s), http.StatusBadRequest)
+					return
+				}
+				msg.RouterIds = append(msg.RouterIds, id)
+			}
+		}
+		format := q.Get("format")
+		if format == "" {
+			format = "svg"
+		}
+		if format != "svg" && format != "dot" && format != "json" {
+			http.Error(w, "invalid format "+strconv.Quote(format), http.StatusBadRequest)
+			return
+		}
+
+		req := connect.NewRequest(msg)
+		for _, h := range []string{"Authorization", grpc.ChallengeHeader} {
+			if v := r.Header.Get(h); v != "" {
+				req.Header().Set(h, v)
+			}
+		}
+		res, err := bgpmap(r.Context(), req, connect.Peer{Addr: r.RemoteAddr, Protocol: connect.ProtocolConnect})
+		if err != nil {
+			status, ok := _connectStatus[connect.CodeOf(err)]
+			if !ok {
+				status = http.StatusBadGateway
+			}
+			var cerr *connect.Error
+			if errors.As(err, &cerr) {
+				for _, h := range []string{"Retry-After", grpc.ChallengeIssuedHeader, grpc.ChallengeDifficultyHeader} {
+					if v := cerr.Meta().Get(h); v != "" {
+						w.Header().Set(h, v)
+					}
+				}
+				err = errors.New(cerr.Message())
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		if len(res.Msg.GetNodes()) == 0 && len(res.Msg.GetErrors()) > 0 {
+			// Without any paths the map would be empty
+			var ids []int64
+			for id := range res.Msg.GetErrors() {
+				ids = append(ids, id)
+			}
+			slices.Sort(ids)
+			var msgs []string
+			for _, id := range ids {
+				msgs = append(msgs, fmt.Sprintf("router %d: %s", id, res.Msg.GetErrors()[id]))
+			}
+			http.Error(w, strings.Join(msgs, "\n"), http.StatusBadGateway)
+			return
+		}
+
+		// Results are only cached by the API
+		w.Header().Set("Cache-Control", "no-store")
+		switch format {
+		case "svg":
+			w.Header().Set("Content-Type", "image/svg+xml")
+			w.Write([]byte(res.Msg.GetSvg()))
+		case "dot":
+			w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+			w.Write([]byte(res.Msg.GetDot()))
+		case "json":
+			res.Msg.Dot, res.Msg.Svg = "", ""
+			b, err := protojson.Marshal(res.Msg)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(b)
+		}
+	})
+}
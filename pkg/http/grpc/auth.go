@@ -0,0 +1,136 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/AS203038/looking-glass/pkg/auth"
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+// authInterceptor authenticates bearer tokens and enforces the scopes of the principal.
+// Requests without token are anonymous, they may not use hidden routers and only the operations open to anonymous requests.
+type authInterceptor struct {
+	auth *auth.Authenticator
+	rts  utils.RouterMap
+}
+
+func (i *authInterceptor) authenticate(ctx context.Context, header http.Header) (context.Context, error) {
+	h := header.Get("Authorization")
+	if h == "" {
+		return ctx, nil
+	}
+	bearer, ok := strings.CutPrefix(h, "Bearer ")
+	if !ok {
+		return nil, connect.NewError(connect.CodeUnauthenticated, errs.TokenInvalid)
+	}
+	p, err := i.auth.Authenticate(ctx, bearer)
+	if errors.Is(err, errs.RoleMissing) {
+		return nil, connect.NewError(connect.CodePermissionDenied, err)
+	} else if err != nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, err)
+	}
+	return auth.WithPrincipal(ctx, p), nil
+}
+
+func (i *authInterceptor) authorize(p *auth.Principal, procedure string, msg any) error {
+	if r, ok := msg.(routerRequest); ok {
+		if ri, ok := i.rts.GetByID(r.GetRouterId()); ok {
+			if err := routerAccess(p, ri); err != nil {
+				return err
+			}
+		}
+	}
+	op, ok := operationOf(procedure, msg)
+	if !ok {
+		return nil
+	}
+	if !allowsOperation(i.auth, p, op) {
+		if p == nil {
+			return connect.NewError(connect.CodeUnauthenticated, errs.AuthRequired)
+		}
+		return connect.NewError(connect.CodePermissionDenied, errs.OperationForbidden)
+	}
+	if wait, err := p.Use(routerCount(i.rts, p, msg)); err != nil {
+		return retryError(err, wait)
+	}
+	return nil
+}
+
+// allowsOperation returns whether the principal may run the operation, p is nil for anonymous requests.
+func allowsOperation(a *auth.Authenticator, p *auth.Principal, op string) bool {
+	if p == nil && !a.AnonymousAllows(op) {
+		return false
+	}
+	return p.AllowsOperation(op)
+}
+
+// routerAccess returns the error for a principal that may not use the router, nil if it may.
+func routerAccess(p *auth.Principal, ri *utils.RouterInstance) error {
+	if p.AllowsRouter(ri.Config) {
+		return nil
+	}
+	if p == nil {
+		return connect.NewError(connect.CodeUnauthenticated, errs.AuthRequired)
+	}
+	if ri.Config.Hidden && !p.Hidden {
+		// Hidden routers do not exist for those that cannot see them
+		return errs.UnknownRouter
+	}
+	return connect.NewError(connect.CodePermissionDenied, errs.RouterForbidden)
+}
+
+func (i *authInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		ctx, err := i.authenticate(ctx, req.Header())
+		if err != nil {
+			return nil, err
+		}
+		if err := i.authorize(auth.FromContext(ctx), req.Spec().Procedure, req.Any()); err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+func (i *authInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *authInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx, err := i.authenticate(ctx, conn.RequestHeader())
+		if err != nil {
+			return err
+		}
+		p := auth.FromContext(ctx)
+		return next(ctx, &checkConn{StreamingHandlerConn: conn, check: func(msg any) error {
+			return i.authorize(p, conn.Spec().Procedure, msg)
+		}})
+	}
+}
+
+// checkConn checks the request of a stream once it is received.
+// Interceptors use it where the operation may only be known from the request.
+type checkConn struct {
+	connect.StreamingHandlerConn
+	check   func(msg any) error
+	checked bool
+}
+
+func (c *checkConn) Receive(msg any) error {
+	if err := c.StreamingHandlerConn.Receive(msg); err != nil {
+		return err
+	}
+	if !c.checked {
+		if err := c.check(msg); err != nil {
+			return err
+		}
+		c.checked = true
+	}
+	return nil
+}
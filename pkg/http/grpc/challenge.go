@@ -0,0 +1,78 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"connectrpc.com/connect"
+	"github.com/AS203038/looking-glass/pkg/auth"
+	"github.com/AS203038/looking-glass/pkg/pow"
+	"github.com/AS203038/looking-glass/pkg/ratelimit"
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+// ChallengeHeader carries the solved challenge, see GetChallenge.
+const ChallengeHeader = "X-LG-Challenge"
+
+// Errors of requests without valid solution carry a new challenge and the difficulty the request needs,
+// clients solve it and retry instead of knowing which requests need one.
+const (
+	ChallengeIssuedHeader     = "X-LG-Challenge-Issued"
+	ChallengeDifficultyHeader = "X-LG-Challenge-Difficulty"
+)
+
+// challengeInterceptor requires a solved challenge for the operations configured as expensive.
+// Authenticated requests do not need one, requests running their operation on several routers need a harder one, see pow.ExtraBits.
+type challengeInterceptor struct {
+	issuer *pow.Issuer
+	rts    utils.RouterMap
+}
+
+func (i *challengeInterceptor) verify(ctx context.Context, procedure string, msg any, peer connect.Peer, solution string) error {
+	if auth.FromContext(ctx) != nil {
+		return nil
+	}
+	op, ok := operationOf(procedure, msg)
+	if !ok || !i.issuer.Requires(op) {
+		return nil
+	}
+	client, n := challengeClient(peer), routerCount(i.rts, nil, msg)
+	if err := i.issuer.Verify(client, solution, n); err != nil {
+		cerr := connect.NewError(connect.CodeUnauthenticated, err)
+		challenge, difficulty, _ := i.issuer.Issue(client)
+		cerr.Meta().Set(ChallengeIssuedHeader, challenge)
+		cerr.Meta().Set(ChallengeDifficultyHeader, strconv.Itoa(difficulty+pow.ExtraBits(n)))
+		return cerr
+	}
+	return nil
+}
+
+func (i *challengeInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if err := i.verify(ctx, req.Spec().Procedure, req.Any(), req.Peer(), req.Header().Get(ChallengeHeader)); err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+func (i *challengeInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *challengeInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		return next(ctx, &checkConn{StreamingHandlerConn: conn, check: func(msg any) error {
+			return i.verify(ctx, conn.Spec().Procedure, msg, conn.Peer(), conn.RequestHeader().Get(ChallengeHeader))
+		}})
+	}
+}
+
+// challengeClient returns the client challenges are bound to, the same a client is rate limited by.
+func challengeClient(peer connect.Peer) string {
+	if ip := net.ParseIP(clientIP(peer)); ip != nil {
+		return ratelimit.ClientKey(ip)
+	}
+	return clientIP(peer)
+}
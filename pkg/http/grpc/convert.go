@@ -0,0 +1,230 @@
+package grpc
+
+import (
+	"sort"
+
+	"github.com/AS203038/looking-glass/pkg/bgpmap"
+	"github.com/AS203038/looking-glass/pkg/enrich"
+	"github.com/AS203038/looking-glass/pkg/rpki"
+	"github.com/AS203038/looking-glass/pkg/utils"
+	pb "github.com/AS203038/looking-glass/protobuf/lookingglass/v0"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+)
+
+func bgpNeighborsToProto(nbrs []*utils.BGPNeighbor) []*pb.BGPNeighbor {
+	var ret []*pb.BGPNeighbor
+	for _, n := range nbrs {
+		ret = append(ret, &pb.BGPNeighbor{
+			Address:          n.Address,
+			Asn:              n.ASN,
+			State:            n.State,
+			Uptime:           durationpb.New(n.Uptime),
+			PrefixesReceived: n.PrefixesReceived,
+			PrefixesSent:     n.PrefixesSent,
+			AddressFamily:    n.AddressFamily,
+			Description:      n.Description,
+		})
+	}
+	return ret
+}
+
+func pingStatsToProto(stats *utils.PingStats) *pb.PingStats {
+	if stats == nil {
+		return nil
+	}
+	ret := &pb.PingStats{
+		Sent:     stats.Sent,
+		Received: stats.Received,
+		Loss:     stats.Loss,
+		Min:      durationpb.New(stats.Min),
+		Avg:      durationpb.New(stats.Avg),
+		Max:      durationpb.New(stats.Max),
+		Mdev:     durationpb.New(stats.Mdev),
+	}
+	for _, p := range stats.Probes {
+		ret.Probes = append(ret.Probes, &pb.PingProbe{
+			Sequence: p.Sequence,
+			Success:  p.Success,
+			Rtt:      durationpb.New(p.RTT),
+			Ttl:      p.TTL,
+			Source:   p.Source,
+			Message:  p.Message,
+		})
+	}
+	return ret
+}
+
+func tracerouteHopsToProto(hops []*utils.TracerouteHop) []*pb.Hop {
+	var ret []*pb.Hop
+	for _, h := range hops {
+		hop := &pb.Hop{
+			Number:    h.Number,
+			Addresses: h.Addresses,
+			Mtu:       h.MTU,
+		}
+		for _, p := range h.Probes {
+			probe := &pb.HopProbe{
+				Address:    p.Address,
+				Hostname:   p.Hostname,
+				Timeout:    p.Timeout,
+				Annotation: p.Annotation,
+			}
+			if !p.Timeout {
+				probe.Rtt = durationpb.New(p.RTT)
+			}
+			hop.Probes = append(hop.Probes, probe)
+		}
+		for _, l := range h.MPLS {
+			hop.Mpls = append(hop.Mpls, &pb.MPLSLabel{
+				Label:         l.Label,
+				TrafficClass:  l.TrafficClass,
+				BottomOfStack: l.BottomOfStack,
+				Ttl:           l.TTL,
+			})
+		}
+		ret = append(ret, hop)
+	}
+	return ret
+}
+
+func hopInfoToProto(info *enrich.Info) *pb.HopInfo {
+	return &pb.HopInfo{
+		Address: info.Address,
+		Ptr:     info.PTR,
+		Asn:     info.ASN,
+		AsName:  info.ASName,
+		Ixp:     info.IXP,
+		IxpName: info.IXPName,
+	}
+}
+
+// asInfoToProto returns the AS info, only with the number if the AS is unknown.
+func asInfoToProto(asn uint32, as *enrich.ASInfo) *pb.ASInfo {
+	if as == nil {
+		return &pb.ASInfo{Asn: asn}
+	}
+	return &pb.ASInfo{
+		Asn:          asn,
+		Name:         as.Name,
+		Organization: as.Org,
+		Country:      as.Country,
+	}
+}
+
+// rpkiValidationToProto returns the validation, nil if the path was not validated.
+func rpkiValidationToProto(v *rpki.Validation) *pb.RPKIValidation {
+	if v == nil {
+		return nil
+	}
+	ret := &pb.RPKIValidation{
+		State:       v.State,
+		Reason:      v.Reason,
+		Origin:      v.Origin,
+		Description: v.Description,
+	}
+	for _, r := range v.ROAs {
+		ret.Roas = append(ret.Roas, &pb.ROA{
+			Prefix:      r.Prefix.String(),
+			MaxLength:   uint32(r.MaxLength),
+			Asn:         r.ASN,
+			TrustAnchor: r.TA,
+		})
+	}
+	return ret
+}
+
+func bgpPathsToProto(paths []*utils.BGPPath) []*pb.BGPPath {
+	var ret []*pb.BGPPath
+	for _, p := range paths {
+		var segments []*pb.ASPathSegment
+		for _, seg := range p.ASPathSegments {
+			segments = append(segments, &pb.ASPathSegment{Type: seg.Type, Asns: seg.ASNs})
+		}
+		ret = append(ret, &pb.BGPPath{
+			Prefix:           p.Prefix,
+			NextHop:          p.NextHop,
+			AsPath:           p.ASPath,
+			Communities:      p.Communities,
+			LargeCommunities: p.LargeCommunities,
+			LocalPref:        p.LocalPref,
+			Med:              p.MED,
+			Origin:           p.Origin,
+			Best:             p.Best,
+			Age:              durationpb.New(p.Age),
+			Peer:             p.Peer,
+			AsPathSegments:   segments,
+		})
+	}
+	return ret
+}
+
+// asPathSegmentsFromProto returns the segments of the AS path, a single AS_SEQUENCE if the router does not report them.
+func asPathSegmentsFromProto(p *pb.BGPPath) []utils.ASPathSegment {
+	if len(p.GetAsPathSegments()) == 0 {
+		if len(p.GetAsPath()) == 0 {
+			return nil
+		}
+		return []utils.ASPathSegment{{Type: utils.ASSequence, ASNs: p.GetAsPath()}}
+	}
+	var ret []utils.ASPathSegment
+	for _, seg := range p.GetAsPathSegments() {
+		ret = append(ret, utils.ASPathSegment{Type: seg.GetType(), ASNs: seg.GetAsns()})
+	}
+	return ret
+}
+
+func operationsToProto(ops map[string]*utils.Operation) []*pb.Operation {
+	var names []string
+	for name := range ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var ret []*pb.Operation
+	for _, name := range names {
+		op := ops[name]
+		var params []*pb.OperationParam
+		for _, p := range op.Params {
+			params = append(params, &pb.OperationParam{
+				Name:        p.Name,
+				Type:        string(p.Type),
+				Description: p.Description,
+				Optional:    p.Optional,
+				Default:     p.Default,
+				Values:      p.Values,
+				Min:         p.Min,
+				Max:         p.Max,
+			})
+		}
+		ret = append(ret, &pb.Operation{
+			Name:        name,
+			Description: op.Description,
+			Params:      params,
+			Builtin:     op.Builtin,
+		})
+	}
+	return ret
+}
+
+func bgpMapToProto(g *bgpmap.Graph) ([]*pb.BGPMapNode, []*pb.BGPMapEdge) {
+	var nodes []*pb.BGPMapNode
+	for _, n := range g.Nodes {
+		nodes = append(nodes, &pb.BGPMapNode{
+			Id:     n.ID,
+			Label:  n.Label,
+			Type:   n.Type,
+			Origin: n.Origin,
+			Name:   n.Name,
+		})
+	}
+	var edges []*pb.BGPMapEdge
+	for _, e := range g.Edges {
+		edges = append(edges, &pb.BGPMapEdge{
+			From:     e.From,
+			To:       e.To,
+			Routers:  e.Routers,
+			Best:     e.Best,
+			Prepends: uint32(e.Prepends),
+		})
+	}
+	return nodes, edges
+}
@@ -6,18 +6,60 @@ import (
 	"net/http"
 	"time"
 
+	"connectrpc.com/connect"
 	"connectrpc.com/grpchealth"
+	"github.com/AS203038/looking-glass/pkg/abuse"
+	"github.com/AS203038/looking-glass/pkg/auth"
+	"github.com/AS203038/looking-glass/pkg/cache"
+	"github.com/AS203038/looking-glass/pkg/enrich"
+	"github.com/AS203038/looking-glass/pkg/pow"
+	"github.com/AS203038/looking-glass/pkg/ratelimit"
+	"github.com/AS203038/looking-glass/pkg/rpki"
 	"github.com/AS203038/looking-glass/pkg/utils"
+	pb "github.com/AS203038/looking-glass/protobuf/lookingglass/v0"
 	"github.com/AS203038/looking-glass/protobuf/lookingglass/v0/lookingglassconnect"
 )
 
 var Health = grpchealth.NewStaticChecker(lookingglassconnect.LookingGlassServiceName)
 
-func Mux(ctx context.Context, mux *http.ServeMux, rts utils.RouterMap) {
-	mux.Handle(lookingglassconnect.NewLookingGlassServiceHandler(NewLookingGlassService(ctx, rts)))
+// LocalBGPMap runs BGPMap in-process for handlers that serve the map outside of the API, for the client at peer.
+// The request passes the same checks as those of the API.
+type LocalBGPMap func(ctx context.Context, req *connect.Request[pb.BGPMapRequest], peer connect.Peer) (*connect.Response[pb.BGPMapResponse], error)
+
+func Mux(ctx context.Context, mux *http.ServeMux, rts utils.RouterMap, c *cache.Cache, limits *ratelimit.Limits, g *abuse.Guard, p *pow.Issuer, a *auth.Authenticator, e *enrich.Enricher, v *rpki.Validator) LocalBGPMap {
+	svc := NewLookingGlassService(ctx, rts, c, g, p, e, v, a)
+	mi := &metricsInterceptor{rts: rts}
+	ai := &authInterceptor{auth: a, rts: rts}
+	ci := &challengeInterceptor{issuer: p, rts: rts}
+	ri := &rateLimitInterceptor{limits: limits, rts: rts}
+	mux.Handle(lookingglassconnect.NewLookingGlassServiceHandler(svc, connect.WithInterceptors(mi, ai, ci, ri)))
 	mux.Handle(grpchealth.NewHandler(Health))
 	Health.SetStatus(lookingglassconnect.LookingGlassServiceName, grpchealth.StatusServing)
 	go healthcheck(ctx, rts)
+	return localBGPMap(svc, mi, ai, ci, ri)
+}
+
+// localBGPMap applies the checks of the interceptors in their order, as requests of handlers have neither peer nor procedure.
+func localBGPMap(svc *LookingGlassService, mi *metricsInterceptor, ai *authInterceptor, ci *challengeInterceptor, ri *rateLimitInterceptor) LocalBGPMap {
+	const procedure = lookingglassconnect.LookingGlassServiceBGPMapProcedure
+	return func(ctx context.Context, req *connect.Request[pb.BGPMapRequest], peer connect.Peer) (res *connect.Response[pb.BGPMapResponse], err error) {
+		defer func(start time.Time) {
+			mi.observe(procedure, mi.router(req.Msg), start, err)
+		}(time.Now())
+		if ctx, err = ai.authenticate(ctx, req.Header()); err != nil {
+			return nil, err
+		}
+		if err = ai.authorize(auth.FromContext(ctx), procedure, req.Msg); err != nil {
+			return nil, err
+		}
+		if err = ci.verify(ctx, procedure, req.Msg, peer, req.Header().Get(ChallengeHeader)); err != nil {
+			return nil, err
+		}
+		if err = ri.allow(ctx, procedure, req.Msg, peer); err != nil {
+			return nil, err
+		}
+		return svc.bgpMap(ctx, req.Msg, clientIP(peer))
+	}
 }
 
 func healthcheck(ctx context.Context, rts utils.RouterMap) {
@@ -29,7 +71,7 @@ func healthcheck(ctx context.Context, rts utils.RouterMap) {
 		case <-ticker.C:
 			for _, r := range rts {
 				o := r.HealthCheck.Healthy
-				if err := r.Healthcheck(); err == nil {
+				if err := r.Healthcheck(ctx); err == nil {
 					if !o {
 						Health.SetStatus(lookingglassconnect.LookingGlassServiceName+"/"+r.Config.Name, grpchealth.StatusServing)
 						log.Printf("NOTICE: Router %s is healthy", r.Config.Name)
@@ -0,0 +1,75 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/AS203038/looking-glass/pkg/metrics"
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+// routerRequest is implemented by all requests that target a single router.
+type routerRequest interface {
+	GetRouterId() int64
+}
+
+// metricsInterceptor records the number and duration of RPCs by procedure and router.
+type metricsInterceptor struct {
+	rts utils.RouterMap
+}
+
+func (i *metricsInterceptor) router(msg any) string {
+	if r, ok := msg.(routerRequest); ok {
+		if ri, ok := i.rts.GetByID(r.GetRouterId()); ok {
+			return ri.Config.Name
+		}
+	}
+	return ""
+}
+
+func (i *metricsInterceptor) observe(procedure string, router string, start time.Time, err error) {
+	code := "ok"
+	if err != nil {
+		code = connect.CodeOf(err).String()
+	}
+	metrics.RPCRequests.WithLabelValues(procedure, router, code).Inc()
+	metrics.RPCDuration.WithLabelValues(procedure, router).Observe(time.Since(start).Seconds())
+}
+
+func (i *metricsInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		start := time.Now()
+		res, err := next(ctx, req)
+		i.observe(req.Spec().Procedure, i.router(req.Any()), start, err)
+		return res, err
+	}
+}
+
+func (i *metricsInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *metricsInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		start := time.Now()
+		rc := &receiveConn{StreamingHandlerConn: conn}
+		err := next(ctx, rc)
+		i.observe(conn.Spec().Procedure, i.router(rc.msg), start, err)
+		return err
+	}
+}
+
+// receiveConn remembers the first request received on a stream.
+type receiveConn struct {
+	connect.StreamingHandlerConn
+	msg any
+}
+
+func (c *receiveConn) Receive(msg any) error {
+	err := c.StreamingHandlerConn.Receive(msg)
+	if err == nil && c.msg == nil {
+		c.msg = msg
+	}
+	return err
+}
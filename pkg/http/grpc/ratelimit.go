@@ -0,0 +1,109 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/AS203038/looking-glass/pkg/auth"
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/metrics"
+	"github.com/AS203038/looking-glass/pkg/ratelimit"
+	"github.com/AS203038/looking-glass/pkg/utils"
+	"github.com/AS203038/looking-glass/protobuf/lookingglass/v0/lookingglassconnect"
+)
+
+// _procedures maps the procedures that run operations on routers to the operation, Execute names its operation itself.
+var _procedures = map[string]string{
+	lookingglassconnect.LookingGlassServicePingProcedure:             "ping",
+	lookingglassconnect.LookingGlassServiceStreamPingProcedure:       "ping",
+	lookingglassconnect.LookingGlassServiceTracerouteProcedure:       "traceroute",
+	lookingglassconnect.LookingGlassServiceStreamTracerouteProcedure: "traceroute",
+	lookingglassconnect.LookingGlassServiceBGPSummaryProcedure:       "bgp.summary",
+	lookingglassconnect.LookingGlassServiceBGPRouteProcedure:         "bgp.route",
+	lookingglassconnect.LookingGlassServiceBGPCommunityProcedure:     "bgp.community",
+	lookingglassconnect.LookingGlassServiceBGPASPathProcedure:        "bgp.aspath",
+	lookingglassconnect.LookingGlassServiceCompareRouteProcedure:     "bgp.route",
+	lookingglassconnect.LookingGlassServiceBGPMapProcedure:           "bgp.route",
+}
+
+// operationRequest is implemented by requests that name the operation to run.
+type operationRequest interface {
+	GetOperation() string
+}
+
+// rateLimitInterceptor rejects operations of clients that exceeded their rate limit.
+// Requests running their operation on several routers count once per router.
+type rateLimitInterceptor struct {
+	limits *ratelimit.Limits
+	rts    utils.RouterMap
+}
+
+// operationOf returns the operation run by the procedure, msg is the request if already received.
+func operationOf(procedure string, msg any) (string, bool) {
+	if r, ok := msg.(operationRequest); ok {
+		return r.GetOperation(), true
+	}
+	op, ok := _procedures[procedure]
+	return op, ok
+}
+
+// allow applies the limits of the client, or those of the principal if it has its own.
+func (i *rateLimitInterceptor) allow(ctx context.Context, procedure string, msg any, peer connect.Peer) error {
+	op, ok := operationOf(procedure, msg)
+	if !ok {
+		return nil
+	}
+	var allowed bool
+	var wait time.Duration
+	p := auth.FromContext(ctx)
+	if p != nil && p.Unlimited {
+		return nil
+	} else if p != nil && p.Limiter != nil {
+		allowed, wait = p.Limiter.AllowN(op, routerCount(i.rts, p, msg))
+	} else {
+		ip := net.ParseIP(clientIP(peer))
+		if ip == nil {
+			return nil
+		}
+		allowed, wait = i.limits.Allow(op, ip, routerCount(i.rts, p, msg))
+	}
+	if allowed {
+		return nil
+	}
+	metrics.RateLimited.WithLabelValues(op).Inc()
+	return retryError(errs.RateLimited, wait)
+}
+
+// retryError returns a resource_exhausted error telling the client when to retry, also as Retry-After header.
+func retryError(err error, wait time.Duration) *connect.Error {
+	retry := int(math.Ceil(wait.Seconds()))
+	cerr := connect.NewError(connect.CodeResourceExhausted, fmt.Errorf("%w, retry after %ds", err, retry))
+	cerr.Meta().Set("Retry-After", strconv.Itoa(retry))
+	return cerr
+}
+
+func (i *rateLimitInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if err := i.allow(ctx, req.Spec().Procedure, req.Any(), req.Peer()); err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+func (i *rateLimitInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *rateLimitInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		return next(ctx, &checkConn{StreamingHandlerConn: conn, check: func(msg any) error {
+			return i.allow(ctx, conn.Spec().Procedure, msg, conn.Peer())
+		}})
+	}
+}
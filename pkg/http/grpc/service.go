@@ -2,30 +2,114 @@ package grpc
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
 	"os"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"connectrpc.com/connect"
+	"github.com/AS203038/looking-glass/pkg/abuse"
+	"github.com/AS203038/looking-glass/pkg/auth"
+	"github.com/AS203038/looking-glass/pkg/bgpmap"
+	"github.com/AS203038/looking-glass/pkg/cache"
+	"github.com/AS203038/looking-glass/pkg/enrich"
 	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/parsers"
+	"github.com/AS203038/looking-glass/pkg/pow"
+	"github.com/AS203038/looking-glass/pkg/rpki"
 	"github.com/AS203038/looking-glass/pkg/utils"
 	pb "github.com/AS203038/looking-glass/protobuf/lookingglass/v0"
 	"github.com/AS203038/looking-glass/protobuf/lookingglass/v0/lookingglassconnect"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
 	emptypb "google.golang.org/protobuf/types/known/emptypb"
 	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type LookingGlassService struct {
 	lookingglassconnect.UnimplementedLookingGlassServiceHandler
-	ctx context.Context
-	rts utils.RouterMap
+	ctx    context.Context
+	rts    utils.RouterMap
+	cache  *cache.Cache
+	guard  *abuse.Guard
+	pow    *pow.Issuer
+	enrich *enrich.Enricher
+	rpki   *rpki.Validator
+	auth   *auth.Authenticator
 }
 
-func NewLookingGlassService(ctx context.Context, rts utils.RouterMap) lookingglassconnect.LookingGlassServiceHandler {
+func NewLookingGlassService(ctx context.Context, rts utils.RouterMap, c *cache.Cache, g *abuse.Guard, p *pow.Issuer, e *enrich.Enricher, v *rpki.Validator, a *auth.Authenticator) *LookingGlassService {
 	return &LookingGlassService{
-		ctx: ctx,
-		rts: rts,
+		ctx:    ctx,
+		rts:    rts,
+		cache:  c,
+		guard:  g,
+		pow:    p,
+		enrich: e,
+		rpki:   v,
+		auth:   a,
+	}
+}
+
+// do runs the operation through the cache, identical operations are identified by router, operation and normalized parameters.
+// Only operations that are actually executed wait for a slot on the router.
+func (s *LookingGlassService) do(ctx context.Context, ri *utils.RouterInstance, client string, op string, params []string, fn func(context.Context) ([]string, error)) (*cache.Entry, bool, error) {
+	return s.cache.Do(ctx, ri.Config.Name, op, params, func(ctx context.Context) ([]string, error) {
+		release, err := acquire(ctx, ri, client, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return fn(ctx)
+	})
+}
+
+// acquire waits for a slot on the router, see utils.Limiter.
+func acquire(ctx context.Context, ri *utils.RouterInstance, client string, position func(int)) (func(), error) {
+	release, err := ri.Limiter.Acquire(ctx, client, position)
+	if err == errs.QueueFull {
+		return nil, connect.NewError(connect.CodeResourceExhausted, err)
+	}
+	return release, err
+}
+
+// probe applies the abuse protection right before the operation is run against the target.
+func (s *LookingGlassService) probe(ctx context.Context, ri *utils.RouterInstance, op string, client string, target *utils.IPNet) error {
+	if wait, err := s.guard.Probe(ctx, op, ri.Config.Name, client, target); err != nil {
+		return retryError(err, wait)
+	}
+	return nil
+}
+
+// checkTarget applies the target policy of the router, hostnames are checked by the address they resolved to.
+func checkTarget(ri *utils.RouterInstance, op string, target *utils.IPNet) error {
+	if err := ri.Policy.Check(op, target); err != nil {
+		return connect.NewError(connect.CodePermissionDenied, err)
+	}
+	return nil
+}
+
+// clientIP returns the address of the client, the peer address is already that of the client behind trusted proxies.
+func clientIP(peer connect.Peer) string {
+	host, _, err := net.SplitHostPort(peer.Addr)
+	if err != nil {
+		return peer.Addr
+	}
+	return host
+}
+
+// setCacheHeader marks the response as served from the cache for the access log.
+func setCacheHeader(h http.Header, cached bool) {
+	if cached {
+		h.Set("X-Cache", "HIT")
+	} else {
+		h.Set("X-Cache", "MISS")
 	}
 }
 
@@ -44,7 +128,15 @@ func (s *LookingGlassService) GetRouters(ctx context.Context, req *connect.Reque
 	var ret []*pb.Router
 	lim := req.Msg.GetLimit()
 	page := req.Msg.GetPageToken()
-	len := uint32(len(s.rts))
+	// Routers the principal may not use are not listed, the IDs of the others do not change
+	p := auth.FromContext(ctx)
+	var ids []int
+	for k, v := range s.rts {
+		if p.AllowsRouter(v.Config) {
+			ids = append(ids, k)
+		}
+	}
+	len := uint32(len(ids))
 	if lim == 0 {
 		lim = 10
 	}
@@ -59,7 +151,8 @@ func (s *LookingGlassService) GetRouters(ctx context.Context, req *connect.Reque
 	if start > len {
 		return connect.NewResponse(&pb.GetRoutersResponse{}), nil
 	}
-	for k, v := range s.rts[start:end] {
+	for _, k := range ids[start:end] {
+		v := s.rts[k]
 		ret = append(ret, &pb.Router{
 			Name:     v.Config.Name,
 			Location: v.Config.Location,
@@ -90,22 +183,34 @@ func (s *LookingGlassService) Ping(ctx context.Context, req *connect.Request[pb.
 	if !ok {
 		return nil, errs.UnknownRouter
 	}
-	target, err := utils.NewIPNetFromProtobuf(req.Msg.GetTarget())
+	target, err := utils.NewHostFromProtobuf(req.Msg.GetTarget())
 	if err != nil {
 		return nil, err
 	}
-	ret, err := ri.Ping(target)
+	if err := checkTarget(ri, "ping", target); err != nil {
+		return nil, err
+	}
+	entry, cached, err := s.do(ctx, ri, clientIP(req.Peer()), "ping", []string{target.ToIP().String()}, func(ctx context.Context) ([]string, error) {
+		if err := s.probe(ctx, ri, "ping", clientIP(req.Peer()), target); err != nil {
+			return nil, err
+		}
+		return ri.Ping(ctx, target)
+	})
 	if err != nil {
 		return nil, err
 	}
-	ts := time.Now()
-	return connect.NewResponse(&pb.PingResponse{
-		Result: []byte(strings.Join(ret, "\n")),
+	ts := entry.Timestamp
+	res := connect.NewResponse(&pb.PingResponse{
+		Result: []byte(strings.Join(entry.Result, "\n")),
 		Timestamp: &timestamppb.Timestamp{
 			Seconds: ts.Unix(),
 			Nanos:   int32(ts.Nanosecond()),
 		},
-	}), nil
+		Stats:  parsePing(ri, entry.Result),
+		Cached: cached,
+	})
+	setCacheHeader(res.Header(), cached)
+	return res, nil
 }
 
 func (s *LookingGlassService) Traceroute(ctx context.Context, req *connect.Request[pb.TracerouteRequest]) (*connect.Response[pb.TracerouteResponse], error) {
@@ -114,22 +219,229 @@ func (s *LookingGlassService) Traceroute(ctx context.Context, req *connect.Reque
 	if !ok {
 		return nil, errs.UnknownRouter
 	}
-	target, err := utils.NewIPNetFromProtobuf(req.Msg.GetTarget())
+	target, err := utils.NewHostFromProtobuf(req.Msg.GetTarget())
 	if err != nil {
 		return nil, err
 	}
-	ret, err := ri.Traceroute(target)
+	if err := checkTarget(ri, "traceroute", target); err != nil {
+		return nil, err
+	}
+	entry, cached, err := s.do(ctx, ri, clientIP(req.Peer()), "traceroute", []string{target.ToIP().String()}, func(ctx context.Context) ([]string, error) {
+		if err := s.probe(ctx, ri, "traceroute", clientIP(req.Peer()), target); err != nil {
+			return nil, err
+		}
+		return ri.Traceroute(ctx, target)
+	})
 	if err != nil {
 		return nil, err
 	}
-	ts := time.Now()
-	return connect.NewResponse(&pb.TracerouteResponse{
-		Result: []byte(strings.Join(ret, "\n")),
+	ts := entry.Timestamp
+	hops := parseTraceroute(ri, entry.Result)
+	if unresolved := s.enrichHops(hops); len(unresolved) > 0 {
+		// Resolve for later requests without delaying this one
+		go s.enrich.Resolve(s.ctx, unresolved)
+	}
+	res := connect.NewResponse(&pb.TracerouteResponse{
+		Result: []byte(strings.Join(entry.Result, "\n")),
 		Timestamp: &timestamppb.Timestamp{
 			Seconds: ts.Unix(),
 			Nanos:   int32(ts.Nanosecond()),
 		},
-	}), nil
+		Hops:   hops,
+		Cached: cached,
+	})
+	setCacheHeader(res.Header(), cached)
+	return res, nil
+}
+
+func (s *LookingGlassService) StreamPing(ctx context.Context, req *connect.Request[pb.PingRequest], stream *connect.ServerStream[pb.StreamPingResponse]) error {
+	rt := req.Msg.GetRouterId()
+	ri, ok := s.rts.GetByID(rt)
+	if !ok {
+		return errs.UnknownRouter
+	}
+	target, err := utils.NewHostFromProtobuf(req.Msg.GetTarget())
+	if err != nil {
+		return err
+	}
+	if err := checkTarget(ri, "ping", target); err != nil {
+		return err
+	}
+	release, err := acquire(ctx, ri, clientIP(req.Peer()), func(pos int) {
+		stream.Send(&pb.StreamPingResponse{
+			Timestamp:     timestamppb.Now(),
+			QueuePosition: uint32(pos),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	defer release()
+	if err := s.probe(ctx, ri, "ping", clientIP(req.Peer()), target); err != nil {
+		return err
+	}
+	var out strings.Builder
+	w := &lineWriter{send: func(b []byte) error {
+		out.Write(b)
+		return stream.Send(&pb.StreamPingResponse{
+			Result:    b,
+			Timestamp: timestamppb.Now(),
+		})
+	}}
+	err = ri.StreamPing(ctx, target, w)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if stats := parsePing(ri, []string{out.String()}); stats != nil {
+		return stream.Send(&pb.StreamPingResponse{
+			Timestamp: timestamppb.Now(),
+			Stats:     stats,
+		})
+	}
+	return nil
+}
+
+// parsePing returns the parsed ping statistics or nil if the router has no ping parser or parsing failed.
+func parsePing(ri *utils.RouterInstance, out []string) *pb.PingStats {
+	p := ri.Router.Parser("ping")
+	if p.Decoder == "" {
+		return nil
+	}
+	stats, err := parsers.Ping(p, out)
+	if err != nil {
+		log.Printf("WARNING: Failed to parse ping of %s: %s", ri.Config.Name, err)
+		return nil
+	}
+	return pingStatsToProto(stats)
+}
+
+func (s *LookingGlassService) StreamTraceroute(ctx context.Context, req *connect.Request[pb.TracerouteRequest], stream *connect.ServerStream[pb.StreamTracerouteResponse]) error {
+	rt := req.Msg.GetRouterId()
+	ri, ok := s.rts.GetByID(rt)
+	if !ok {
+		return errs.UnknownRouter
+	}
+	target, err := utils.NewHostFromProtobuf(req.Msg.GetTarget())
+	if err != nil {
+		return err
+	}
+	if err := checkTarget(ri, "traceroute", target); err != nil {
+		return err
+	}
+	release, err := acquire(ctx, ri, clientIP(req.Peer()), func(pos int) {
+		stream.Send(&pb.StreamTracerouteResponse{
+			Timestamp:     timestamppb.Now(),
+			QueuePosition: uint32(pos),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	defer release()
+	if err := s.probe(ctx, ri, "traceroute", clientIP(req.Peer()), target); err != nil {
+		return err
+	}
+	var out strings.Builder
+	w := &lineWriter{send: func(b []byte) error {
+		out.Write(b)
+		return stream.Send(&pb.StreamTracerouteResponse{
+			Result:    b,
+			Timestamp: timestamppb.Now(),
+		})
+	}}
+	err = ri.StreamTraceroute(ctx, target, w)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	hops := parseTraceroute(ri, []string{out.String()})
+	if hops == nil {
+		return nil
+	}
+	unresolved := s.enrichHops(hops)
+	if err := stream.Send(&pb.StreamTracerouteResponse{
+		Timestamp: timestamppb.Now(),
+		Hops:      hops,
+	}); err != nil || len(unresolved) == 0 {
+		return err
+	}
+	// The raw output and hops are sent, send the hops again with the resolved names
+	s.enrich.Resolve(ctx, unresolved)
+	s.enrichHops(hops)
+	return stream.Send(&pb.StreamTracerouteResponse{
+		Timestamp: timestamppb.Now(),
+		Hops:      hops,
+	})
+}
+
+// enrichHops annotates the addresses of the hops from what is known without blocking
+// and returns the addresses whose reverse DNS names have to be resolved first.
+func (s *LookingGlassService) enrichHops(hops []*pb.Hop) []string {
+	if s.enrich == nil {
+		return nil
+	}
+	var addrs []string
+	for _, hop := range hops {
+		hop.Info = nil
+		for _, addr := range hop.GetAddresses() {
+			hop.Info = append(hop.Info, hopInfoToProto(s.enrich.Lookup(addr)))
+			addrs = append(addrs, addr)
+		}
+	}
+	return s.enrich.Unresolved(addrs)
+}
+
+// parseTraceroute returns the parsed hops or nil if the router has no traceroute parser or parsing failed.
+func parseTraceroute(ri *utils.RouterInstance, out []string) []*pb.Hop {
+	p := ri.Router.Parser("traceroute")
+	if p.Decoder == "" {
+		return nil
+	}
+	hops, err := parsers.Traceroute(p, out)
+	if err != nil {
+		log.Printf("WARNING: Failed to parse traceroute of %s: %s", ri.Config.Name, err)
+		return nil
+	}
+	return tracerouteHopsToProto(hops)
+}
+
+func (s *LookingGlassService) BGPSummary(ctx context.Context, req *connect.Request[pb.BGPSummaryRequest]) (*connect.Response[pb.BGPSummaryResponse], error) {
+	rt := req.Msg.GetRouterId()
+	ri, ok := s.rts.GetByID(rt)
+	if !ok {
+		return nil, errs.UnknownRouter
+	}
+	entry, cached, err := s.do(ctx, ri, clientIP(req.Peer()), "bgp.summary", nil, ri.BGPSummary)
+	if err != nil {
+		return nil, err
+	}
+	var neighbors []*pb.BGPNeighbor
+	p := ri.Router.Parser("bgp.summary")
+	result, out := p.Split(entry.Result)
+	if p.Decoder != "" {
+		nbrs, err := parsers.BGPSummary(p, out)
+		if err != nil {
+			log.Printf("WARNING: Failed to parse BGP summary of %s: %s", ri.Config.Name, err)
+		}
+		neighbors = bgpNeighborsToProto(nbrs)
+	}
+	ts := entry.Timestamp
+	res := connect.NewResponse(&pb.BGPSummaryResponse{
+		Result: []byte(strings.Join(result, "\n")),
+		Timestamp: &timestamppb.Timestamp{
+			Seconds: ts.Unix(),
+			Nanos:   int32(ts.Nanosecond()),
+		},
+		Neighbors: neighbors,
+		Cached:    cached,
+	})
+	setCacheHeader(res.Header(), cached)
+	return res, nil
 }
 
 func (s *LookingGlassService) BGPRoute(ctx context.Context, req *connect.Request[pb.BGPRouteRequest]) (*connect.Response[pb.BGPRouteResponse], error) {
@@ -142,18 +454,28 @@ func (s *LookingGlassService) BGPRoute(ctx context.Context, req *connect.Request
 	if err != nil {
 		return nil, err
 	}
-	ret, err := ri.BGPRoute(target)
+	if err := checkTarget(ri, "bgp.route", target); err != nil {
+		return nil, err
+	}
+	entry, cached, err := s.do(ctx, ri, clientIP(req.Peer()), "bgp.route", []string{target.ToIP().String() + "/" + target.CIDR}, func(ctx context.Context) ([]string, error) {
+		return ri.BGPRoute(ctx, target)
+	})
 	if err != nil {
 		return nil, err
 	}
-	ts := time.Now()
-	return connect.NewResponse(&pb.BGPRouteResponse{
-		Result: []byte(strings.Join(ret, "\n")),
+	ts := entry.Timestamp
+	result, out := ri.Router.Parser("bgp.route").Split(entry.Result)
+	res := connect.NewResponse(&pb.BGPRouteResponse{
+		Result: []byte(strings.Join(result, "\n")),
 		Timestamp: &timestamppb.Timestamp{
 			Seconds: ts.Unix(),
 			Nanos:   int32(ts.Nanosecond()),
 		},
-	}), nil
+		Paths:  s.parseBGPPaths(ri, "bgp.route", out),
+		Cached: cached,
+	})
+	setCacheHeader(res.Header(), cached)
+	return res, nil
 }
 
 func (s *LookingGlassService) BGPCommunity(ctx context.Context, req *connect.Request[pb.BGPCommunityRequest]) (*connect.Response[pb.BGPCommunityResponse], error) {
@@ -163,18 +485,26 @@ func (s *LookingGlassService) BGPCommunity(ctx context.Context, req *connect.Req
 		return nil, errs.UnknownRouter
 	}
 	community := req.Msg.GetCommunity()
-	ret, err := ri.BGPCommunity(strconv.Itoa(int(community.Asn)) + ":" + strconv.Itoa(int(community.Value)))
+	param := strconv.Itoa(int(community.Asn)) + ":" + strconv.Itoa(int(community.Value))
+	entry, cached, err := s.do(ctx, ri, clientIP(req.Peer()), "bgp.community", []string{param}, func(ctx context.Context) ([]string, error) {
+		return ri.BGPCommunity(ctx, param)
+	})
 	if err != nil {
 		return nil, err
 	}
-	ts := time.Now()
-	return connect.NewResponse(&pb.BGPCommunityResponse{
-		Result: []byte(strings.Join(ret, "\n")),
+	ts := entry.Timestamp
+	result, out := ri.Router.Parser("bgp.community").Split(entry.Result)
+	res := connect.NewResponse(&pb.BGPCommunityResponse{
+		Result: []byte(strings.Join(result, "\n")),
 		Timestamp: &timestamppb.Timestamp{
 			Seconds: ts.Unix(),
 			Nanos:   int32(ts.Nanosecond()),
 		},
-	}), nil
+		Paths:  s.parseBGPPaths(ri, "bgp.community", out),
+		Cached: cached,
+	})
+	setCacheHeader(res.Header(), cached)
+	return res, nil
 }
 
 func (s *LookingGlassService) BGPASPath(ctx context.Context, req *connect.Request[pb.BGPASPathRequest]) (*connect.Response[pb.BGPASPathResponse], error) {
@@ -187,16 +517,419 @@ func (s *LookingGlassService) BGPASPath(ctx context.Context, req *connect.Reques
 	if err != nil {
 		return nil, err
 	}
-	ret, err := ri.BGPASPath(aspath)
+	entry, cached, err := s.do(ctx, ri, clientIP(req.Peer()), "bgp.aspath", []string{aspath}, func(ctx context.Context) ([]string, error) {
+		return ri.BGPASPath(ctx, aspath)
+	})
 	if err != nil {
 		return nil, err
 	}
-	ts := time.Now()
-	return connect.NewResponse(&pb.BGPASPathResponse{
-		Result: []byte(strings.Join(ret, "\n")),
+	ts := entry.Timestamp
+	result, out := ri.Router.Parser("bgp.aspath").Split(entry.Result)
+	res := connect.NewResponse(&pb.BGPASPathResponse{
+		Result: []byte(strings.Join(result, "\n")),
 		Timestamp: &timestamppb.Timestamp{
 			Seconds: ts.Unix(),
 			Nanos:   int32(ts.Nanosecond()),
 		},
+		Paths:  s.parseBGPPaths(ri, "bgp.aspath", out),
+		Cached: cached,
+	})
+	setCacheHeader(res.Header(), cached)
+	return res, nil
+}
+
+// parseBGPPaths returns the parsed and annotated paths or nil if the router has no parser for the operation or parsing failed.
+func (s *LookingGlassService) parseBGPPaths(ri *utils.RouterInstance, op string, out []string) []*pb.BGPPath {
+	p := ri.Router.Parser(op)
+	if p.Decoder == "" {
+		return nil
+	}
+	paths, err := parsers.BGPPaths(p, out)
+	if err != nil {
+		log.Printf("WARNING: Failed to parse %s of %s: %s", op, ri.Config.Name, err)
+		return nil
+	}
+	return s.annotatePaths(bgpPathsToProto(paths))
+}
+
+// annotatePaths adds the names of the ASes to the AS paths if AS names are configured
+// and the RPKI origin validation if RPKI is enabled.
+func (s *LookingGlassService) annotatePaths(paths []*pb.BGPPath) []*pb.BGPPath {
+	for _, p := range paths {
+		if s.enrich.HasASNames() {
+			p.AsPathInfo = nil
+			for _, asn := range p.GetAsPath() {
+				p.AsPathInfo = append(p.AsPathInfo, asInfoToProto(asn, s.enrich.AS(asn)))
+			}
+		}
+		p.Rpki = rpkiValidationToProto(s.rpki.Validate(p.GetPrefix(), asPathSegmentsFromProto(p)))
+	}
+	return paths
+}
+
+// asName returns the name of the AS for the BGP map, empty if it is unknown.
+func (s *LookingGlassService) asName(asn uint32) string {
+	if !s.enrich.HasASNames() {
+		return ""
+	}
+	if as := s.enrich.AS(asn); as != nil {
+		return as.Name
+	}
+	return ""
+}
+
+// maxLookupASNs is the most ASNs of a LookupASN request.
+const maxLookupASNs = 256
+
+func (s *LookingGlassService) LookupASN(ctx context.Context, req *connect.Request[pb.LookupASNRequest]) (*connect.Response[pb.LookupASNResponse], error) {
+	if !s.enrich.HasASNames() {
+		return nil, connect.NewError(connect.CodeUnavailable, errs.ASNamesUnavailable)
+	}
+	if len(req.Msg.GetAsns()) > maxLookupASNs {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errs.TooManyASNs)
+	}
+	res := &pb.LookupASNResponse{}
+	for _, asn := range req.Msg.GetAsns() {
+		res.Asns = append(res.Asns, asInfoToProto(asn, s.enrich.AS(asn)))
+	}
+	return connect.NewResponse(res), nil
+}
+
+func (s *LookingGlassService) GetOperations(ctx context.Context, req *connect.Request[pb.GetOperationsRequest]) (*connect.Response[pb.GetOperationsResponse], error) {
+	rt := req.Msg.GetRouterId()
+	ri, ok := s.rts.GetByID(rt)
+	if !ok {
+		return nil, errs.UnknownRouter
+	}
+	// Operations the principal may not run are not listed
+	p := auth.FromContext(ctx)
+	ops := make(map[string]*utils.Operation)
+	for name, op := range ri.Router.Operations() {
+		if allowsOperation(s.auth, p, name) {
+			ops[name] = op
+		}
+	}
+	return connect.NewResponse(&pb.GetOperationsResponse{
+		Operations: operationsToProto(ops),
+	}), nil
+}
+
+func (s *LookingGlassService) Execute(ctx context.Context, req *connect.Request[pb.ExecuteRequest]) (*connect.Response[pb.ExecuteResponse], error) {
+	rt := req.Msg.GetRouterId()
+	ri, ok := s.rts.GetByID(rt)
+	if !ok {
+		return nil, errs.UnknownRouter
+	}
+	entry, cached, err := s.execute(ctx, ri, clientIP(req.Peer()), req.Msg.GetOperation(), req.Msg.GetParams())
+	if err != nil {
+		return nil, err
+	}
+	ts := entry.Timestamp
+	result, _ := ri.Router.Parser(req.Msg.GetOperation()).Split(entry.Result)
+	res := connect.NewResponse(&pb.ExecuteResponse{
+		Result: []byte(strings.Join(result, "\n")),
+		Timestamp: &timestamppb.Timestamp{
+			Seconds: ts.Unix(),
+			Nanos:   int32(ts.Nanosecond()),
+		},
+		Cached: cached,
+	})
+	setCacheHeader(res.Header(), cached)
+	return res, nil
+}
+
+// execute validates the parameters of the named operation and runs it on the router.
+func (s *LookingGlassService) execute(ctx context.Context, ri *utils.RouterInstance, client string, name string, raw map[string]string) (*cache.Entry, bool, error) {
+	op, ok := ri.Router.Operations()[name]
+	if !ok {
+		return nil, false, errs.OperationUnknown
+	}
+	values, err := op.Validate(raw)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, v := range values {
+		if target, ok := v.(*utils.IPNet); ok {
+			if err := checkTarget(ri, name, target); err != nil {
+				return nil, false, err
+			}
+		}
+	}
+	var params []string
+	for k, v := range values {
+		params = append(params, fmt.Sprintf("%s=%v", k, v))
+	}
+	sort.Strings(params)
+	return s.do(ctx, ri, client, name, params, func(ctx context.Context) ([]string, error) {
+		for _, v := range values {
+			if target, ok := v.(*utils.IPNet); ok {
+				if err := s.probe(ctx, ri, name, client, target); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return ri.Execute(ctx, name, values)
+	})
+}
+
+// Query runs the operation on the selected routers in parallel and streams their results as they finish.
+// Routers selected by location or health that the principal may not use are skipped, those selected by ID fail.
+func (s *LookingGlassService) Query(ctx context.Context, req *connect.Request[pb.QueryRequest], stream *connect.ServerStream[pb.QueryResponse]) error {
+	p := auth.FromContext(ctx)
+	ids, err := queryRouters(s.rts, p, req.Msg.GetRouterIds(), req.Msg.GetLocation(), req.Msg.GetAll())
+	if err != nil {
+		return err
+	}
+	client := clientIP(req.Peer())
+	results := make(chan *pb.QueryResponse, len(ids))
+	for _, id := range ids {
+		go func(id int64) {
+			results <- s.query(ctx, p, id, client, req.Msg)
+		}(id)
+	}
+	for range ids {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case res := <-results:
+			if err := stream.Send(res); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// maxQueryRouters is the most routers a request may run its operation on.
+const maxQueryRouters = 10
+
+// routersRequest is implemented by requests that run their operation on several routers.
+type routersRequest interface {
+	GetRouterIds() []int64
+	GetLocation() string
+	GetAll() bool
+}
+
+// queryRouters returns the IDs of the routers selected by ID, else by location, else all healthy routers if all is set.
+// At most maxQueryRouters routers may be selected.
+func queryRouters(rts utils.RouterMap, p *auth.Principal, ids []int64, location string, all bool) ([]int64, error) {
+	if len(ids) > 0 {
+		ids = slices.Clone(ids)
+		slices.Sort(ids)
+		ids = slices.Compact(ids)
+		if len(ids) > maxQueryRouters {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("%w, select at most %d", errs.TooManyRouters, maxQueryRouters))
+		}
+		return ids, nil
+	}
+	if location == "" && !all {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errs.NoRouters)
+	}
+	for k, v := range rts {
+		if !v.HealthCheck.Healthy || !p.AllowsRouter(v.Config) {
+			continue
+		}
+		if location != "" && !strings.EqualFold(v.Config.Location, location) {
+			continue
+		}
+		ids = append(ids, int64(k+1))
+	}
+	if len(ids) == 0 {
+		return nil, connect.NewError(connect.CodeNotFound, errs.NoRouters)
+	}
+	if len(ids) > maxQueryRouters {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("%w, select at most %d", errs.TooManyRouters, maxQueryRouters))
+	}
+	return ids, nil
+}
+
+// routerCount returns the number of routers the request runs its operation on, limits count the request once per router.
+// Requests whose selection is invalid count once, they fail without running anything.
+func routerCount(rts utils.RouterMap, p *auth.Principal, msg any) int {
+	r, ok := msg.(routersRequest)
+	if !ok {
+		return 1
+	}
+	ids, err := queryRouters(rts, p, r.GetRouterIds(), r.GetLocation(), r.GetAll())
+	if err != nil {
+		return 1
+	}
+	return len(ids)
+}
+
+// query runs the operation of the query on one router, errors are part of the result.
+func (s *LookingGlassService) query(ctx context.Context, p *auth.Principal, id int64, client string, msg *pb.QueryRequest) *pb.QueryResponse {
+	res := &pb.QueryResponse{RouterId: id}
+	start := time.Now()
+	var entry *cache.Entry
+	ri, ok := s.rts.GetByID(id)
+	err := errs.UnknownRouter
+	if ok {
+		if err = routerAccess(p, ri); err == nil {
+			entry, res.Cached, err = s.execute(ctx, ri, client, msg.GetOperation(), msg.GetParams())
+		}
+	}
+	res.Duration = durationpb.New(time.Since(start))
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	result, out := ri.Router.Parser(msg.GetOperation()).Split(entry.Result)
+	res.Result = []byte(strings.Join(result, "\n"))
+	res.Timestamp = &timestamppb.Timestamp{
+		Seconds: entry.Timestamp.Unix(),
+		Nanos:   int32(entry.Timestamp.Nanosecond()),
+	}
+	if strings.HasPrefix(msg.GetOperation(), "bgp.") && msg.GetOperation() != "bgp.summary" {
+		res.Paths = s.parseBGPPaths(ri, msg.GetOperation(), out)
+	}
+	return res
+}
+
+// CompareRoute looks up the route on the selected routers in parallel and compares their normalized best paths.
+func (s *LookingGlassService) CompareRoute(ctx context.Context, req *connect.Request[pb.CompareRouteRequest]) (*connect.Response[pb.CompareRouteResponse], error) {
+	p := auth.FromContext(ctx)
+	// Hostnames are resolved once here, the bgp.route operation only accepts addresses and prefixes
+	target, err := utils.NewIPNetFromProtobuf(req.Msg.GetTarget())
+	if err != nil {
+		return nil, err
+	}
+	ids, err := queryRouters(s.rts, p, req.Msg.GetRouterIds(), req.Msg.GetLocation(), req.Msg.GetAll())
+	if err != nil {
+		return nil, err
+	}
+	client := clientIP(req.Peer())
+	routers := make([]*pb.RouteComparison, len(ids))
+	best := make([]*utils.BGPPath, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id int64) {
+			defer wg.Done()
+			routers[i], best[i] = s.compareRoute(ctx, p, id, client, target.String())
+		}(i, id)
+	}
+	wg.Wait()
+	res := &pb.CompareRouteResponse{Routers: routers}
+	var paths []*utils.BGPPath
+	for i, b := range best {
+		if b != nil {
+			paths = append(paths, b)
+		}
+		if routers[i].Missing {
+			res.Missing = append(res.Missing, ids[i])
+		}
+	}
+	res.Differences = utils.PathDifferences(paths)
+	return connect.NewResponse(res), nil
+}
+
+// compareRoute looks up the route on one router and returns its normalized best path, errors are part of the result.
+func (s *LookingGlassService) compareRoute(ctx context.Context, p *auth.Principal, id int64, client string, target string) (*pb.RouteComparison, *utils.BGPPath) {
+	res := &pb.RouteComparison{RouterId: id}
+	_, entry, paths, err := s.lookupRoute(ctx, p, id, client, target)
+	if err != nil {
+		res.Error = err.Error()
+		return res, nil
+	}
+	res.Timestamp = &timestamppb.Timestamp{
+		Seconds: entry.Timestamp.Unix(),
+		Nanos:   int32(entry.Timestamp.Nanosecond()),
+	}
+	res.Paths = uint32(len(paths))
+	if len(paths) == 0 {
+		res.Missing = true
+		return res, nil
+	}
+	// Without a best path, e.g. if all paths are invalid, there is nothing to compare
+	best := utils.BestPath(paths)
+	if best == nil {
+		res.Error = errs.NoBestPath.Error()
+		return res, nil
+	}
+	best = best.Normalize()
+	res.Best = s.annotatePaths(bgpPathsToProto([]*utils.BGPPath{best}))[0]
+	return res, best
+}
+
+// lookupRoute looks up the route on one router and returns its parsed paths.
+func (s *LookingGlassService) lookupRoute(ctx context.Context, p *auth.Principal, id int64, client string, target string) (*utils.RouterInstance, *cache.Entry, []*utils.BGPPath, error) {
+	ri, ok := s.rts.GetByID(id)
+	if !ok {
+		return nil, nil, nil, errs.UnknownRouter
+	}
+	if err := routerAccess(p, ri); err != nil {
+		return nil, nil, nil, err
+	}
+	entry, _, err := s.execute(ctx, ri, client, "bgp.route", map[string]string{"target": target})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	parser := ri.Router.Parser("bgp.route")
+	_, out := parser.Split(entry.Result)
+	paths, err := parsers.BGPPaths(parser, out)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return ri, entry, paths, nil
+}
+
+func (s *LookingGlassService) BGPMap(ctx context.Context, req *connect.Request[pb.BGPMapRequest]) (*connect.Response[pb.BGPMapResponse], error) {
+	return s.bgpMap(ctx, req.Msg, clientIP(req.Peer()))
+}
+
+// bgpMap looks up the route on the selected routers in parallel and builds the map of their paths.
+func (s *LookingGlassService) bgpMap(ctx context.Context, msg *pb.BGPMapRequest, client string) (*connect.Response[pb.BGPMapResponse], error) {
+	p := auth.FromContext(ctx)
+	// Hostnames are resolved once here, the bgp.route operation only accepts addresses and prefixes
+	target, err := utils.NewIPNetFromProtobuf(msg.GetTarget())
+	if err != nil {
+		return nil, err
+	}
+	ids, err := queryRouters(s.rts, p, msg.GetRouterIds(), msg.GetLocation(), msg.GetAll())
+	if err != nil {
+		return nil, err
+	}
+	res := &pb.BGPMapResponse{}
+	routes := make(map[string][]*utils.BGPPath, len(ids))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id int64) {
+			defer wg.Done()
+			ri, _, paths, err := s.lookupRoute(ctx, p, id, client, target.String())
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if res.Errors == nil {
+					res.Errors = make(map[int64]string)
+				}
+				res.Errors[id] = err.Error()
+				return
+			}
+			routes[ri.Config.Name] = paths
+		}(id)
+	}
+	wg.Wait()
+	g := bgpmap.Build(routes, s.asName)
+	res.Nodes, res.Edges = bgpMapToProto(g)
+	res.Dot = g.DOT()
+	res.Svg = g.SVG()
+	return connect.NewResponse(res), nil
+}
+
+func (s *LookingGlassService) GetChallenge(ctx context.Context, req *connect.Request[pb.GetChallengeRequest]) (*connect.Response[pb.GetChallengeResponse], error) {
+	if s.pow == nil || auth.FromContext(ctx) != nil {
+		return connect.NewResponse(&pb.GetChallengeResponse{}), nil
+	}
+	challenge, difficulty, expires := s.pow.Issue(challengeClient(req.Peer()))
+	return connect.NewResponse(&pb.GetChallengeResponse{
+		Challenge:  challenge,
+		Difficulty: uint32(difficulty),
+		Expires: &timestamppb.Timestamp{
+			Seconds: expires.Unix(),
+		},
+		Operations: s.pow.Operations(),
 	}), nil
 }
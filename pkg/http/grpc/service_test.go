@@ -0,0 +1,55 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/AS203038/looking-glass/pkg/enrich"
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/utils"
+	pb "github.com/AS203038/looking-glass/protobuf/lookingglass/v0"
+)
+
+func TestLookupASN(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	file := filepath.Join(t.TempDir(), "as2org.txt")
+	if err := os.WriteFile(file, []byte("# format:aut|changed|aut_name|org_id|opaque_id|source\n64496|20240101|EXAMPLE-NET|EX||ARIN\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	e := enrich.FromConfig(ctx, utils.EnrichmentConfig{Enabled: true, ASNames: utils.DatasetConfig{File: file}})
+	// The dataset is loaded in the background
+	for deadline := time.Now().Add(time.Second); e.AS(64496) == nil; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("AS names not loaded")
+		}
+	}
+	s := NewLookingGlassService(ctx, nil, nil, nil, nil, e, nil, nil)
+
+	asns := make([]uint32, maxLookupASNs)
+	for i := range asns {
+		asns[i] = 64496 + uint32(i)
+	}
+	res, err := s.LookupASN(ctx, connect.NewRequest(&pb.LookupASNRequest{Asns: asns}))
+	if err != nil {
+		t.Fatalf("LookupASN of %d ASNs: %s", len(asns), err)
+	}
+	if got := res.Msg.GetAsns(); len(got) != len(asns) || got[0].GetName() != "EXAMPLE-NET" || got[1].GetAsn() != 64497 || got[1].GetName() != "" {
+		t.Errorf("LookupASN = %v, want EXAMPLE-NET followed by unknown ASes", got)
+	}
+
+	_, err = s.LookupASN(ctx, connect.NewRequest(&pb.LookupASNRequest{Asns: append(asns, 65000)}))
+	if connect.CodeOf(err) != connect.CodeInvalidArgument || !errors.Is(err, errs.TooManyASNs) {
+		t.Errorf("LookupASN of %d ASNs = %v, want %v", len(asns)+1, err, errs.TooManyASNs)
+	}
+
+	disabled := NewLookingGlassService(ctx, nil, nil, nil, nil, nil, nil, nil)
+	if _, err := disabled.LookupASN(ctx, connect.NewRequest(&pb.LookupASNRequest{Asns: []uint32{64496}})); connect.CodeOf(err) != connect.CodeUnavailable {
+		t.Errorf("LookupASN without AS names = %v, want %v", err, errs.ASNamesUnavailable)
+	}
+}
@@ -0,0 +1,50 @@
+package grpc
+
+import (
+	"bytes"
+	"sync"
+)
+
+// lineWriter splits the written output into lines and hands every complete line to send.
+// Once closed, the remaining partial line is flushed and further writes are discarded.
+type lineWriter struct {
+	mu     sync.Mutex
+	buf    []byte
+	closed bool
+	send   func([]byte) error
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return len(p), nil
+	}
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := make([]byte, i+1)
+		copy(line, w.buf[:i+1])
+		w.buf = w.buf[i+1:]
+		if err := w.send(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if len(w.buf) == 0 {
+		return nil
+	}
+	return w.send(w.buf)
+}
@@ -1,36 +1,37 @@
 package http
 
 import (
-	"bytes"
 	"context"
-	"crypto/md5"
+	"crypto/subtle"
 	"crypto/tls"
-	"encoding/json"
 	"fmt"
-	"io"
 	"io/fs"
 	"log"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/AS203038/looking-glass/pkg/abuse"
+	"github.com/AS203038/looking-glass/pkg/auth"
+	"github.com/AS203038/looking-glass/pkg/cache"
+	"github.com/AS203038/looking-glass/pkg/enrich"
 	"github.com/AS203038/looking-glass/pkg/http/grpc"
 	"github.com/AS203038/looking-glass/pkg/http/webui"
+	"github.com/AS203038/looking-glass/pkg/metrics"
+	"github.com/AS203038/looking-glass/pkg/pow"
+	"github.com/AS203038/looking-glass/pkg/ratelimit"
+	"github.com/AS203038/looking-glass/pkg/rpki"
 	"github.com/AS203038/looking-glass/pkg/utils"
 	"github.com/getsentry/sentry-go"
 	sentryhttp "github.com/getsentry/sentry-go/http"
-	"github.com/redis/go-redis/v9"
 	"github.com/rs/cors"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 )
 
-var redisClient *redis.Client
-
 type httpwriter struct {
 	http.ResponseWriter
 	Status int
-	Body   []byte
 }
 
 func (w *httpwriter) Header() http.Header {
@@ -43,64 +44,42 @@ func (w *httpwriter) WriteHeader(status int) {
 }
 
 func (w *httpwriter) Write(b []byte) (int, error) {
-	w.Body = append(w.Body, b...)
 	return w.ResponseWriter.Write(b)
 }
 
-type CacheEnrty struct {
-	Body   []byte      `json:"body"`
-	Status int         `json:"status"`
-	Header http.Header `json:"header"`
+// Flush sends buffered data to the client, this is required for streaming RPCs.
+func (w *httpwriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
 }
 
-func cacheHandler(cfg utils.RedisConfig, h http.Handler) http.Handler {
-	ttl, err := time.ParseDuration(cfg.TTL)
-	if err != nil {
-		log.Println("WARNING: Failed to parse TTL:", err, "using default of 60 seconds")
-		ttl = 60 * time.Second
-	}
+func (w *httpwriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// purgeHandler removes cached results, optionally only those of a router and/or operation.
+// It requires the admin token as bearer token.
+func purgeHandler(c *cache.Cache, token string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		bd, _ := io.ReadAll(r.Body)
-		// reset the body so it can be read again
-		r.Body = io.NopCloser(bytes.NewReader(bd))
-		cacheKey := fmt.Sprintf("%x", md5.Sum([]byte(r.URL.Path+string(bd))))
-		cachedResponse, err := redisClient.Get(context.Background(), cacheKey).Result()
-		if err == nil {
-			var cacheEntry CacheEnrty
-			err = json.Unmarshal([]byte(cachedResponse), &cacheEntry)
-			if err == nil {
-				w.Header().Set("X-Cache", "HIT")
-				for k, v := range cacheEntry.Header {
-					w.Header()[k] = v
-				}
-				if cacheEntry.Status > 0 {
-					w.WriteHeader(cacheEntry.Status)
-				}
-				w.Write(cacheEntry.Body)
-				return
-			}
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
 		}
-
-		// If the response is not cached, execute the handler and cache the response
-		responseWriter := &httpwriter{ResponseWriter: w}
-		h.ServeHTTP(responseWriter, r)
-
-		go func() {
-			cacheEntry := CacheEnrty{
-				Body:   responseWriter.Body,
-				Status: responseWriter.Status,
-				Header: responseWriter.Header(),
-			}
-			cachejson, err := json.Marshal(cacheEntry)
-			if err != nil {
-				log.Println("ERROR: Failed to marshal cache entry:", err)
-				return
-			}
-			_, err = redisClient.Set(context.Background(), cacheKey, cachejson, ttl).Result()
-			if err != nil {
-				log.Println("ERROR: Failed to cache response:", err)
-			}
-		}() // Don't wait for the cache to complete
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		router := r.URL.Query().Get("router")
+		op := r.URL.Query().Get("operation")
+		n, err := c.Purge(r.Context(), router, op)
+		if err != nil {
+			log.Println("ERROR: Failed to purge cache:", err)
+			http.Error(w, "failed to purge cache", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("NOTICE: Purged %d cached results (router=%q operation=%q)", n, router, op)
+		fmt.Fprintf(w, "%d\n", n)
 	})
 }
 
@@ -119,11 +98,8 @@ func loggingHandler(h http.Handler) http.Handler {
 			h.ServeHTTP(wr, r)
 		}
 
-		// Log the request
-		p := r.Header.Get("X-Forwarded-For")
-		if p == "" {
-			p = r.RemoteAddr
-		}
+		// Log the request, the remote address is that of the client behind trusted proxies
+		p := r.RemoteAddr
 		c := wr.Header().Get("X-Cache")
 		log.Printf("%s \"%s %s %s\" %d %d \"%s\" \"%s\" %s %s",
 			p,
@@ -150,7 +126,12 @@ func SecurityTxtInjector(cfg utils.SecurityTxtConfig) http.Handler {
 func ListenAndServe(ctx context.Context, cfg *utils.Config, rts utils.RouterMap, webfs fs.FS) error {
 	mux := http.NewServeMux()
 	if cfg.Grpc.Enabled {
-		grpc.Mux(ctx, mux, rts)
+		c := cache.FromConfig(ctx, cfg)
+		bgpmap := grpc.Mux(ctx, mux, rts, c, ratelimit.FromConfig(ctx, cfg.RateLimit), abuse.FromConfig(ctx, cfg), pow.FromConfig(ctx, cfg.Challenge), auth.FromConfig(ctx, cfg.Auth), enrich.FromConfig(ctx, cfg.Enrichment), rpki.FromConfig(ctx, cfg.RPKI))
+		if cfg.Cache.AdminToken != "" {
+			mux.Handle("/admin/cache/purge", purgeHandler(c, cfg.Cache.AdminToken))
+		}
+		mux.Handle("/bgpmap", bgpmapHandler(bgpmap))
 	}
 	if cfg.SecurityTxt.Enabled {
 		mux.Handle("/.well-known/security.txt", SecurityTxtInjector(cfg.SecurityTxt))
@@ -178,33 +159,54 @@ func ListenAndServe(ctx context.Context, cfg *utils.Config, rts utils.RouterMap,
 			"Grpc-Timeout",             // Used for gRPC-web
 			"X-Grpc-Web",               // Used for gRPC-web
 			"X-User-Agent",             // Used for gRPC-web
+			"X-LG-Challenge",           // Solved challenges, see GetChallenge
 			"baggage",                  // Used for sentry
 			"sentry-trace",             // Used for sentry
 		},
 		ExposedHeaders: []string{
-			"Content-Encoding",         // Unused in web browsers, but added for future-proofing
-			"Connect-Content-Encoding", // Unused in web browsers, but added for future-proofing
-			"Grpc-Status",              // Required for gRPC-web
-			"Grpc-Message",             // Required for gRPC-web
+			"Content-Encoding",          // Unused in web browsers, but added for future-proofing
+			"Connect-Content-Encoding",  // Unused in web browsers, but added for future-proofing
+			"Grpc-Status",               // Required for gRPC-web
+			"Grpc-Message",              // Required for gRPC-web
+			"Retry-After",               // Sent with rate limited responses
+			"X-LG-Challenge-Issued",     // Sent with challenge errors, see grpc.ChallengeIssuedHeader
+			"X-LG-Challenge-Difficulty", // Sent with challenge errors
 		},
 	})
 
 	var handler http.Handler = mux
 
-	if cfg.Redis.Enabled {
-		// Create a Redis client
-		opts, err := redis.ParseURL(cfg.Redis.URI)
-		if err != nil {
-			log.Println("ERROR: Failed to parse Redis URL:", err, "disabling Redis cache")
+	if cfg.Metrics.Enabled {
+		path := cfg.Metrics.Path
+		if path == "" {
+			path = "/metrics"
+		}
+		if cfg.Metrics.Listen == "" {
+			// Serve metrics next to the API
+			m := http.NewServeMux()
+			m.Handle(path, metrics.Handler())
+			m.Handle("/", handler)
+			handler = m
+			log.Printf("NOTICE: Serving metrics on %s", path)
 		} else {
-			log.Println("NOTICE: Connecting to Redis at", cfg.Redis.URI)
-			redisClient = redis.NewClient(opts)
-			// Wrap the existing handler with the cache middleware
-			handler = cacheHandler(cfg.Redis, handler)
+			m := http.NewServeMux()
+			m.Handle(path, metrics.Handler())
+			msrv := &http.Server{
+				Addr:     cfg.Metrics.Listen,
+				Handler:  m,
+				ErrorLog: log.Default(),
+			}
+			log.Printf("NOTICE: Serving metrics on %s%s", cfg.Metrics.Listen, path)
+			go func() {
+				if err := msrv.ListenAndServe(); err != nil {
+					log.Println("ERROR: Metrics listener failed:", err)
+				}
+			}()
 		}
 	}
 
 	handler = loggingHandler(corsHandler.Handler(handler))
+	handler = clientIPHandler(parseProxies(cfg.Grpc.TrustedProxies), handler)
 
 	if cfg.Web.Sentry.Enabled {
 		err := sentry.Init(sentry.ClientOptions{
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+// parseProxies parses the trusted proxies, either networks in CIDR notation or single addresses.
+func parseProxies(proxies []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, p := range proxies {
+		n, err := utils.ParseNet(p)
+		if err != nil {
+			log.Printf("ERROR: Invalid trusted proxy %q, ignoring it", p)
+			continue
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+func trusted(proxies []*net.IPNet, ip net.IP) bool {
+	for _, n := range proxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIPHandler replaces the remote address of requests from trusted proxies with the address of the client.
+// X-Forwarded-For is read from the right, skipping trusted proxies, X-Real-IP is only used without X-Forwarded-For.
+// Both headers are ignored for requests that do not come from a trusted proxy.
+func clientIPHandler(proxies []*net.IPNet, h http.Handler) http.Handler {
+	if len(proxies) == 0 {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, port, err := net.SplitHostPort(r.RemoteAddr)
+		ip := net.ParseIP(host)
+		if err != nil || ip == nil || !trusted(proxies, ip) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+			hops := strings.Split(strings.Join(xff, ","), ",")
+			for i := len(hops) - 1; i >= 0; i-- {
+				hop := net.ParseIP(strings.TrimSpace(hops[i]))
+				if hop == nil {
+					break
+				}
+				ip = hop
+				if !trusted(proxies, hop) {
+					break
+				}
+			}
+		} else if hop := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); hop != nil {
+			ip = hop
+		}
+		r.RemoteAddr = net.JoinHostPort(ip.String(), port)
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "lookingglass"
+
+var (
+	RPCRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_requests_total",
+		Help:      "Number of RPCs handled by procedure, router and Connect code.",
+	}, []string{"procedure", "router", "code"})
+	RPCDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_duration_seconds",
+		Help:      "Duration of RPCs by procedure and router.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"procedure", "router"})
+	SSHDialDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ssh_dial_duration_seconds",
+		Help:      "Duration of SSH connects including the handshake by router.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"router"})
+	SSHExecDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ssh_exec_duration_seconds",
+		Help:      "Duration of operations on routers including dialing by router and operation.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"router", "operation"})
+	SSHErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ssh_errors_total",
+		Help:      "Number of failed operations on routers by router, operation and error.",
+	}, []string{"router", "operation", "error"})
+	SSHInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ssh_in_flight",
+		Help:      "Number of operations currently running on routers by router and operation.",
+	}, []string{"router", "operation"})
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Number of cache lookups by result (hit, miss or coalesced).",
+	}, []string{"result"})
+	RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Number of requests rejected by the client rate limits by operation.",
+	}, []string{"operation"})
+	RouterQueued = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "router_queued",
+		Help:      "Number of operations waiting for a free slot on the router.",
+	}, []string{"router"})
+	RouterHealthy = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "router_healthy",
+		Help:      "Whether the last healthcheck of the router succeeded.",
+	}, []string{"router", "location"})
+	RouterHealthcheckTimestamp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "router_healthcheck_timestamp_seconds",
+		Help:      "Unix time of the last healthcheck of the router.",
+	}, []string{"router", "location"})
+)
+
+// _errors maps the errors of the errs package to their label, the first match wins.
+var _errors = []struct {
+	err   error
+	label string
+}{
+	{context.DeadlineExceeded, "timeout"},
+	{context.Canceled, "canceled"},
+	{errs.AuthFailed, "auth_failed"},
+	{errs.ExecFailed, "exec_failed"},
+	{errs.ConnectionFailed, "connection_failed"},
+	{errs.SessionRefused, "session_refused"},
+	{errs.HostKeyMismatch, "host_key_mismatch"},
+	{errs.HostKeyUnknown, "host_key_unknown"},
+	{errs.HostKeyInvalid, "host_key_invalid"},
+	{errs.OperationUnknown, "operation_unknown"},
+	{errs.RouterUnavailable, "router_unavailable"},
+}
+
+// ErrorLabel returns a label for the error that is bounded in cardinality.
+func ErrorLabel(err error) string {
+	for _, e := range _errors {
+		if errors.Is(err, e.err) {
+			return e.label
+		}
+	}
+	return "other"
+}
+
+// Handler serves the metrics of the default registry.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
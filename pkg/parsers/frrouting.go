@@ -0,0 +1,301 @@
+package parsers
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+var (
+	_ = register(_bgpSummary, "BGP Summary", utils.FormatText, "frrouting", BGPSummaryParser(frroutingBGPSummary))
+	_ = register(_bgpPaths, "BGP Path", utils.FormatJSON, "frrouting", BGPPathsParser(frroutingBGPPaths))
+
+	frrSummaryAFIRegex = regexp.MustCompile(`^(\S+ \S+) Summary`)
+	frrUptimeRegex     = regexp.MustCompile(`^(?:(\d+)w)?(?:(\d+)d)?(?:(\d+)h)?(?:(\d+)m)?$`)
+)
+
+// frroutingBGPSummary parses the tabular output of `show bgp summary`.
+// Columns are located by the header line so that both older and newer FRR releases are supported.
+func frroutingBGPSummary(out []string) ([]*utils.BGPNeighbor, error) {
+	var ret []*utils.BGPNeighbor
+	for _, o := range out {
+		var afi string
+		var cols map[string]int
+		var wrapped string
+		for _, line := range strings.Split(o, "\n") {
+			line = strings.TrimRight(line, "\r ")
+			if m := frrSummaryAFIRegex.FindStringSubmatch(line); m != nil {
+				afi = m[1]
+				cols = nil
+				continue
+			}
+			fields := strings.Fields(line)
+			if len(fields) == 0 || strings.HasPrefix(line, "Total number") {
+				cols = nil
+				continue
+			}
+			if fields[0] == "Neighbor" {
+				cols = make(map[string]int, len(fields))
+				for i, f := range fields {
+					cols[f] = i
+				}
+				continue
+			}
+			if cols == nil {
+				continue
+			}
+			// Long neighbor names are printed on a line of their own
+			if len(fields) == 1 {
+				wrapped = fields[0]
+				continue
+			}
+			if wrapped != "" {
+				fields = append([]string{wrapped}, fields...)
+				wrapped = ""
+			}
+			nbr, err := frroutingBGPNeighbor(fields, cols)
+			if err != nil {
+				return nil, err
+			}
+			nbr.AddressFamily = afi
+			ret = append(ret, nbr)
+		}
+	}
+	return ret, nil
+}
+
+func frroutingBGPNeighbor(fields []string, cols map[string]int) (*utils.BGPNeighbor, error) {
+	get := func(name string) string {
+		i, ok := cols[name]
+		if !ok || i >= len(fields) {
+			return ""
+		}
+		return fields[i]
+	}
+	asn, err := strconv.ParseUint(get("AS"), 10, 32)
+	if err != nil {
+		return nil, errs.OutputMalformed
+	}
+	nbr := &utils.BGPNeighbor{
+		Address: fields[0],
+		ASN:     uint32(asn),
+		Uptime:  frroutingUptime(get("Up/Down")),
+	}
+	state := get("State/PfxRcd")
+	if pfx, err := strconv.ParseInt(state, 10, 64); err == nil {
+		nbr.State = "Established"
+		nbr.PrefixesReceived = pfx
+	} else {
+		nbr.State = state
+	}
+	if pfx, err := strconv.ParseInt(get("PfxSnt"), 10, 64); err == nil {
+		nbr.PrefixesSent = pfx
+	}
+	if i, ok := cols["Desc"]; ok && i < len(fields) {
+		nbr.Description = strings.Join(fields[i:], " ")
+		if nbr.Description == "N/A" {
+			nbr.Description = ""
+		}
+	}
+	return nbr, nil
+}
+
+// frroutingUptime converts FRR's peer uptime formats (hh:mm:ss, XdYYhZZm, XXwYdZZh) into a duration.
+// Unknown formats such as "never" yield zero.
+func frroutingUptime(s string) time.Duration {
+	if p := strings.Split(s, ":"); len(p) == 3 {
+		h, _ := strconv.Atoi(p[0])
+		m, _ := strconv.Atoi(p[1])
+		sec, _ := strconv.Atoi(p[2])
+		return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(sec)*time.Second
+	}
+	m := frrUptimeRegex.FindStringSubmatch(s)
+	if m == nil || s == "" {
+		return 0
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute} {
+		if n, err := strconv.Atoi(m[i+1]); err == nil {
+			d += time.Duration(n) * unit
+		}
+	}
+	return d
+}
+
+// frrJSONPath covers both the detailed path of `show bgp <prefix> json` and the table entry of `show bgp <filter> json`.
+type frrJSONPath struct {
+	// Detailed
+	ASPath         json.RawMessage `json:"aspath"`
+	LocalPref      *uint32         `json:"localpref"`
+	BestPath       json.RawMessage `json:"bestpath"`
+	Community      *frrJSONList    `json:"community"`
+	LargeCommunity *frrJSONList    `json:"largeCommunity"`
+	LastUpdate     *frrJSONEpoch   `json:"lastUpdate"`
+	Peer           *frrJSONPeer    `json:"peer"`
+	// Table
+	Path    *string `json:"path"`
+	LocPrf  *uint32 `json:"locPrf"`
+	Metric  *uint32 `json:"metric"`
+	PeerID  string  `json:"peerId"`
+	Network string  `json:"network"`
+	// Both
+	MED      *uint32 `json:"med"`
+	Origin   string  `json:"origin"`
+	NextHops []struct {
+		IP   string `json:"ip"`
+		Used bool   `json:"used"`
+	} `json:"nexthops"`
+}
+
+type frrJSONList struct {
+	List []string `json:"list"`
+}
+
+type frrJSONEpoch struct {
+	Epoch int64 `json:"epoch"`
+}
+
+type frrJSONPeer struct {
+	PeerID string `json:"peerId"`
+}
+
+type frrJSONASPath struct {
+	String   string `json:"string"`
+	Segments []struct {
+		Type string   `json:"type"`
+		List []uint32 `json:"list"`
+	} `json:"segments"`
+}
+
+// frroutingBGPPaths decodes the JSON output of `show bgp ... json` for a single prefix or a filtered table.
+func frroutingBGPPaths(out []string) ([]*utils.BGPPath, error) {
+	var ret []*utils.BGPPath
+	for _, o := range out {
+		if strings.TrimSpace(o) == "" {
+			continue
+		}
+		var doc struct {
+			Prefix string                    `json:"prefix"`
+			Paths  []*frrJSONPath            `json:"paths"`
+			Routes map[string][]*frrJSONPath `json:"routes"`
+		}
+		if err := json.Unmarshal([]byte(o), &doc); err != nil {
+			return nil, errs.OutputMalformed
+		}
+		for _, p := range doc.Paths {
+			ret = append(ret, p.toBGPPath(doc.Prefix))
+		}
+		prefixes := make([]string, 0, len(doc.Routes))
+		for prefix := range doc.Routes {
+			prefixes = append(prefixes, prefix)
+		}
+		sort.Strings(prefixes)
+		for _, prefix := range prefixes {
+			for _, p := range doc.Routes[prefix] {
+				ret = append(ret, p.toBGPPath(prefix))
+			}
+		}
+	}
+	return ret, nil
+}
+
+func (p *frrJSONPath) toBGPPath(prefix string) *utils.BGPPath {
+	ret := &utils.BGPPath{
+		Prefix: prefix,
+		Origin: p.Origin,
+		Peer:   p.PeerID,
+	}
+	if p.Network != "" {
+		ret.Prefix = p.Network
+	}
+	for _, nh := range p.NextHops {
+		if ret.NextHop == "" || nh.Used {
+			ret.NextHop = nh.IP
+		}
+	}
+	if p.Peer != nil {
+		ret.Peer = p.Peer.PeerID
+	}
+	// The AS path is an object in detailed output and a plain string in tables or older releases
+	var aspath string
+	if p.Path != nil {
+		aspath = *p.Path
+	} else if len(p.ASPath) > 0 {
+		var obj frrJSONASPath
+		if err := json.Unmarshal(p.ASPath, &obj); err == nil {
+			aspath = obj.String
+			for _, seg := range obj.Segments {
+				ret.ASPath = append(ret.ASPath, seg.List...)
+				ret.ASPathSegments = append(ret.ASPathSegments, utils.ASPathSegment{Type: seg.Type, ASNs: seg.List})
+			}
+		} else {
+			json.Unmarshal(p.ASPath, &aspath)
+		}
+	}
+	if ret.ASPath == nil {
+		ret.ASPathSegments = parseASPath(aspath)
+		for _, seg := range ret.ASPathSegments {
+			ret.ASPath = append(ret.ASPath, seg.ASNs...)
+		}
+	}
+	for _, v := range []*uint32{p.LocalPref, p.LocPrf} {
+		if v != nil {
+			ret.LocalPref = *v
+		}
+	}
+	for _, v := range []*uint32{p.Metric, p.MED} {
+		if v != nil {
+			ret.MED = *v
+		}
+	}
+	// bestpath is {"overall": true} in detailed output and a plain boolean in tables
+	var best struct {
+		Overall bool `json:"overall"`
+	}
+	if json.Unmarshal(p.BestPath, &best) == nil {
+		ret.Best = best.Overall
+	} else {
+		json.Unmarshal(p.BestPath, &ret.Best)
+	}
+	if p.Community != nil {
+		ret.Communities = p.Community.List
+	}
+	if p.LargeCommunity != nil {
+		ret.LargeCommunities = p.LargeCommunity.List
+	}
+	if p.LastUpdate != nil && p.LastUpdate.Epoch > 0 {
+		ret.Age = time.Since(time.Unix(p.LastUpdate.Epoch, 0)).Truncate(time.Second)
+	}
+	return ret
+}
+
+// parseASPath splits a textual AS path into its segments, such as 64496 {64497,64498} for a sequence followed by a set.
+// Confederation sequences are enclosed in parentheses and confederation sets in square brackets.
+func parseASPath(s string) []utils.ASPathSegment {
+	open := map[byte]string{'{': utils.ASSet, '(': utils.ASConfedSequence, '[': utils.ASConfedSet}
+	var ret []utils.ASPathSegment
+	// next is set if the following AS starts a new segment
+	typ, next := utils.ASSequence, true
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' }) {
+		if t, ok := open[f[0]]; ok {
+			typ, next = t, true
+		}
+		if asn, err := strconv.ParseUint(strings.Trim(f, "{}()[]"), 10, 32); err == nil {
+			if next {
+				ret = append(ret, utils.ASPathSegment{Type: typ})
+				next = false
+			}
+			ret[len(ret)-1].ASNs = append(ret[len(ret)-1].ASNs, uint32(asn))
+		}
+		if strings.ContainsAny(f, "})]") {
+			typ, next = utils.ASSequence, true
+		}
+	}
+	return ret
+}
@@ -0,0 +1,235 @@
+package parsers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+const frrSummary = `IPv4 Unicast Summary (VRF default):
+BGP router identifier 192.0.2.254, local AS number 64500 vrf-id 0
+BGP table version 12
+RIB entries 23, using 4232 bytes of memory
+Peers 3, using 2172 KiB of memory
+
+Neighbor        V         AS   MsgRcvd   MsgSent   TblVer  InQ OutQ  Up/Down State/PfxRcd   PfxSnt Desc
+192.0.2.1       4      64496      1234      1200        0    0    0 1d02h03m           10       12 transit-a
+2001:db8:ffff:ffff::1234
+                4      64497       100        99        0    0    0 00:05:10       Active        0 N/A
+198.51.100.1    4      64498         0         0        0    0    0    never      Connect        0 peer b
+
+Total number of neighbors 3
+`
+
+// frrSummaryLegacy is the output of FRR 7, which has neither the PfxSnt nor the Desc column.
+const frrSummaryLegacy = `
+IPv6 Unicast Summary:
+BGP router identifier 192.0.2.254, local AS number 64500 vrf-id 0
+BGP table version 4
+RIB entries 7, using 1288 bytes of memory
+Peers 1, using 21 KiB of memory
+
+Neighbor        V         AS MsgRcvd MsgSent   TblVer  InQ OutQ  Up/Down State/PfxRcd
+2001:db8::1     4      64496   50123   48011        0    0    0 02w3d04h          100
+
+Total number of neighbors 1
+`
+
+const frrRouteJSON = `{
+  "prefix":"192.0.2.0/24",
+  "paths":[
+    {
+      "aspath":{"string":"174 64496","segments":[{"type":"as-sequence","list":[174,64496]}],"length":2},
+      "origin":"IGP",
+      "med":10,
+      "localpref":100,
+      "valid":true,
+      "bestpath":{"overall":true,"selectionReason":"First path received"},
+      "community":{"string":"174:21000 174:22013","list":["174:21000","174:22013"]},
+      "largeCommunity":{"string":"64500:1:2","list":["64500:1:2"]},
+      "nexthops":[{"ip":"198.51.100.1","afi":"ipv4","metric":0,"accessible":true,"used":true}],
+      "peer":{"peerId":"198.51.100.1","routerId":"198.51.100.1","type":"external"}
+    },
+    {
+      "aspath":{"string":"3356 {64496,64497}","segments":[{"type":"as-sequence","list":[3356]},{"type":"as-set","list":[64496,64497]}],"length":2},
+      "origin":"incomplete",
+      "valid":true,
+      "nexthops":[{"ip":"2001:db8::2","afi":"ipv6","scope":"global","used":false},{"ip":"fe80::2","afi":"ipv6","scope":"link-local","used":true}],
+      "peer":{"peerId":"2001:db8::2","routerId":"198.51.100.2","type":"external"}
+    }
+  ]
+}`
+
+// frrTableJSON is the output of a filtered table such as show bgp ipv4 unicast regexp _64496$ json.
+const frrTableJSON = `{
+ "vrfId": 0,
+ "vrfName": "default",
+ "tableVersion": 12,
+ "routerId": "192.0.2.254",
+ "defaultLocPrf": 100,
+ "localAS": 64500,
+ "routes": { "198.51.100.0/24": [
+  {
+    "valid":true,
+    "pathFrom":"external",
+    "prefix":"198.51.100.0",
+    "prefixLen":24,
+    "network":"198.51.100.0/24",
+    "metric":0,
+    "weight":0,
+    "peerId":"192.0.2.1",
+    "path":"174 64496",
+    "origin":"IGP",
+    "nexthops":[{"ip":"192.0.2.1","hostname":"transit-a","afi":"ipv4","used":true}]
+  }
+],"192.0.2.0/24": [
+  {
+    "valid":true,
+    "bestpath":true,
+    "pathFrom":"external",
+    "prefix":"192.0.2.0",
+    "prefixLen":24,
+    "network":"192.0.2.0/24",
+    "metric":5,
+    "locPrf":200,
+    "weight":0,
+    "peerId":"192.0.2.2",
+    "aspath":"64496",
+    "origin":"IGP",
+    "nexthops":[{"ip":"192.0.2.2","afi":"ipv4","used":true}]
+  }
+] }
+}`
+
+func TestFRRoutingBGPSummary(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want []*utils.BGPNeighbor
+	}{
+		{"current", frrSummary, []*utils.BGPNeighbor{
+			{Address: "192.0.2.1", ASN: 64496, State: "Established", Uptime: 26*time.Hour + 3*time.Minute, PrefixesReceived: 10, PrefixesSent: 12, AddressFamily: "IPv4 Unicast", Description: "transit-a"},
+			{Address: "2001:db8:ffff:ffff::1234", ASN: 64497, State: "Active", Uptime: 5*time.Minute + 10*time.Second, AddressFamily: "IPv4 Unicast"},
+			{Address: "198.51.100.1", ASN: 64498, State: "Connect", AddressFamily: "IPv4 Unicast", Description: "peer b"},
+		}},
+		{"legacy", frrSummaryLegacy, []*utils.BGPNeighbor{
+			{Address: "2001:db8::1", ASN: 64496, State: "Established", Uptime: 17*24*time.Hour + 4*time.Hour, PrefixesReceived: 100, AddressFamily: "IPv6 Unicast"},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := BGPSummary(utils.Parser{Decoder: "frrouting"}, []string{tt.out})
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: BGPSummary returned %d neighbors, want %d", tt.name, len(got), len(tt.want))
+			continue
+		}
+		for i := range got {
+			if !reflect.DeepEqual(got[i], tt.want[i]) {
+				t.Errorf("%s: neighbor %d = %+v, want %+v", tt.name, i, got[i], tt.want[i])
+			}
+		}
+	}
+	garbage := "Neighbor V AS Up/Down State/PfxRcd\n192.0.2.1 4 notanas never Idle\n"
+	if _, err := BGPSummary(utils.Parser{Decoder: "frrouting"}, []string{garbage}); !errors.Is(err, errs.OutputMalformed) {
+		t.Errorf("BGPSummary of garbage = %v, want %v", err, errs.OutputMalformed)
+	}
+}
+
+func TestFRRoutingBGPPaths(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want []*utils.BGPPath
+	}{
+		{"route", frrRouteJSON, []*utils.BGPPath{
+			{
+				Prefix: "192.0.2.0/24", NextHop: "198.51.100.1", Peer: "198.51.100.1", Origin: "IGP",
+				ASPath:           []uint32{174, 64496},
+				ASPathSegments:   []utils.ASPathSegment{{Type: utils.ASSequence, ASNs: []uint32{174, 64496}}},
+				Communities:      []string{"174:21000", "174:22013"},
+				LargeCommunities: []string{"64500:1:2"},
+				LocalPref:        100, MED: 10, Best: true,
+			},
+			{
+				Prefix: "192.0.2.0/24", NextHop: "fe80::2", Peer: "2001:db8::2", Origin: "incomplete",
+				ASPath: []uint32{3356, 64496, 64497},
+				ASPathSegments: []utils.ASPathSegment{
+					{Type: utils.ASSequence, ASNs: []uint32{3356}},
+					{Type: utils.ASSet, ASNs: []uint32{64496, 64497}},
+				},
+			},
+		}},
+		{"table", frrTableJSON, []*utils.BGPPath{
+			{
+				Prefix: "192.0.2.0/24", NextHop: "192.0.2.2", Peer: "192.0.2.2", Origin: "IGP",
+				ASPath:         []uint32{64496},
+				ASPathSegments: []utils.ASPathSegment{{Type: utils.ASSequence, ASNs: []uint32{64496}}},
+				LocalPref:      200, MED: 5, Best: true,
+			},
+			{
+				Prefix: "198.51.100.0/24", NextHop: "192.0.2.1", Peer: "192.0.2.1", Origin: "IGP",
+				ASPath:         []uint32{174, 64496},
+				ASPathSegments: []utils.ASPathSegment{{Type: utils.ASSequence, ASNs: []uint32{174, 64496}}},
+			},
+		}},
+		{"empty", "{}", nil},
+	}
+	for _, tt := range tests {
+		got, err := BGPPaths(utils.Parser{Decoder: "frrouting", Format: utils.FormatJSON}, []string{tt.out})
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: BGPPaths returned %d paths, want %d", tt.name, len(got), len(tt.want))
+			continue
+		}
+		for i := range got {
+			if !reflect.DeepEqual(got[i], tt.want[i]) {
+				t.Errorf("%s: path %d = %+v, want %+v", tt.name, i, got[i], tt.want[i])
+			}
+		}
+	}
+	if _, err := BGPPaths(utils.Parser{Decoder: "frrouting", Format: utils.FormatJSON}, []string{"% Unknown command: show bgp json"}); !errors.Is(err, errs.OutputMalformed) {
+		t.Errorf("BGPPaths of garbage = %v, want %v", err, errs.OutputMalformed)
+	}
+}
+
+func TestParseASPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want []utils.ASPathSegment
+	}{
+		{"", nil},
+		{"Local", nil},
+		{"174 13335", []utils.ASPathSegment{{Type: utils.ASSequence, ASNs: []uint32{174, 13335}}}},
+		{"174 13335 {64496,64497}", []utils.ASPathSegment{
+			{Type: utils.ASSequence, ASNs: []uint32{174, 13335}},
+			{Type: utils.ASSet, ASNs: []uint32{64496, 64497}},
+		}},
+		{"174 {64496} 13335", []utils.ASPathSegment{
+			{Type: utils.ASSequence, ASNs: []uint32{174}},
+			{Type: utils.ASSet, ASNs: []uint32{64496}},
+			{Type: utils.ASSequence, ASNs: []uint32{13335}},
+		}},
+		{"(65001 65002) [65003,65004] 174 {64496} {64497}", []utils.ASPathSegment{
+			{Type: utils.ASConfedSequence, ASNs: []uint32{65001, 65002}},
+			{Type: utils.ASConfedSet, ASNs: []uint32{65003, 65004}},
+			{Type: utils.ASSequence, ASNs: []uint32{174}},
+			{Type: utils.ASSet, ASNs: []uint32{64496}},
+			{Type: utils.ASSet, ASNs: []uint32{64497}},
+		}},
+	}
+	for _, tt := range tests {
+		if got := parseASPath(tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseASPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package parsers
+
+import (
+	"log"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+type BGPSummaryParser func([]string) ([]*utils.BGPNeighbor, error)
+type BGPPathsParser func([]string) ([]*utils.BGPPath, error)
+type PingParser func([]string) (*utils.PingStats, error)
+type TracerouteParser func([]string) ([]*utils.TracerouteHop, error)
+
+var (
+	_bgpSummary = make(map[string]BGPSummaryParser)
+	_bgpPaths   = make(map[string]BGPPathsParser)
+	_ping       = make(map[string]PingParser)
+	_traceroute = make(map[string]TracerouteParser)
+)
+
+func key(format string, name string) string {
+	if format == "" {
+		format = utils.FormatText
+	}
+	return format + ":" + name
+}
+
+func register[T any](m map[string]T, kind string, format string, name string, p T) bool {
+	if name == "" {
+		log.Panicln("ERROR: Parser name cannot be empty")
+	}
+	if _, ok := m[key(format, name)]; ok {
+		log.Panicf("WARNING: %s parser %s (%s) already registered", kind, name, format)
+	}
+	m[key(format, name)] = p
+	return true
+}
+
+func lookup[T any](m map[string]T, parser utils.Parser) (T, error) {
+	p, ok := m[key(parser.Format, parser.Decoder)]
+	if !ok {
+		return p, errs.ParserUnknown
+	}
+	return p, nil
+}
+
+// BGPSummary parses the output of a bgp.summary operation with the given parser.
+// It returns errs.ParserUnknown if no such parser is registered.
+func BGPSummary(parser utils.Parser, out []string) ([]*utils.BGPNeighbor, error) {
+	p, err := lookup(_bgpSummary, parser)
+	if err != nil {
+		return nil, err
+	}
+	return p(out)
+}
+
+// BGPPaths parses the output of a bgp.route, bgp.community or bgp.aspath operation with the given parser.
+// It returns errs.ParserUnknown if no such parser is registered.
+func BGPPaths(parser utils.Parser, out []string) ([]*utils.BGPPath, error) {
+	p, err := lookup(_bgpPaths, parser)
+	if err != nil {
+		return nil, err
+	}
+	return p(out)
+}
+
+// Ping parses the output of a ping operation with the given parser.
+// It returns errs.ParserUnknown if no such parser is registered.
+func Ping(parser utils.Parser, out []string) (*utils.PingStats, error) {
+	p, err := lookup(_ping, parser)
+	if err != nil {
+		return nil, err
+	}
+	return p(out)
+}
+
+// Traceroute parses the output of a traceroute operation with the given parser.
+// It returns errs.ParserUnknown if no such parser is registered.
+func Traceroute(parser utils.Parser, out []string) ([]*utils.TracerouteHop, error) {
+	p, err := lookup(_traceroute, parser)
+	if err != nil {
+		return nil, err
+	}
+	return p(out)
+}
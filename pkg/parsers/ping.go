@@ -0,0 +1,143 @@
+package parsers
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+var (
+	_ = register(_ping, "Ping", utils.FormatText, "iputils", PingParser(unixPing))
+	_ = register(_ping, "Ping", utils.FormatText, "junos", PingParser(unixPing))
+	_ = register(_ping, "Ping", utils.FormatText, "cisco", PingParser(ciscoPing))
+
+	unixPingReplyRegex = regexp.MustCompile(`from (\S+?)[:,]? .*icmp_seq=(\d+) (?:ttl|hlim)=(\d+) time=([\d.]+) ms`)
+	unixPingErrorRegex = regexp.MustCompile(`^From (\S+?):? .*icmp_seq=(\d+) (.+)$`)
+	unixPingCountRegex = regexp.MustCompile(`(\d+) packets transmitted, (\d+) (?:packets )?received`)
+	unixPingLossRegex  = regexp.MustCompile(`([\d.]+)% packet loss`)
+	unixPingRTTRegex   = regexp.MustCompile(`(?:rtt|round-trip) min/avg/max/(?:mdev|stddev|std-dev) = ([\d.]+)/([\d.]+)/([\d.]+)/([\d.]+) ms`)
+
+	ciscoPingProbesRegex  = regexp.MustCompile(`^[!.UQMA?&CIN]+$`)
+	ciscoPingSuccessRegex = regexp.MustCompile(`Success rate is (\d+) percent \((\d+)/(\d+)\)(?:, round-trip min/avg/max = (\d+)/(\d+)/(\d+) ms)?`)
+)
+
+func msDuration(s string) time.Duration {
+	f, _ := strconv.ParseFloat(s, 64)
+	return time.Duration(f * float64(time.Millisecond))
+}
+
+func atou32(s string) uint32 {
+	n, _ := strconv.ParseUint(s, 10, 32)
+	return uint32(n)
+}
+
+// unixPing parses the output of Linux iputils and BSD derived ping implementations such as the one of Junos.
+func unixPing(out []string) (*utils.PingStats, error) {
+	ret := &utils.PingStats{}
+	found := false
+	for _, o := range out {
+		for _, line := range strings.Split(o, "\n") {
+			line = strings.TrimSpace(line)
+			if m := unixPingReplyRegex.FindStringSubmatch(line); m != nil {
+				ret.Probes = append(ret.Probes, &utils.PingProbe{
+					Sequence: atou32(m[2]),
+					Success:  true,
+					RTT:      msDuration(m[4]),
+					TTL:      atou32(m[3]),
+					Source:   m[1],
+				})
+			} else if m := unixPingErrorRegex.FindStringSubmatch(line); m != nil {
+				ret.Probes = append(ret.Probes, &utils.PingProbe{
+					Sequence: atou32(m[2]),
+					Source:   m[1],
+					Message:  m[3],
+				})
+			} else if m := unixPingCountRegex.FindStringSubmatch(line); m != nil {
+				found = true
+				ret.Sent = atou32(m[1])
+				ret.Received = atou32(m[2])
+				if l := unixPingLossRegex.FindStringSubmatch(line); l != nil {
+					ret.Loss, _ = strconv.ParseFloat(l[1], 64)
+				}
+			} else if m := unixPingRTTRegex.FindStringSubmatch(line); m != nil {
+				ret.Min = msDuration(m[1])
+				ret.Avg = msDuration(m[2])
+				ret.Max = msDuration(m[3])
+				ret.Mdev = msDuration(m[4])
+			}
+		}
+	}
+	if !found {
+		return nil, errs.OutputMalformed
+	}
+	return ret, nil
+}
+
+// ciscoPing parses the output of Cisco IOS, IOS-XE and IOS-XR ping.
+// Per probe results are only known as success or failure, RTTs are reported as whole milliseconds.
+func ciscoPing(out []string) (*utils.PingStats, error) {
+	ret := &utils.PingStats{}
+	found := false
+	for _, o := range out {
+		for _, line := range strings.Split(o, "\n") {
+			line = strings.TrimSpace(line)
+			if ciscoPingProbesRegex.MatchString(line) {
+				for _, c := range line {
+					p := &utils.PingProbe{
+						Sequence: uint32(len(ret.Probes)),
+						Success:  c == '!',
+					}
+					if !p.Success {
+						p.Message = ciscoPingCode(c)
+					}
+					ret.Probes = append(ret.Probes, p)
+				}
+			} else if m := ciscoPingSuccessRegex.FindStringSubmatch(line); m != nil {
+				found = true
+				ret.Received = atou32(m[2])
+				ret.Sent = atou32(m[3])
+				if ret.Sent > 0 {
+					ret.Loss = 100 * float64(ret.Sent-ret.Received) / float64(ret.Sent)
+				}
+				if m[4] != "" {
+					ret.Min = msDuration(m[4])
+					ret.Avg = msDuration(m[5])
+					ret.Max = msDuration(m[6])
+				}
+			}
+		}
+	}
+	if !found {
+		return nil, errs.OutputMalformed
+	}
+	return ret, nil
+}
+
+func ciscoPingCode(c rune) string {
+	switch c {
+	case '.':
+		return "timeout"
+	case 'U':
+		return "destination unreachable"
+	case 'Q':
+		return "source quench"
+	case 'M':
+		return "could not fragment"
+	case 'A':
+		return "administratively prohibited"
+	case '&':
+		return "TTL exceeded"
+	case 'N':
+		return "network unreachable"
+	case 'I':
+		return "interrupted"
+	case 'C':
+		return "congestion experienced"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,167 @@
+package parsers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+const iputilsPing = `PING 192.0.2.1 (192.0.2.1) from 198.51.100.1 : 56(84) bytes of data.
+64 bytes from 192.0.2.1: icmp_seq=1 ttl=57 time=9.84 ms
+64 bytes from 192.0.2.1: icmp_seq=2 ttl=57 time=9.91 ms
+From 198.51.100.254 icmp_seq=3 Destination Host Unreachable
+64 bytes from 192.0.2.1: icmp_seq=4 ttl=57 time=10.2 ms
+
+--- 192.0.2.1 ping statistics ---
+4 packets transmitted, 3 received, +1 errors, 25% packet loss, time 3004ms
+rtt min/avg/max/mdev = 9.840/9.983/10.200/0.155 ms
+`
+
+const iputilsPing6 = `PING 2001:db8::1(2001:db8::1) from 2001:db8:ffff::1 : 56 data bytes
+64 bytes from 2001:db8::1: icmp_seq=1 ttl=58 time=0.512 ms
+64 bytes from 2001:db8::1: icmp_seq=2 ttl=58 time=0.488 ms
+
+--- 2001:db8::1 ping statistics ---
+2 packets transmitted, 2 received, 0% packet loss, time 1001ms
+rtt min/avg/max/mdev = 0.488/0.500/0.512/0.012 ms
+`
+
+const iputilsPingUnreachable = `PING 192.0.2.99 (192.0.2.99) 56(84) bytes of data.
+
+--- 192.0.2.99 ping statistics ---
+5 packets transmitted, 0 received, 100% packet loss, time 4099ms
+`
+
+const junosPing = `PING 192.0.2.1 (192.0.2.1): 56 data bytes
+64 bytes from 192.0.2.1: icmp_seq=0 ttl=58 time=1.234 ms
+64 bytes from 192.0.2.1: icmp_seq=1 ttl=58 time=1.102 ms
+Request timeout for icmp_seq 2
+
+--- 192.0.2.1 ping statistics ---
+3 packets transmitted, 2 packets received, 33% packet loss
+round-trip min/avg/max/stddev = 1.102/1.168/1.234/0.066 ms
+`
+
+const junosPing6 = `PING6(56=40+8+8 bytes) 2001:db8:ffff::1 --> 2001:db8::1
+16 bytes from 2001:db8::1, icmp_seq=0 hlim=62 time=0.517 ms
+16 bytes from 2001:db8::1, icmp_seq=1 hlim=62 time=0.482 ms
+
+--- 2001:db8::1 ping6 statistics ---
+2 packets transmitted, 2 packets received, 0% packet loss
+round-trip min/avg/max/std-dev = 0.482/0.500/0.517/0.018 ms
+`
+
+const ciscoPingOutput = `Type escape sequence to abort.
+Sending 5, 100-byte ICMP Echos to 192.0.2.1, timeout is 2 seconds:
+Packet sent with a source address of 198.51.100.1
+!!.!U
+Success rate is 60 percent (3/5), round-trip min/avg/max = 1/2/4 ms
+`
+
+const ciscoPingFailed = `Type escape sequence to abort.
+Sending 5, 100-byte ICMP Echos to 192.0.2.99, timeout is 2 seconds:
+.....
+Success rate is 0 percent (0/5)
+`
+
+func ms(f float64) time.Duration {
+	return time.Duration(f * float64(time.Millisecond))
+}
+
+func TestPing(t *testing.T) {
+	tests := []struct {
+		name   string
+		parser string
+		out    string
+		want   *utils.PingStats
+	}{
+		{"iputils", "iputils", iputilsPing, &utils.PingStats{
+			Sent: 4, Received: 3, Loss: 25,
+			Min: ms(9.84), Avg: ms(9.983), Max: ms(10.2), Mdev: ms(0.155),
+			Probes: []*utils.PingProbe{
+				{Sequence: 1, Success: true, RTT: ms(9.84), TTL: 57, Source: "192.0.2.1"},
+				{Sequence: 2, Success: true, RTT: ms(9.91), TTL: 57, Source: "192.0.2.1"},
+				{Sequence: 3, Source: "198.51.100.254", Message: "Destination Host Unreachable"},
+				{Sequence: 4, Success: true, RTT: ms(10.2), TTL: 57, Source: "192.0.2.1"},
+			},
+		}},
+		{"iputils IPv6", "iputils", iputilsPing6, &utils.PingStats{
+			Sent: 2, Received: 2,
+			Min: ms(0.488), Avg: ms(0.5), Max: ms(0.512), Mdev: ms(0.012),
+			Probes: []*utils.PingProbe{
+				{Sequence: 1, Success: true, RTT: ms(0.512), TTL: 58, Source: "2001:db8::1"},
+				{Sequence: 2, Success: true, RTT: ms(0.488), TTL: 58, Source: "2001:db8::1"},
+			},
+		}},
+		{"iputils unreachable", "iputils", iputilsPingUnreachable, &utils.PingStats{
+			Sent: 5, Loss: 100,
+		}},
+		{"junos", "junos", junosPing, &utils.PingStats{
+			Sent: 3, Received: 2, Loss: 33,
+			Min: ms(1.102), Avg: ms(1.168), Max: ms(1.234), Mdev: ms(0.066),
+			Probes: []*utils.PingProbe{
+				{Sequence: 0, Success: true, RTT: ms(1.234), TTL: 58, Source: "192.0.2.1"},
+				{Sequence: 1, Success: true, RTT: ms(1.102), TTL: 58, Source: "192.0.2.1"},
+			},
+		}},
+		{"junos IPv6", "junos", junosPing6, &utils.PingStats{
+			Sent: 2, Received: 2,
+			Min: ms(0.482), Avg: ms(0.5), Max: ms(0.517), Mdev: ms(0.018),
+			Probes: []*utils.PingProbe{
+				{Sequence: 0, Success: true, RTT: ms(0.517), TTL: 62, Source: "2001:db8::1"},
+				{Sequence: 1, Success: true, RTT: ms(0.482), TTL: 62, Source: "2001:db8::1"},
+			},
+		}},
+		{"cisco", "cisco", ciscoPingOutput, &utils.PingStats{
+			Sent: 5, Received: 3, Loss: 40,
+			Min: ms(1), Avg: ms(2), Max: ms(4),
+			Probes: []*utils.PingProbe{
+				{Sequence: 0, Success: true},
+				{Sequence: 1, Success: true},
+				{Sequence: 2, Message: "timeout"},
+				{Sequence: 3, Success: true},
+				{Sequence: 4, Message: "destination unreachable"},
+			},
+		}},
+		{"cisco failed", "cisco", ciscoPingFailed, &utils.PingStats{
+			Sent: 5, Loss: 100,
+			Probes: []*utils.PingProbe{
+				{Sequence: 0, Message: "timeout"},
+				{Sequence: 1, Message: "timeout"},
+				{Sequence: 2, Message: "timeout"},
+				{Sequence: 3, Message: "timeout"},
+				{Sequence: 4, Message: "timeout"},
+			},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := Ping(utils.Parser{Decoder: tt.parser}, []string{tt.out})
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Ping = %+v, want %+v", tt.name, got, tt.want)
+			for i := range min(len(got.Probes), len(tt.want.Probes)) {
+				if !reflect.DeepEqual(got.Probes[i], tt.want.Probes[i]) {
+					t.Errorf("%s: probe %d = %+v, want %+v", tt.name, i, got.Probes[i], tt.want.Probes[i])
+				}
+			}
+		}
+	}
+}
+
+func TestPingMalformed(t *testing.T) {
+	for _, parser := range []string{"iputils", "junos", "cisco"} {
+		if _, err := Ping(utils.Parser{Decoder: parser}, []string{"% Unknown command"}); !errors.Is(err, errs.OutputMalformed) {
+			t.Errorf("%s: Ping of garbage = %v, want %v", parser, err, errs.OutputMalformed)
+		}
+	}
+	if _, err := Ping(utils.Parser{Decoder: "unknown"}, []string{iputilsPing}); !errors.Is(err, errs.ParserUnknown) {
+		t.Errorf("Ping with unknown parser = %v, want %v", err, errs.ParserUnknown)
+	}
+}
@@ -0,0 +1,148 @@
+package parsers
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+var (
+	_ = register(_traceroute, "Traceroute", utils.FormatText, "linux", TracerouteParser(traceroute))
+	_ = register(_traceroute, "Traceroute", utils.FormatText, "junos", TracerouteParser(traceroute))
+	_ = register(_traceroute, "Traceroute", utils.FormatText, "cisco", TracerouteParser(traceroute))
+
+	tracerouteHopRegex        = regexp.MustCompile(`^\s*(\d+)\s+(.*)$`)
+	tracerouteLinuxMPLSRegex  = regexp.MustCompile(`<MPLS:([^>]*)>`)
+	tracerouteCiscoMPLSRegex  = regexp.MustCompile(`\[MPLS: Labels? ([\d/]+) Exp (\d+)\]`)
+	tracerouteJunosMPLSRegex  = regexp.MustCompile(`^\s*MPLS Label=(\d+) CoS=(\d+) TTL=(\d+) S=(\d)`)
+	tracerouteAnnotationRegex = regexp.MustCompile(`^![A-Za-z]?\d*(<\d+>)?$`)
+)
+
+// traceroute parses the output of Linux traceroute (including -e and --mtu), Junos and Cisco IOS/XR traceroute.
+func traceroute(out []string) ([]*utils.TracerouteHop, error) {
+	var ret []*utils.TracerouteHop
+	for _, o := range out {
+		var hop *utils.TracerouteHop
+		for _, line := range strings.Split(o, "\n") {
+			line = strings.TrimRight(line, "\r ")
+			if m := tracerouteJunosMPLSRegex.FindStringSubmatch(line); m != nil {
+				if hop != nil {
+					hop.MPLS = append(hop.MPLS, &utils.MPLSLabel{
+						Label:         atou32(m[1]),
+						TrafficClass:  atou32(m[2]),
+						TTL:           atou32(m[3]),
+						BottomOfStack: m[4] == "1",
+					})
+				}
+				continue
+			}
+			m := tracerouteHopRegex.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			hop = &utils.TracerouteHop{Number: atou32(m[1])}
+			rest := m[2]
+			if l := tracerouteLinuxMPLSRegex.FindAllStringSubmatch(rest, -1); l != nil {
+				for _, stack := range l {
+					hop.MPLS = append(hop.MPLS, linuxMPLSLabels(stack[1])...)
+				}
+				rest = tracerouteLinuxMPLSRegex.ReplaceAllString(rest, " ")
+			}
+			if l := tracerouteCiscoMPLSRegex.FindAllStringSubmatch(rest, -1); l != nil {
+				for _, stack := range l {
+					labels := strings.Split(stack[1], "/")
+					for i, lbl := range labels {
+						hop.MPLS = append(hop.MPLS, &utils.MPLSLabel{
+							Label:         atou32(lbl),
+							TrafficClass:  atou32(stack[2]),
+							BottomOfStack: i == len(labels)-1,
+						})
+					}
+				}
+				rest = tracerouteCiscoMPLSRegex.ReplaceAllString(rest, " ")
+			}
+			tracerouteProbes(hop, strings.Fields(rest))
+			ret = append(ret, hop)
+		}
+	}
+	if ret == nil {
+		return nil, errs.OutputMalformed
+	}
+	return ret, nil
+}
+
+// tracerouteProbes walks the tokens of a hop line, an address applies to all following probes until the next address.
+func tracerouteProbes(hop *utils.TracerouteHop, tokens []string) {
+	var addr, name string
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t == "*":
+			hop.Probes = append(hop.Probes, &utils.TracerouteProbe{Timeout: true})
+		case strings.HasPrefix(t, "F="):
+			hop.MTU = atou32(t[2:])
+		case strings.HasPrefix(t, "'") && strings.HasSuffix(t, "'"):
+			// Backward hop count printed by --back
+		case tracerouteAnnotationRegex.MatchString(t):
+			if n := len(hop.Probes); n > 0 {
+				hop.Probes[n-1].Annotation = t
+			}
+		case i+1 < len(tokens) && (tokens[i+1] == "ms" || tokens[i+1] == "msec"):
+			if _, err := strconv.ParseFloat(t, 64); err != nil {
+				continue
+			}
+			hop.Probes = append(hop.Probes, &utils.TracerouteProbe{
+				Address:  addr,
+				Hostname: name,
+				RTT:      msDuration(t),
+			})
+			i++
+		case i+1 < len(tokens) && strings.HasPrefix(tokens[i+1], "(") && strings.HasSuffix(tokens[i+1], ")"):
+			name, addr = t, strings.Trim(tokens[i+1], "()")
+			if name == addr {
+				name = ""
+			}
+			hopAddress(hop, addr)
+			i++
+		case net.ParseIP(t) != nil:
+			name, addr = "", t
+			hopAddress(hop, addr)
+		}
+	}
+}
+
+func hopAddress(hop *utils.TracerouteHop, addr string) {
+	for _, a := range hop.Addresses {
+		if a == addr {
+			return
+		}
+	}
+	hop.Addresses = append(hop.Addresses, addr)
+}
+
+// linuxMPLSLabels parses a label stack as printed by traceroute -e, e.g. L=24001,E=0,S=0,T=1/L=24002,E=0,S=1,T=1.
+func linuxMPLSLabels(s string) []*utils.MPLSLabel {
+	var ret []*utils.MPLSLabel
+	for _, entry := range strings.Split(s, "/") {
+		lbl := &utils.MPLSLabel{}
+		for _, kv := range strings.Split(entry, ",") {
+			k, v, _ := strings.Cut(kv, "=")
+			switch k {
+			case "L":
+				lbl.Label = atou32(v)
+			case "E":
+				lbl.TrafficClass = atou32(v)
+			case "S":
+				lbl.BottomOfStack = v == "1"
+			case "T":
+				lbl.TTL = atou32(v)
+			}
+		}
+		ret = append(ret, lbl)
+	}
+	return ret
+}
@@ -0,0 +1,138 @@
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+const linuxTraceroute = `traceroute to 192.0.2.1 (192.0.2.1), 30 hops max, 60 byte packets
+ 1  gw.example.net (198.51.100.254)  0.412 ms F=1500 '64'
+ 2  10.0.0.1 (10.0.0.1) <MPLS:L=24001,E=0,S=0,T=1/L=24002,E=0,S=1,T=1>  1.234 ms '-1'
+ 3  *
+ 4  10.0.1.1 (10.0.1.1)  2.001 ms  10.0.1.2 (10.0.1.2)  2.345 ms  2.101 ms
+ 5  192.0.2.1 (192.0.2.1)  9.876 ms !H
+`
+
+const junosTraceroute = `traceroute to 192.0.2.1 (192.0.2.1), 30 hops max, 52 byte packets
+ 1  198.51.100.254 (198.51.100.254)  0.512 ms  0.402 ms  0.399 ms
+ 2  10.0.0.1 (10.0.0.1)  1.234 ms  1.111 ms  1.122 ms
+     MPLS Label=299776 CoS=0 TTL=1 S=1
+ 3  * * *
+ 4  192.0.2.1 (192.0.2.1)  9.900 ms  9.800 ms  9.700 ms
+`
+
+const ciscoTraceroute = `Type escape sequence to abort.
+Tracing the route to 192.0.2.1
+VRF info: (vrf in name/id, vrf out name/id)
+  1 198.51.100.254 0 msec 1 msec 0 msec
+  2 10.0.0.1 [MPLS: Labels 24001/24002 Exp 0] 4 msec 4 msec 4 msec
+  3 192.0.2.1 8 msec *  8 msec
+`
+
+func TestTraceroute(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want []*utils.TracerouteHop
+	}{
+		{"linux", linuxTraceroute, []*utils.TracerouteHop{
+			{Number: 1, Addresses: []string{"198.51.100.254"}, MTU: 1500, Probes: []*utils.TracerouteProbe{
+				{Address: "198.51.100.254", Hostname: "gw.example.net", RTT: ms(0.412)},
+			}},
+			{Number: 2, Addresses: []string{"10.0.0.1"}, Probes: []*utils.TracerouteProbe{
+				{Address: "10.0.0.1", RTT: ms(1.234)},
+			}, MPLS: []*utils.MPLSLabel{
+				{Label: 24001, TTL: 1},
+				{Label: 24002, TTL: 1, BottomOfStack: true},
+			}},
+			{Number: 3, Probes: []*utils.TracerouteProbe{{Timeout: true}}},
+			{Number: 4, Addresses: []string{"10.0.1.1", "10.0.1.2"}, Probes: []*utils.TracerouteProbe{
+				{Address: "10.0.1.1", RTT: ms(2.001)},
+				{Address: "10.0.1.2", RTT: ms(2.345)},
+				{Address: "10.0.1.2", RTT: ms(2.101)},
+			}},
+			{Number: 5, Addresses: []string{"192.0.2.1"}, Probes: []*utils.TracerouteProbe{
+				{Address: "192.0.2.1", RTT: ms(9.876), Annotation: "!H"},
+			}},
+		}},
+		{"junos", junosTraceroute, []*utils.TracerouteHop{
+			{Number: 1, Addresses: []string{"198.51.100.254"}, Probes: []*utils.TracerouteProbe{
+				{Address: "198.51.100.254", RTT: ms(0.512)},
+				{Address: "198.51.100.254", RTT: ms(0.402)},
+				{Address: "198.51.100.254", RTT: ms(0.399)},
+			}},
+			{Number: 2, Addresses: []string{"10.0.0.1"}, Probes: []*utils.TracerouteProbe{
+				{Address: "10.0.0.1", RTT: ms(1.234)},
+				{Address: "10.0.0.1", RTT: ms(1.111)},
+				{Address: "10.0.0.1", RTT: ms(1.122)},
+			}, MPLS: []*utils.MPLSLabel{
+				{Label: 299776, TTL: 1, BottomOfStack: true},
+			}},
+			{Number: 3, Probes: []*utils.TracerouteProbe{{Timeout: true}, {Timeout: true}, {Timeout: true}}},
+			{Number: 4, Addresses: []string{"192.0.2.1"}, Probes: []*utils.TracerouteProbe{
+				{Address: "192.0.2.1", RTT: ms(9.9)},
+				{Address: "192.0.2.1", RTT: ms(9.8)},
+				{Address: "192.0.2.1", RTT: ms(9.7)},
+			}},
+		}},
+		{"cisco", ciscoTraceroute, []*utils.TracerouteHop{
+			{Number: 1, Addresses: []string{"198.51.100.254"}, Probes: []*utils.TracerouteProbe{
+				{Address: "198.51.100.254", RTT: 0},
+				{Address: "198.51.100.254", RTT: ms(1)},
+				{Address: "198.51.100.254", RTT: 0},
+			}},
+			{Number: 2, Addresses: []string{"10.0.0.1"}, Probes: []*utils.TracerouteProbe{
+				{Address: "10.0.0.1", RTT: ms(4)},
+				{Address: "10.0.0.1", RTT: ms(4)},
+				{Address: "10.0.0.1", RTT: ms(4)},
+			}, MPLS: []*utils.MPLSLabel{
+				{Label: 24001},
+				{Label: 24002, BottomOfStack: true},
+			}},
+			{Number: 3, Addresses: []string{"192.0.2.1"}, Probes: []*utils.TracerouteProbe{
+				{Address: "192.0.2.1", RTT: ms(8)},
+				{Timeout: true},
+				{Address: "192.0.2.1", RTT: ms(8)},
+			}},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := Traceroute(utils.Parser{Decoder: tt.name}, []string{tt.out})
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: Traceroute returned %d hops, want %d", tt.name, len(got), len(tt.want))
+			continue
+		}
+		for i := range got {
+			if !reflect.DeepEqual(got[i], tt.want[i]) {
+				t.Errorf("%s: hop %d = %s, want %s", tt.name, i+1, hopString(got[i]), hopString(tt.want[i]))
+			}
+		}
+	}
+}
+
+func TestTracerouteMalformed(t *testing.T) {
+	if _, err := Traceroute(utils.Parser{Decoder: "linux"}, []string{"traceroute: unknown host"}); !errors.Is(err, errs.OutputMalformed) {
+		t.Errorf("Traceroute of garbage = %v, want %v", err, errs.OutputMalformed)
+	}
+}
+
+// hopString formats the hop including its probes and labels for error messages.
+func hopString(hop *utils.TracerouteHop) string {
+	s := fmt.Sprintf("%d %v MTU %d", hop.Number, hop.Addresses, hop.MTU)
+	for _, p := range hop.Probes {
+		s += fmt.Sprintf(" %+v", *p)
+	}
+	for _, l := range hop.MPLS {
+		s += fmt.Sprintf(" %+v", *l)
+	}
+	return s
+}
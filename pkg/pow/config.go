@@ -0,0 +1,65 @@
+package pow
+
+import (
+	"context"
+	"crypto/rand"
+	"log"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+const (
+	defaultDifficulty    = 16
+	defaultMaxDifficulty = 24
+	defaultStep          = 10
+	defaultWindow        = 10 * time.Minute
+	defaultTTL           = 2 * time.Minute
+)
+
+var defaultOperations = []string{"traceroute", "bgp.aspath", "bgp.community"}
+
+// FromConfig returns the configured issuer, or nil if challenges are disabled.
+// Without a secret a random one is used, challenges are then only valid on this instance until it restarts.
+func FromConfig(ctx context.Context, cfg utils.ChallengeConfig) *Issuer {
+	if !cfg.Enabled {
+		return nil
+	}
+	i := &Issuer{
+		ops:     cfg.Operations,
+		key:     []byte(cfg.Secret),
+		base:    cfg.Difficulty,
+		max:     cfg.MaxDifficulty,
+		step:    float64(cfg.Step),
+		window:  utils.ParseDuration(cfg.Window, defaultWindow),
+		ttl:     utils.ParseDuration(cfg.TTL, defaultTTL),
+		clients: make(map[string]*activity),
+		used:    make(map[string]time.Time),
+	}
+	if len(i.ops) == 0 {
+		i.ops = defaultOperations
+	}
+	if i.base <= 0 {
+		i.base = defaultDifficulty
+	}
+	if i.max < i.base {
+		i.max = max(i.base, defaultMaxDifficulty)
+	}
+	if i.step <= 0 {
+		i.step = defaultStep
+	}
+	if i.window <= 0 {
+		i.window = defaultWindow
+	}
+	if i.ttl <= 0 {
+		i.ttl = defaultTTL
+	}
+	if len(i.key) == 0 {
+		log.Println("WARNING: No challenge secret configured, using a random one")
+		i.key = make([]byte, 32)
+		rand.Read(i.key)
+	}
+	log.Printf("NOTICE: Requiring challenges for %v with difficulty %d to %d", i.ops, i.base, i.max)
+	go i.sweep(ctx)
+	return i
+}
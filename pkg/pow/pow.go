@@ -0,0 +1,206 @@
+package pow
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+)
+
+const sweepInterval = time.Minute
+
+// Issuer issues hashcash-style challenges and verifies their solutions.
+// Challenges are signed and bound to the client, each solution is accepted once.
+// The difficulty grows by one bit per step requests of the client within the decaying window.
+type Issuer struct {
+	ops    []string
+	key    []byte
+	base   int
+	max    int
+	step   float64
+	window time.Duration
+	ttl    time.Duration
+
+	mu      sync.Mutex
+	clients map[string]*activity
+	used    map[string]time.Time
+}
+
+// activity is an exponentially decaying request count.
+type activity struct {
+	score float64
+	last  time.Time
+}
+
+func (a *activity) decay(now time.Time, window time.Duration) float64 {
+	return a.score * math.Exp(-now.Sub(a.last).Seconds()/window.Seconds())
+}
+
+// Requires returns whether the operation requires a solved challenge.
+func (i *Issuer) Requires(op string) bool {
+	return i != nil && slices.Contains(i.ops, op)
+}
+
+// Operations returns the operations requiring a solved challenge.
+func (i *Issuer) Operations() []string {
+	if i == nil {
+		return nil
+	}
+	return i.ops
+}
+
+// Difficulty returns the current difficulty for the client.
+func (i *Issuer) Difficulty(client string) int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	d := i.base
+	if a, ok := i.clients[client]; ok {
+		d += int(a.decay(time.Now(), i.window) / i.step)
+	}
+	return min(d, i.max)
+}
+
+// Issue returns a challenge for the client and its difficulty and expiry.
+func (i *Issuer) Issue(client string) (string, int, time.Time) {
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	difficulty := i.Difficulty(client)
+	expires := time.Now().Add(i.ttl)
+	payload := strings.Join([]string{
+		hex.EncodeToString(nonce),
+		strconv.Itoa(difficulty),
+		strconv.FormatInt(expires.Unix(), 10),
+	}, ".")
+	return payload + "." + i.sign(payload, client), difficulty, expires
+}
+
+// ExtraBits returns the bits added to the difficulty of a request running its operation on n routers,
+// the work grows linearly with the number of routers.
+func ExtraBits(n int) int {
+	if n <= 1 {
+		return 0
+	}
+	return bits.Len(uint(n - 1))
+}
+
+// Verify checks the solution of the form "<challenge>:<solution>" sent by the client for a request
+// running its operation on n routers and counts the request once per router.
+func (i *Issuer) Verify(client string, solution string, n int) error {
+	if solution == "" {
+		return errs.ChallengeRequired
+	}
+	sep := strings.LastIndexByte(solution, ':')
+	if sep < 0 {
+		return errs.ChallengeInvalid
+	}
+	challenge := solution[:sep]
+	parts := strings.Split(challenge, ".")
+	if len(parts) != 4 {
+		return errs.ChallengeInvalid
+	}
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(i.sign(payload, client))) {
+		return errs.ChallengeInvalid
+	}
+	difficulty, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return errs.ChallengeInvalid
+	}
+	exp, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return errs.ChallengeInvalid
+	}
+	expires := time.Unix(exp, 0)
+	if time.Now().After(expires) {
+		return fmt.Errorf("%w: expired", errs.ChallengeInvalid)
+	}
+	if _, err := strconv.ParseUint(solution[sep+1:], 10, 64); err != nil || !Solved(solution, difficulty+ExtraBits(n)) {
+		return fmt.Errorf("%w: wrong solution", errs.ChallengeInvalid)
+	}
+
+	now := time.Now()
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if _, ok := i.used[parts[0]]; ok {
+		return fmt.Errorf("%w: already used", errs.ChallengeInvalid)
+	}
+	i.used[parts[0]] = expires
+	a, ok := i.clients[client]
+	if !ok {
+		a = &activity{}
+		i.clients[client] = a
+	}
+	a.score = a.decay(now, i.window) + float64(max(n, 1))
+	a.last = now
+	return nil
+}
+
+func (i *Issuer) sign(payload string, client string) string {
+	mac := hmac.New(sha256.New, i.key)
+	mac.Write([]byte(payload + "|" + client))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Solved returns whether the SHA-256 hash of the solution starts with difficulty zero bits.
+func Solved(solution string, difficulty int) bool {
+	sum := sha256.Sum256([]byte(solution))
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			zeros += bits.LeadingZeros8(b)
+			break
+		}
+		zeros += 8
+	}
+	return zeros >= difficulty
+}
+
+// Solve finds a solution for the challenge, it gives up once the context is done.
+func Solve(ctx context.Context, challenge string, difficulty int) (string, error) {
+	for n := uint64(0); ; n++ {
+		if n%4096 == 0 && ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		solution := challenge + ":" + strconv.FormatUint(n, 10)
+		if Solved(solution, difficulty) {
+			return solution, nil
+		}
+	}
+}
+
+// sweep forgets used challenges once they expired and clients whose activity decayed.
+func (i *Issuer) sweep(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			i.mu.Lock()
+			for k, exp := range i.used {
+				if now.After(exp) {
+					delete(i.used, k)
+				}
+			}
+			for k, a := range i.clients {
+				if a.decay(now, i.window) < 0.01 {
+					delete(i.clients, k)
+				}
+			}
+			i.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package pow
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+func newTestIssuer(t *testing.T) *Issuer {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return FromConfig(ctx, utils.ChallengeConfig{
+		Enabled:       true,
+		Secret:        "secret",
+		Difficulty:    4,
+		MaxDifficulty: 6,
+		Step:          2,
+	})
+}
+
+// solveExactly finds a solution with exactly difficulty leading zero bits.
+func solveExactly(challenge string, difficulty int) string {
+	for n := 0; ; n++ {
+		solution := challenge + ":" + strconv.Itoa(n)
+		if Solved(solution, difficulty) && !Solved(solution, difficulty+1) {
+			return solution
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	i := newTestIssuer(t)
+	challenge, difficulty, _ := i.Issue("a")
+	if difficulty != 4 {
+		t.Fatalf("difficulty = %d, want 4", difficulty)
+	}
+	solution := solveExactly(challenge, difficulty)
+	parts := strings.Split(challenge, ".")
+	tampered := strings.Join([]string{parts[0], "0", parts[2], parts[3]}, ".")
+	tests := []struct {
+		name     string
+		client   string
+		solution string
+		err      error
+	}{
+		{"missing", "a", "", errs.ChallengeRequired},
+		{"malformed", "a", "nonsense", errs.ChallengeInvalid},
+		{"other client", "b", solution, errs.ChallengeInvalid},
+		{"tampered difficulty", "a", solveExactly(tampered, 0), errs.ChallengeInvalid},
+		{"not a number", "a", challenge + ":x", errs.ChallengeInvalid},
+		{"unsolved", "a", solveExactly(challenge, 0), errs.ChallengeInvalid},
+		{"solved", "a", solution, nil},
+		{"reused", "a", solution, errs.ChallengeInvalid},
+	}
+	for _, tt := range tests {
+		if err := i.Verify(tt.client, tt.solution, 1); !errors.Is(err, tt.err) {
+			t.Errorf("%s: Verify = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestVerifyRouters(t *testing.T) {
+	i := newTestIssuer(t)
+	challenge, difficulty, _ := i.Issue("a")
+	if err := i.Verify("a", solveExactly(challenge, difficulty), 4); !errors.Is(err, errs.ChallengeInvalid) {
+		t.Errorf("Verify of a solution without extra bits for 4 routers = %v, want %v", err, errs.ChallengeInvalid)
+	}
+	if err := i.Verify("a", solveExactly(challenge, difficulty+ExtraBits(4)), 4); err != nil {
+		t.Errorf("Verify of a solution with extra bits for 4 routers = %v", err)
+	}
+}
+
+func TestDifficulty(t *testing.T) {
+	i := newTestIssuer(t)
+	verify := func(n int) {
+		challenge, difficulty, _ := i.Issue("a")
+		if err := i.Verify("a", solveExactly(challenge, difficulty+ExtraBits(n)), n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The difficulty grows by one bit per step (2) requests within the decaying window and is capped at the maximum (6)
+	verify(1)
+	if d := i.Difficulty("a"); d != 4 {
+		t.Errorf("difficulty after 1 request = %d, want 4", d)
+	}
+	// A request on several routers counts once per router
+	verify(2)
+	if d := i.Difficulty("a"); d != 5 {
+		t.Errorf("difficulty after 3 requests = %d, want 5", d)
+	}
+	verify(3)
+	if d := i.Difficulty("a"); d != 6 {
+		t.Errorf("difficulty after 6 requests = %d, want 6", d)
+	}
+	verify(4)
+	if d := i.Difficulty("a"); d != 6 {
+		t.Errorf("difficulty after 10 requests = %d, want the maximum 6", d)
+	}
+	if d := i.Difficulty("b"); d != 4 {
+		t.Errorf("difficulty of another client = %d, want 4", d)
+	}
+}
+
+func TestExtraBits(t *testing.T) {
+	tests := []struct {
+		n    int
+		want int
+	}{
+		{0, 0}, {1, 0}, {2, 1}, {3, 2}, {4, 2}, {5, 3}, {8, 3}, {9, 4}, {10, 4},
+	}
+	for _, tt := range tests {
+		if got := ExtraBits(tt.n); got != tt.want {
+			t.Errorf("ExtraBits(%d) = %d, want %d", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestSolve(t *testing.T) {
+	solution, err := Solve(context.Background(), "challenge", 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(solution, "challenge:") || !Solved(solution, 8) {
+		t.Errorf("Solve = %q, not a solution of difficulty 8", solution)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Solve(ctx, "challenge", 64); err == nil {
+		t.Error("Solve with cancelled context succeeded")
+	}
+}
@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"net"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+const defaultInterval = time.Minute
+
+// Limits holds the limiters of the operations, operations without own limit share the default limit but not its buckets.
+type Limits struct {
+	def *Limiter
+	ops map[string]*Limiter
+}
+
+// FromConfig returns the configured limits, or nil if rate limiting is disabled.
+func FromConfig(ctx context.Context, cfg utils.RateLimitConfig) *Limits {
+	if !cfg.Enabled {
+		return nil
+	}
+	l := &Limits{
+		def: limiter(ctx, "default", cfg.Default),
+		ops: make(map[string]*Limiter),
+	}
+	for op, c := range cfg.Operations {
+		l.ops[op] = limiter(ctx, op, c)
+	}
+	return l
+}
+
+// limiter returns nil if the limit does not limit anything.
+func limiter(ctx context.Context, name string, c utils.RateLimit) *Limiter {
+	if c.Rate <= 0 {
+		log.Printf("NOTICE: Not rate limiting %s operations", name)
+		return nil
+	}
+	interval := defaultInterval
+	if c.Interval != "" {
+		d, err := time.ParseDuration(c.Interval)
+		if err != nil || d <= 0 {
+			log.Printf("ERROR: Invalid rate limit interval %q of %s, using %s", c.Interval, name, defaultInterval)
+		} else {
+			interval = d
+		}
+	}
+	log.Printf("NOTICE: Rate limiting %s operations to %d per %s per client", name, c.Rate, interval)
+	return New(ctx, c.Rate, interval, c.Burst)
+}
+
+// Allow takes n tokens for the operation from the bucket of the client, one per router it runs on.
+// If the client is limited it returns false and how long until it may retry.
+func (l *Limits) Allow(op string, ip net.IP, n int) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	lim, ok := l.ops[op]
+	if !ok {
+		lim = l.def
+	}
+	if lim == nil {
+		return true, 0
+	}
+	return lim.AllowN(op+"|"+ClientKey(ip), n)
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+// Limiter is a set of token buckets, one per key, that refill at the same rate.
+type Limiter struct {
+	rate  float64 // rate in tokens per second
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// New returns a limiter allowing rate operations per interval and key with bursts of up to burst operations.
+// Buckets of idle keys are removed until the context is done.
+func New(ctx context.Context, rate int, interval time.Duration, burst int) *Limiter {
+	if burst <= 0 {
+		burst = rate
+	}
+	l := &Limiter{
+		rate:    float64(rate) / interval.Seconds(),
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+	go l.sweep(ctx)
+	return l
+}
+
+// Allow takes a token from the bucket of the key.
+// If the bucket is empty it returns false and how long until the next token is available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	return l.AllowN(key, 1)
+}
+
+// AllowN takes n tokens from the bucket of the key, at most the burst so that any n is allowed once the bucket is full.
+// If the bucket has too few tokens it returns false and how long until enough are available.
+func (l *Limiter) AllowN(key string, n int) (bool, time.Duration) {
+	need := min(float64(max(n, 1)), l.burst)
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < need {
+		return false, time.Duration((need - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens -= need
+	return true, 0
+}
+
+// CopyFrom copies the buckets of another limiter, so that replacing a limiter does not refill the buckets of its keys.
+func (l *Limiter) CopyFrom(from *Limiter) {
+	from.mu.Lock()
+	buckets := make(map[string]bucket, len(from.buckets))
+	for k, b := range from.buckets {
+		buckets[k] = *b
+	}
+	from.mu.Unlock()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for k, b := range buckets {
+		b.tokens = min(b.tokens, l.burst)
+		l.buckets[k] = &b
+	}
+}
+
+// sweep removes the buckets that have been refilled completely, they are identical to new ones.
+func (l *Limiter) sweep(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			l.mu.Lock()
+			for k, b := range l.buckets {
+				if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+					delete(l.buckets, k)
+				}
+			}
+			l.mu.Unlock()
+		}
+	}
+}
+
+// ClientKey returns the key clients are limited by, the address for IPv4 and the /64 for IPv6.
+func ClientKey(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.String()
+	}
+	return (&net.IPNet{IP: ip.Mask(net.CIDRMask(64, 128)), Mask: net.CIDRMask(64, 128)}).String()
+}
@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestLimiterAllowN(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tests := []struct {
+		name  string
+		burst int
+		n     []int
+		want  []bool
+	}{
+		{"single", 3, []int{1, 1, 1, 1}, []bool{true, true, true, false}},
+		{"fan out", 10, []int{4, 4, 4, 1}, []bool{true, true, false, true}},
+		{"larger than burst", 5, []int{8, 1}, []bool{true, false}},
+		{"zero counts once", 2, []int{0, 0, 0}, []bool{true, true, false}},
+	}
+	for _, tt := range tests {
+		l := New(ctx, 1, time.Hour, tt.burst)
+		for i, n := range tt.n {
+			allowed, wait := l.AllowN("key", n)
+			if allowed != tt.want[i] {
+				t.Errorf("%s: AllowN(%d) #%d = %t, want %t", tt.name, n, i, allowed, tt.want[i])
+			}
+			if !allowed && wait <= 0 {
+				t.Errorf("%s: AllowN(%d) #%d denied without wait", tt.name, n, i)
+			}
+		}
+	}
+}
+
+func TestLimitsAllow(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	l := &Limits{
+		def: New(ctx, 5, time.Hour, 5),
+		ops: map[string]*Limiter{"ping": New(ctx, 1, time.Hour, 1)},
+	}
+	a, b := net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1")
+	if ok, _ := l.Allow("bgp.route", a, 5); !ok {
+		t.Error("bgp.route on 5 routers denied")
+	}
+	if ok, _ := l.Allow("bgp.route", a, 1); ok {
+		t.Error("bgp.route allowed after using up the burst")
+	}
+	if ok, _ := l.Allow("bgp.route", b, 1); !ok {
+		t.Error("bgp.route of another client denied")
+	}
+	if ok, _ := l.Allow("ping", a, 1); !ok {
+		t.Error("ping with own limit denied")
+	}
+	// IPv6 clients are limited by their /64
+	if ok, _ := l.Allow("ping", net.ParseIP("2001:db8::1"), 1); !ok {
+		t.Error("first ping of the /64 denied")
+	}
+	if ok, _ := l.Allow("ping", net.ParseIP("2001:db8::2"), 1); ok {
+		t.Error("ping of the same /64 allowed")
+	}
+	var none *Limits
+	if ok, _ := none.Allow("ping", a, 100); !ok {
+		t.Error("nil limits denied")
+	}
+}
+
+func TestLimiterCopyFrom(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	old := New(ctx, 3, time.Hour, 3)
+	old.AllowN("192.0.2.1", 3)
+	old.AllowN("192.0.2.2", 1)
+
+	// A smaller burst caps the copied buckets
+	l := New(ctx, 1, time.Hour, 1)
+	l.CopyFrom(old)
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"192.0.2.1", false},
+		{"192.0.2.2", true},
+		{"192.0.2.2", false},
+		{"192.0.2.3", true},
+	}
+	for i, tt := range tests {
+		if ok, _ := l.Allow(tt.key); ok != tt.want {
+			t.Errorf("Allow(%s) #%d = %t, want %t", tt.key, i, ok, tt.want)
+		}
+	}
+}
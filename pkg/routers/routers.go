@@ -1,6 +1,7 @@
 package routers
 
 import (
+	"context"
 	"log"
 
 	"github.com/AS203038/looking-glass/pkg/utils"
@@ -36,12 +37,19 @@ func CreateRouterMap(cfg *utils.Config) utils.RouterMap {
 			log.Printf("ERROR: Router Type %s not found (%s)\n", v.Type, v.Name)
 			continue
 		}
+		policy, err := utils.NewPolicy(cfg.Policy, v.Policy)
+		if err != nil {
+			log.Printf("ERROR: Invalid target policy of %s: %s\n", v.Name, err)
+			continue
+		}
 		ri := &utils.RouterInstance{
 			Config:      &v,
 			Router:      Get(v.Type),
 			HealthCheck: &utils.HealthCheck{},
+			Limiter:     utils.NewLimiter(v.Name, v.MaxConcurrent, v.QueueDepth),
+			Policy:      policy,
 		}
-		go ri.Healthcheck()
+		go ri.Healthcheck(context.Background())
 		rm = append(rm, ri)
 	}
 	return rm
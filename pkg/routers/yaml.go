@@ -2,9 +2,12 @@ package routers
 
 import (
 	"bytes"
+	"context"
 	"embed"
+	"fmt"
 	"log"
 	"os"
+	"slices"
 	"strings"
 	"text/template"
 
@@ -21,10 +24,49 @@ type _tpl_data struct {
 	ASPath    string              // ASPath holds the AS path information.
 }
 
+// LargeCommunity reports whether the community is a large community (asn:value:value), templates use it to pick the command.
+func (d _tpl_data) LargeCommunity() bool {
+	return strings.Count(d.Community, ":") == 2
+}
+
+// _op_data represents the template data used for user-defined operations.
+type _op_data struct {
+	Cfg    *utils.RouterConfig // Cfg holds the router configuration.
+	Params map[string]any      // Params holds the validated parameters by name.
+}
+
+// _builtins describes the parameters of the operations with a dedicated RPC.
+var _builtins = map[string]*utils.Operation{
+	"ping": {
+		Description: "Ping",
+		Params:      []utils.OperationParam{{Name: "target", Type: utils.ParamIP, Description: "IP address or hostname"}},
+	},
+	"traceroute": {
+		Description: "Traceroute",
+		Params:      []utils.OperationParam{{Name: "target", Type: utils.ParamIP, Description: "IP address or hostname"}},
+	},
+	"bgp.summary": {
+		Description: "BGP Summary",
+	},
+	"bgp.route": {
+		Description: "BGP Route",
+		Params:      []utils.OperationParam{{Name: "target", Type: utils.ParamPrefix, Description: "IP address or prefix"}},
+	},
+	"bgp.community": {
+		Description: "BGP Community",
+		Params:      []utils.OperationParam{{Name: "community", Type: utils.ParamCommunity, Description: "Standard or large community"}},
+	},
+	"bgp.aspath": {
+		Description: "BGP ASPath Regex",
+		Params:      []utils.OperationParam{{Name: "pattern", Type: utils.ParamASPath, Description: "AS path regular expression"}},
+	},
+}
+
 // Yaml represents the structure of a YAML file.
 type Yaml struct {
-	Path     string // Path represents the file path.
-	Template struct {
+	Path       string                      // Path represents the file path.
+	operations map[string]*utils.Operation // operations holds the builtin and user-defined operations of the router.
+	Template   struct {
 		Name string `yaml:"name"` // Name represents the template name.
 		Ping struct {
 			Any  []string `yaml:"any"`  // Any represents the list of ping targets for any IP address.
@@ -37,10 +79,13 @@ type Yaml struct {
 			IPv6 []string `yaml:"ipv6"` // IPv6 represents the list of traceroute targets for IPv6 addresses.
 		} `yaml:"traceroute"` // Traceroute represents the traceroute section in the template.
 		BGP struct {
+			Summary   []string `yaml:"summary"`   // Summary represents the list of BGP summaries.
 			Route     []string `yaml:"route"`     // Route represents the list of BGP routes.
 			Community []string `yaml:"community"` // Community represents the list of BGP communities.
 			ASPath    []string `yaml:"aspath"`    // ASPath represents the list of BGP AS paths.
 		} `yaml:"bgp"` // BGP represents the BGP section in the template.
+		Parsers    map[string]utils.Parser     `yaml:"parsers"`    // Parsers maps operation names to the parser used for their output.
+		Operations map[string]*utils.Operation `yaml:"operations"` // Operations holds user-defined operations by name.
 	}
 }
 
@@ -81,6 +126,9 @@ func init() {
 				log.Printf("ERROR: Router name cannot be empty (%s/%s)", rd, y.Path)
 				continue
 			}
+			if err := y.compile(); err != nil {
+				log.Panicf("ERROR: Invalid operations in file %s/%s: %+v", rd, y.Path, err)
+			}
 			register(y.Template.Name, y)
 			log.Printf("NOTICE: Router %s (%s/%s) registered\n", y.Template.Name, rd, y.Path)
 		}
@@ -101,6 +149,9 @@ func init() {
 		if err != nil {
 			log.Panicf("ERROR: Could not Unmarshal file builtin:%s: %+v", y.Path, err)
 		}
+		if err := y.compile(); err != nil {
+			log.Panicf("ERROR: Invalid operations in file builtin:%s: %+v", y.Path, err)
+		}
 		if _, ok := _routers[y.Template.Name]; !ok {
 			register(y.Template.Name, y)
 			log.Printf("NOTICE: Router %s (builtin:%s) registered\n", y.Template.Name, y.Path)
@@ -110,14 +161,96 @@ func init() {
 	}
 }
 
+// compile validates the user-defined operations and collects them together with the supported builtin operations.
+// User-defined operations cannot replace builtin ones.
+func (rt *Yaml) compile() error {
+	rt.operations = make(map[string]*utils.Operation)
+	for name, op := range _builtins {
+		if rt.supports(name) {
+			cp := *op
+			cp.Builtin = true
+			rt.operations[name] = &cp
+		}
+	}
+	for name, op := range rt.Template.Operations {
+		if _, ok := _builtins[name]; ok {
+			return fmt.Errorf("%w: %s is a builtin operation", errs.OperationInvalid, name)
+		}
+		if op == nil {
+			return fmt.Errorf("%w: %s is empty", errs.OperationInvalid, name)
+		}
+		op.Builtin = false
+		if err := op.Check(); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		for _, t := range op.Commands {
+			if _, err := template.New(t).Parse(t); err != nil {
+				return fmt.Errorf("%w: %s: %s", errs.OperationInvalid, name, err)
+			}
+		}
+		rt.operations[name] = op
+	}
+	for name, p := range rt.Template.Parsers {
+		if len(p.Commands) == 0 {
+			continue
+		}
+		// Streamed output is written as it arrives, it cannot be split from the output of the parser's commands
+		if name == "ping" || name == "traceroute" {
+			return fmt.Errorf("%w: parser of %s cannot have commands", errs.OperationInvalid, name)
+		}
+		for _, t := range p.Commands {
+			if _, err := template.New(t).Parse(t); err != nil {
+				return fmt.Errorf("%w: parser of %s: %s", errs.OperationInvalid, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// supports reports whether the router has templates for the given builtin operation.
+func (rt *Yaml) supports(name string) bool {
+	switch name {
+	case "ping":
+		return len(rt.Template.Ping.Any)+len(rt.Template.Ping.IPv4)+len(rt.Template.Ping.IPv6) > 0
+	case "traceroute":
+		return len(rt.Template.Traceroute.Any)+len(rt.Template.Traceroute.IPv4)+len(rt.Template.Traceroute.IPv6) > 0
+	case "bgp.summary":
+		return len(rt.Template.BGP.Summary) > 0
+	case "bgp.route":
+		return len(rt.Template.BGP.Route) > 0
+	case "bgp.community":
+		return len(rt.Template.BGP.Community) > 0
+	case "bgp.aspath":
+		return len(rt.Template.BGP.ASPath) > 0
+	}
+	return false
+}
+
+// _render executes each of the templates with the provided data.
+func _render(tpl []string, data any) ([]string, error) {
+	var ret []string
+	for _, t := range tpl {
+		var buf bytes.Buffer
+		tt, err := template.New(t).Parse(t)
+		if err != nil {
+			return nil, err
+		}
+		err = tt.Execute(&buf, data)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, buf.String())
+	}
+	return ret, nil
+}
+
 // _tpl is a helper function used to generate a list of strings based on the provided template name and data.
 // It takes a template name and data as input and returns a list of strings generated from the template.
 // The function first determines the appropriate template to use based on the template name and the IP version in the data.
-// It then iterates over the selected template(s), executes them with the provided data, and appends the generated strings to the result list.
+// It then iterates over the selected template(s) followed by the commands of the operation's parser, executes them with the provided data, and appends the generated strings to the result list.
 // If no template is found for the given name, the function returns nil and an error of type `errs.OperationUnknown`.
 func (rt *Yaml) _tpl(name string, data _tpl_data) ([]string, error) {
 	var tpl []string
-	var ret []string
 	switch name {
 	case "ping":
 		tpl = rt.Template.Ping.Any
@@ -133,6 +266,8 @@ func (rt *Yaml) _tpl(name string, data _tpl_data) ([]string, error) {
 		} else if data.IP.IsIPv6() {
 			tpl = rt.Template.Traceroute.IPv6
 		}
+	case "bgp.summary":
+		tpl = rt.Template.BGP.Summary
 	case "bgp.route":
 		tpl = rt.Template.BGP.Route
 	case "bgp.community":
@@ -143,46 +278,83 @@ func (rt *Yaml) _tpl(name string, data _tpl_data) ([]string, error) {
 	if tpl == nil {
 		return nil, errs.OperationUnknown
 	}
-	for _, t := range tpl {
-		var buf bytes.Buffer
-		tt, err := template.New(t).Parse(t)
-		if err != nil {
-			return nil, err
-		}
-		err = tt.Execute(&buf, data)
-		if err != nil {
-			return nil, err
-		}
-		ret = append(ret, buf.String())
+	if p := rt.Template.Parsers[name]; p.Decoder != "" {
+		tpl = append(slices.Clip(tpl), p.Commands...)
 	}
-	return ret, nil
+	return _render(tpl, data)
 }
 
 // Ping sends a ping request to the specified IP address using the provided router configuration.
 // It returns a slice of strings representing the ping response and an error if any.
-func (rt *Yaml) Ping(cfg *utils.RouterConfig, ip *utils.IPNet) ([]string, error) {
+func (rt *Yaml) Ping(ctx context.Context, cfg *utils.RouterConfig, ip *utils.IPNet) ([]string, error) {
 	return rt._tpl("ping", _tpl_data{Cfg: cfg, IP: ip})
 }
 
 // Traceroute performs a traceroute operation using the provided router configuration and IP address.
 // It returns a slice of strings representing the traceroute results and an error if any.
-func (rt *Yaml) Traceroute(cfg *utils.RouterConfig, ip *utils.IPNet) ([]string, error) {
+func (rt *Yaml) Traceroute(ctx context.Context, cfg *utils.RouterConfig, ip *utils.IPNet) ([]string, error) {
 	return rt._tpl("traceroute", _tpl_data{Cfg: cfg, IP: ip})
 }
 
+// BGPSummary returns a slice of strings representing the BGP summary commands for the given router configuration.
+func (rt *Yaml) BGPSummary(ctx context.Context, cfg *utils.RouterConfig) ([]string, error) {
+	return rt._tpl("bgp.summary", _tpl_data{Cfg: cfg})
+}
+
 // BGPRoute generates BGP route configuration based on the provided RouterConfig and IPNet.
 // It returns a slice of strings representing the generated configuration and an error if any.
-func (rt *Yaml) BGPRoute(cfg *utils.RouterConfig, ip *utils.IPNet) ([]string, error) {
+func (rt *Yaml) BGPRoute(ctx context.Context, cfg *utils.RouterConfig, ip *utils.IPNet) ([]string, error) {
 	return rt._tpl("bgp.route", _tpl_data{Cfg: cfg, IP: ip})
 }
 
 // BGPCommunity returns a list of strings representing the BGP community values for the given router configuration and community.
-func (rt *Yaml) BGPCommunity(cfg *utils.RouterConfig, community string) ([]string, error) {
+func (rt *Yaml) BGPCommunity(ctx context.Context, cfg *utils.RouterConfig, community string) ([]string, error) {
 	return rt._tpl("bgp.community", _tpl_data{Cfg: cfg, Community: community})
 }
 
 // BGPASPath returns a slice of strings representing the BGP AS path for the given router configuration and AS path string.
 // It uses the "_tpl" method to render the "bgp.aspath" template with the provided configuration and AS path.
-func (rt *Yaml) BGPASPath(cfg *utils.RouterConfig, aspath string) ([]string, error) {
+func (rt *Yaml) BGPASPath(ctx context.Context, cfg *utils.RouterConfig, aspath string) ([]string, error) {
 	return rt._tpl("bgp.aspath", _tpl_data{Cfg: cfg, ASPath: aspath})
 }
+
+// Parser returns the parser configured for the given operation.
+// Its decoder is empty if the output of the operation is not parsed.
+func (rt *Yaml) Parser(name string) utils.Parser {
+	return rt.Template.Parsers[name]
+}
+
+// Operations returns the builtin operations supported by the router and its user-defined operations.
+func (rt *Yaml) Operations() map[string]*utils.Operation {
+	return rt.operations
+}
+
+// Execute renders the commands of the named operation with parameters already validated by Operation.Validate.
+// Builtin operations use the same templates as their dedicated methods.
+func (rt *Yaml) Execute(ctx context.Context, cfg *utils.RouterConfig, name string, params map[string]any) ([]string, error) {
+	op, ok := rt.operations[name]
+	if !ok {
+		return nil, errs.OperationUnknown
+	}
+	if !op.Builtin {
+		data := _op_data{Cfg: cfg, Params: make(map[string]any, len(op.Params))}
+		for _, p := range op.Params {
+			data.Params[p.Name] = p.Zero()
+			if v, ok := params[p.Name]; ok {
+				data.Params[p.Name] = v
+			}
+		}
+		return _render(op.Commands, data)
+	}
+	data := _tpl_data{Cfg: cfg}
+	if ip, ok := params["target"].(*utils.IPNet); ok {
+		data.IP = ip
+	}
+	if community, ok := params["community"].(string); ok {
+		data.Community = community
+	}
+	if aspath, ok := params["pattern"].(string); ok {
+		data.ASPath = aspath
+	}
+	return rt._tpl(name, data)
+}
@@ -0,0 +1,95 @@
+package routers
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/AS203038/looking-glass/pkg/utils"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const testTemplate = `
+name: test
+operations:
+    route:
+        params:
+            - name: target
+              type: prefix
+              optional: true
+            - name: asn
+              type: asn
+              optional: true
+            - name: detail
+              type: enum
+              values: ["detail"]
+              optional: true
+        commands:
+            - show {{if .Params.target.IsIPv6}}ipv6{{else}}ip{{end}} route{{if .Params.target}} {{.Params.target}}{{end}}{{if .Params.asn}} as {{.Params.asn}}{{end}}{{if .Params.detail}} detail{{end}}
+`
+
+func TestExecuteOptionalParams(t *testing.T) {
+	y := &Yaml{Path: "test.yml"}
+	if err := yaml.Unmarshal([]byte(testTemplate), &y.Template); err != nil {
+		t.Fatal(err)
+	}
+	if err := y.compile(); err != nil {
+		t.Fatal(err)
+	}
+	op := y.Operations()["route"]
+	tests := []struct {
+		params map[string]string
+		want   string
+	}{
+		{map[string]string{}, "show ip route"},
+		{map[string]string{"target": "2001:db8::/32"}, "show ipv6 route 2001:db8::/32"},
+		{map[string]string{"target": "192.0.2.0/24", "asn": "AS64496", "detail": "detail"}, "show ip route 192.0.2.0/24 as 64496 detail"},
+	}
+	for _, tt := range tests {
+		values, err := op.Validate(tt.params)
+		if err != nil {
+			t.Fatalf("Validate(%v): %s", tt.params, err)
+		}
+		cmd, err := y.Execute(context.Background(), &utils.RouterConfig{}, "route", values)
+		if err != nil {
+			t.Errorf("Execute(%v): %s", tt.params, err)
+			continue
+		}
+		if !slices.Equal(cmd, []string{tt.want}) {
+			t.Errorf("Execute(%v) = %q, want %q", tt.params, cmd, tt.want)
+		}
+	}
+}
+
+func TestFRRoutingCommunity(t *testing.T) {
+	rt := Get("frrouting")
+	if rt == nil {
+		t.Fatal("frrouting is not registered")
+	}
+	op := rt.Operations()["bgp.community"]
+	tests := []struct {
+		community string
+		want      string
+	}{
+		{"65000:100", "community 65000:100"},
+		{"65000:1:100", "large-community 65000:1:100"},
+	}
+	for _, tt := range tests {
+		values, err := op.Validate(map[string]string{"community": tt.community})
+		if err != nil {
+			t.Fatalf("Validate(%s): %s", tt.community, err)
+		}
+		cmd, err := rt.Execute(context.Background(), &utils.RouterConfig{VRF: "default"}, "bgp.community", values)
+		if err != nil {
+			t.Errorf("Execute(%s): %s", tt.community, err)
+			continue
+		}
+		want := []string{
+			"vtysh -c 'show bgp vrf default ipv4 unicast " + tt.want + "'",
+			"vtysh -c 'show bgp vrf default ipv6 unicast " + tt.want + "'",
+		}
+		if !slices.Equal(cmd, want) {
+			t.Errorf("Execute(%s) = %q, want %q", tt.community, cmd, want)
+		}
+	}
+}
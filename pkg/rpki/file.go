@@ -0,0 +1,55 @@
+package rpki
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// asnJSON is an AS number as a number, as exported by rpki-client, or a string with the AS prefix, as exported by Routinator.
+type asnJSON uint32
+
+func (a *asnJSON) UnmarshalJSON(b []byte) error {
+	s := strings.TrimPrefix(string(bytes.Trim(b, `"`)), "AS")
+	asn, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return err
+	}
+	*a = asnJSON(asn)
+	return nil
+}
+
+// parseROAs parses the validated ROA payloads of an rpki-client or Routinator JSON export with objects of
+//
+//	{"roas": [{"asn": 13335, "prefix": "1.1.1.0/24", "maxLength": 24, "ta": "apnic"}]}
+//
+// ROAs with an invalid prefix are skipped.
+func parseROAs(b []byte) (*set, error) {
+	var export struct {
+		ROAs []struct {
+			ASN       asnJSON `json:"asn"`
+			Prefix    string  `json:"prefix"`
+			MaxLength uint8   `json:"maxLength"`
+			TA        string  `json:"ta"`
+		} `json:"roas"`
+	}
+	if err := json.Unmarshal(b, &export); err != nil {
+		return nil, err
+	}
+	roas := make([]ROA, 0, len(export.ROAs))
+	for _, r := range export.ROAs {
+		p, err := netip.ParsePrefix(r.Prefix)
+		if err != nil {
+			continue
+		}
+		roas = append(roas, ROA{Prefix: p, MaxLength: r.MaxLength, ASN: uint32(r.ASN), TA: r.TA})
+	}
+	s := newSet(roas)
+	if s.len == 0 {
+		return nil, errors.New("no ROAs")
+	}
+	return s, nil
+}
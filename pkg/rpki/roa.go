@@ -0,0 +1,156 @@
+package rpki
+
+import (
+	"cmp"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// Validation states of RFC 6811.
+const (
+	Valid    = "Valid"
+	Invalid  = "Invalid"
+	NotFound = "NotFound"
+)
+
+// Reasons a route is invalid.
+const (
+	ReasonMaxLength = "max_length" // ReasonMaxLength is a route of an authorized origin AS longer than the max length of its ROAs
+	ReasonOrigin    = "origin"     // ReasonOrigin is a route of an origin AS that no covering ROA authorizes, or without origin AS
+)
+
+// ROA is a validated ROA payload, it authorizes the AS to originate the prefix and its more specifics up to the max length.
+type ROA struct {
+	Prefix    netip.Prefix
+	MaxLength uint8
+	ASN       uint32
+	TA        string // TA is the trust anchor, it is unknown for ROAs received over RTR
+}
+
+func (r ROA) String() string {
+	return fmt.Sprintf("%s-%d AS%d", r.Prefix, r.MaxLength, r.ASN)
+}
+
+// Validation is the result of the route origin validation of a route.
+type Validation struct {
+	State       string
+	Reason      string // Reason is only set for invalid routes
+	Origin      uint32 // Origin is 0 for routes whose AS path ends in an AS_SET
+	ROAs        []ROA  // ROAs are the ROA authorizing a valid route or the ROAs an invalid route conflicts with
+	Description string
+}
+
+// set is an immutable index of ROAs by their prefix.
+type set struct {
+	roas map[netip.Prefix][]ROA
+	bits []int // bits are the distinct prefix lengths of the ROAs, longest first
+	len  int
+}
+
+// newSet indexes the ROAs, duplicates and ROAs with an invalid max length are skipped.
+func newSet(roas []ROA) *set {
+	s := &set{roas: make(map[netip.Prefix][]ROA)}
+	seen := make(map[ROA]bool, len(roas))
+	lengths := make(map[int]bool)
+	for _, r := range roas {
+		r.Prefix = r.Prefix.Masked()
+		if !r.Prefix.IsValid() || int(r.MaxLength) < r.Prefix.Bits() || int(r.MaxLength) > r.Prefix.Addr().BitLen() {
+			continue
+		}
+		k := ROA{Prefix: r.Prefix, MaxLength: r.MaxLength, ASN: r.ASN}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		s.roas[r.Prefix] = append(s.roas[r.Prefix], r)
+		lengths[r.Prefix.Bits()] = true
+		s.len++
+	}
+	for _, roas := range s.roas {
+		slices.SortFunc(roas, func(a, b ROA) int {
+			if a.ASN != b.ASN {
+				return cmp.Compare(a.ASN, b.ASN)
+			}
+			return cmp.Compare(a.MaxLength, b.MaxLength)
+		})
+	}
+	for b := range lengths {
+		s.bits = append(s.bits, b)
+	}
+	slices.Sort(s.bits)
+	slices.Reverse(s.bits)
+	return s
+}
+
+// covering returns the ROAs whose prefix covers the prefix, most specific first.
+func (s *set) covering(prefix netip.Prefix) []ROA {
+	var ret []ROA
+	for _, b := range s.bits {
+		if b > prefix.Bits() {
+			continue
+		}
+		p, err := prefix.Addr().Prefix(b)
+		if err != nil {
+			continue
+		}
+		ret = append(ret, s.roas[p]...)
+	}
+	return ret
+}
+
+// validate validates the origin AS of the route as described in RFC 6811, AS 0 is never authorized.
+func (s *set) validate(prefix netip.Prefix, origin uint32) *Validation {
+	prefix = prefix.Masked()
+	v := &Validation{Origin: origin}
+	covering := s.covering(prefix)
+	if len(covering) == 0 {
+		v.State = NotFound
+		v.Description = fmt.Sprintf("No ROA covers %s", prefix)
+		return v
+	}
+	var authorized []ROA
+	for _, r := range covering {
+		if origin == 0 || r.ASN != origin {
+			continue
+		}
+		if prefix.Bits() <= int(r.MaxLength) {
+			v.State, v.ROAs = Valid, []ROA{r}
+			v.Description = fmt.Sprintf("AS%d is authorized to originate %s by ROA %s", origin, prefix, r)
+			return v
+		}
+		authorized = append(authorized, r)
+	}
+	v.State = Invalid
+	if len(authorized) > 0 {
+		v.Reason, v.ROAs = ReasonMaxLength, authorized
+		v.Description = fmt.Sprintf("%s is longer than the max length of ROA %s", prefix, join(authorized))
+	} else {
+		v.Reason, v.ROAs = ReasonOrigin, covering
+		v.Description = fmt.Sprintf("AS%d is not authorized to originate %s by ROA %s", origin, prefix, join(covering))
+	}
+	return v
+}
+
+// validateNone validates a route without origin AS, which no ROA authorizes, so it is invalid if any ROA covers it.
+func (s *set) validateNone(prefix netip.Prefix) *Validation {
+	prefix = prefix.Masked()
+	v := &Validation{State: NotFound}
+	covering := s.covering(prefix)
+	if len(covering) == 0 {
+		v.Description = fmt.Sprintf("No ROA covers %s", prefix)
+		return v
+	}
+	v.State, v.Reason, v.ROAs = Invalid, ReasonOrigin, covering
+	v.Description = fmt.Sprintf("The AS path of %s ends in an AS_SET and has no origin AS, it is covered by ROA %s", prefix, join(covering))
+	return v
+}
+
+func join(roas []ROA) string {
+	s := make([]string, len(roas))
+	for i, r := range roas {
+		s[i] = r.String()
+	}
+	return strings.Join(s, ", ")
+}
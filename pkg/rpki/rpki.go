@@ -0,0 +1,85 @@
+package rpki
+
+import (
+	"context"
+	"log"
+	"net/netip"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+const defaultReload = 5 * time.Minute
+
+// Validator validates the origin AS of routes against the ROAs of a JSON export or of a local validator over RTR.
+type Validator struct {
+	file *utils.Dataset[*set]
+	rtr  *rtrClient
+}
+
+// FromConfig returns the configured validator, or nil if RPKI is disabled.
+// The ROAs are received over RTR if it is enabled, otherwise they are loaded from the file.
+func FromConfig(ctx context.Context, cfg utils.RPKIConfig) *Validator {
+	if !cfg.Enabled {
+		return nil
+	}
+	v := &Validator{}
+	switch {
+	case cfg.RTR.Enabled:
+		v.rtr = newRTRClient(cfg.RTR)
+		go v.rtr.run(ctx)
+		log.Printf("NOTICE: Validating routes with ROAs from RTR cache %s", cfg.RTR.Address)
+	case cfg.File != "":
+		v.file = utils.NewDataset(ctx, "ROAs", cfg.File, utils.ParseDuration(cfg.Reload, defaultReload), parseROAs)
+	default:
+		log.Printf("WARNING: RPKI is enabled without ROA file or RTR cache, routes are not validated")
+		return nil
+	}
+	return v
+}
+
+// Validate validates the route by the origin AS of its AS path, see origin.
+// It returns nil if the route is invalid or has no AS path, or if no current ROAs are available.
+func (v *Validator) Validate(prefix string, segments []utils.ASPathSegment) *Validation {
+	if v == nil {
+		return nil
+	}
+	asn, none, ok := origin(segments)
+	if !ok {
+		return nil
+	}
+	p, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return nil
+	}
+	var s *set
+	if v.rtr != nil {
+		s = v.rtr.get()
+	} else {
+		s = v.file.Get()
+	}
+	if s == nil {
+		return nil
+	}
+	if none {
+		return s.validateNone(p)
+	}
+	return s.validate(p, asn)
+}
+
+// origin returns the origin AS of the AS path as described in RFC 6811, the last AS of its final segment.
+// Confederation segments are skipped. ok is false if the AS path is empty,
+// none is set if it ends in an AS_SET, such a route has no origin AS.
+func origin(segments []utils.ASPathSegment) (asn uint32, none bool, ok bool) {
+	for i := len(segments) - 1; i >= 0; i-- {
+		seg := segments[i]
+		if len(seg.ASNs) == 0 || seg.Type == utils.ASConfedSequence || seg.Type == utils.ASConfedSet {
+			continue
+		}
+		if seg.Type == utils.ASSet {
+			return 0, true, true
+		}
+		return seg.ASNs[len(seg.ASNs)-1], false, true
+	}
+	return 0, false, false
+}
@@ -0,0 +1,81 @@
+package rpki
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+func seq(asns ...uint32) utils.ASPathSegment {
+	return utils.ASPathSegment{Type: utils.ASSequence, ASNs: asns}
+}
+
+func asSet(asns ...uint32) utils.ASPathSegment {
+	return utils.ASPathSegment{Type: utils.ASSet, ASNs: asns}
+}
+
+func TestOrigin(t *testing.T) {
+	tests := []struct {
+		name     string
+		segments []utils.ASPathSegment
+		asn      uint32
+		none     bool
+		ok       bool
+	}{
+		{"empty", nil, 0, false, false},
+		{"sequence", []utils.ASPathSegment{seq(174, 13335)}, 13335, false, true},
+		{"set in the middle", []utils.ASPathSegment{seq(174), asSet(64496, 64497), seq(13335)}, 13335, false, true},
+		{"trailing set", []utils.ASPathSegment{seq(174, 13335), asSet(64496, 64497)}, 0, true, true},
+		{"trailing set of one AS", []utils.ASPathSegment{seq(174), asSet(13335)}, 0, true, true},
+		{"trailing confederation", []utils.ASPathSegment{seq(13335), {Type: utils.ASConfedSequence, ASNs: []uint32{65001}}}, 13335, false, true},
+		{"only confederation", []utils.ASPathSegment{{Type: utils.ASConfedSet, ASNs: []uint32{65001}}}, 0, false, false},
+	}
+	for _, tt := range tests {
+		asn, none, ok := origin(tt.segments)
+		if asn != tt.asn || none != tt.none || ok != tt.ok {
+			t.Errorf("%s: origin = %d, %t, %t, want %d, %t, %t", tt.name, asn, none, ok, tt.asn, tt.none, tt.ok)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s := newSet([]ROA{
+		{Prefix: netip.MustParsePrefix("192.0.2.0/24"), MaxLength: 24, ASN: 64496},
+		{Prefix: netip.MustParsePrefix("198.51.100.0/22"), MaxLength: 23, ASN: 64497},
+		{Prefix: netip.MustParsePrefix("203.0.113.0/24"), MaxLength: 24, ASN: 0},
+	})
+	tests := []struct {
+		prefix   string
+		segments []utils.ASPathSegment
+		state    string
+		reason   string
+	}{
+		{"192.0.2.0/24", []utils.ASPathSegment{seq(174, 64496)}, Valid, ""},
+		{"192.0.2.0/24", []utils.ASPathSegment{seq(174, 64499)}, Invalid, ReasonOrigin},
+		{"192.0.2.0/25", []utils.ASPathSegment{seq(64496)}, Invalid, ReasonMaxLength},
+		{"198.51.100.0/23", []utils.ASPathSegment{seq(64497)}, Valid, ""},
+		{"198.51.100.0/24", []utils.ASPathSegment{seq(64497)}, Invalid, ReasonMaxLength},
+		{"2001:db8::/32", []utils.ASPathSegment{seq(64496)}, NotFound, ""},
+		// AS 0 ROAs never authorize a route
+		{"203.0.113.0/24", []utils.ASPathSegment{seq(0)}, Invalid, ReasonOrigin},
+		// A route ending in an AS_SET has no origin AS, even if the set contains the authorized AS
+		{"192.0.2.0/24", []utils.ASPathSegment{seq(174), asSet(64496)}, Invalid, ReasonOrigin},
+		{"2001:db8::/32", []utils.ASPathSegment{seq(174), asSet(64496)}, NotFound, ""},
+	}
+	v := &Validator{rtr: &rtrClient{set: s, expires: time.Now().Add(time.Hour)}}
+	for _, tt := range tests {
+		got := v.Validate(tt.prefix, tt.segments)
+		if got == nil {
+			t.Errorf("Validate(%s, %v) = nil", tt.prefix, tt.segments)
+			continue
+		}
+		if got.State != tt.state || got.Reason != tt.reason {
+			t.Errorf("Validate(%s, %v) = %s (%s), want %s (%s)", tt.prefix, tt.segments, got.State, got.Reason, tt.state, tt.reason)
+		}
+	}
+	if got := v.Validate("192.0.2.0/24", nil); got != nil {
+		t.Errorf("Validate without AS path = %v, want nil", got)
+	}
+}
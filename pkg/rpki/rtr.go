@@ -0,0 +1,307 @@
+package rpki
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/utils"
+)
+
+// PDU types of RFC 8210.
+const (
+	pduSerialNotify  = 0
+	pduSerialQuery   = 1
+	pduResetQuery    = 2
+	pduCacheResponse = 3
+	pduIPv4Prefix    = 4
+	pduIPv6Prefix    = 6
+	pduEndOfData     = 7
+	pduCacheReset    = 8
+	pduRouterKey     = 9
+	pduErrorReport   = 10
+)
+
+const (
+	rtrVersion     = 1
+	rtrTimeout     = time.Minute
+	maxPDULength   = 1 << 16
+	errNoData      = 2 // errNoData is the error code of a cache that has no data yet
+	errVersion     = 4 // errVersion is the error code of a cache that does not support the protocol version
+	defaultRefresh = time.Hour
+	defaultRetry   = time.Minute // defaultRetry is shorter than the one of RFC 8210 as the cache is expected to be local
+	defaultExpire  = 2 * time.Hour
+)
+
+var errUnsupportedVersion = errors.New("cache does not support the protocol version")
+
+// rtrClient receives ROAs from an RTR cache as described in RFC 8210, falling back to version 0 of RFC 6810.
+// The ROAs are kept after the session failed until they expire.
+type rtrClient struct {
+	address string
+	refresh time.Duration // refresh, retry and expire override the intervals of the cache if set
+	retry   time.Duration
+	expire  time.Duration
+
+	// The session state is only used by run.
+	version   uint8
+	session   uint16
+	serial    uint32
+	synced    bool // synced is whether roas are the data of serial
+	resetting bool
+	waiting   bool // waiting is whether a query is not answered yet
+	roas      map[ROA]struct{}
+
+	// The intervals of the cache, only sent by version 1 caches.
+	cacheRefresh time.Duration
+	cacheRetry   time.Duration
+	cacheExpire  time.Duration
+
+	mu      sync.RWMutex
+	set     *set
+	expires time.Time
+}
+
+func newRTRClient(cfg utils.RTRConfig) *rtrClient {
+	return &rtrClient{
+		address: cfg.Address,
+		refresh: utils.ParseDuration(cfg.Refresh, 0),
+		retry:   utils.ParseDuration(cfg.Retry, 0),
+		expire:  utils.ParseDuration(cfg.Expire, 0),
+		version: rtrVersion,
+		roas:    make(map[ROA]struct{}),
+
+		cacheRefresh: defaultRefresh,
+		cacheRetry:   defaultRetry,
+		cacheExpire:  defaultExpire,
+	}
+}
+
+// get returns the ROAs, or nil if they expired.
+func (c *rtrClient) get() *set {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.set == nil || time.Now().After(c.expires) {
+		return nil
+	}
+	return c.set
+}
+
+// run keeps a session to the cache until the context is done.
+func (c *rtrClient) run(ctx context.Context) {
+	for {
+		err := c.connect(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if c.waiting {
+			c.synced = false
+		}
+		if errors.Is(err, errUnsupportedVersion) && c.version > 0 {
+			c.version--
+			log.Printf("WARNING: RTR cache %s does not support version %d, falling back to version %d", c.address, c.version+1, c.version)
+			continue
+		}
+		retry := interval(c.retry, c.cacheRetry)
+		log.Printf("ERROR: RTR session with %s failed, retrying in %s: %s", c.address, retry, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+	}
+}
+
+// interval returns the configured interval or the one of the cache.
+func interval(cfg time.Duration, cache time.Duration) time.Duration {
+	if cfg > 0 {
+		return cfg
+	}
+	return cache
+}
+
+func (c *rtrClient) connect(ctx context.Context) error {
+	d := net.Dialer{Timeout: rtrTimeout}
+	conn, err := d.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	r := bufio.NewReader(conn)
+	if err := c.query(conn); err != nil {
+		return err
+	}
+	for {
+		if c.waiting {
+			conn.SetReadDeadline(time.Now().Add(rtrTimeout))
+		} else {
+			conn.SetReadDeadline(time.Now().Add(interval(c.refresh, c.cacheRefresh)))
+		}
+		version, typ, session, body, err := readPDU(r)
+		if errors.Is(err, os.ErrDeadlineExceeded) && !c.waiting {
+			if err := c.query(conn); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+		if typ == pduErrorReport {
+			return reportError(session, body)
+		}
+		if version > c.version {
+			return fmt.Errorf("unexpected version %d", version)
+		}
+		c.version = version
+		switch typ {
+		case pduSerialNotify:
+			if !c.waiting {
+				if err := c.query(conn); err != nil {
+					return err
+				}
+			}
+		case pduCacheResponse:
+			if c.resetting {
+				clear(c.roas)
+			}
+			c.session = session
+		case pduIPv4Prefix, pduIPv6Prefix:
+			if !c.waiting {
+				return errors.New("unexpected prefix")
+			}
+			roa, announce, err := parsePrefix(typ, body)
+			if err != nil {
+				return err
+			}
+			if announce {
+				c.roas[roa] = struct{}{}
+			} else {
+				delete(c.roas, roa)
+			}
+		case pduEndOfData:
+			if len(body) < 4 {
+				return errors.New("short end of data")
+			}
+			c.serial = binary.BigEndian.Uint32(body)
+			if len(body) >= 16 {
+				c.cacheRefresh = time.Duration(binary.BigEndian.Uint32(body[4:])) * time.Second
+				c.cacheRetry = time.Duration(binary.BigEndian.Uint32(body[8:])) * time.Second
+				c.cacheExpire = time.Duration(binary.BigEndian.Uint32(body[12:])) * time.Second
+			}
+			c.publish()
+			c.synced, c.waiting = true, false
+		case pduCacheReset:
+			c.synced = false
+			if err := c.query(conn); err != nil {
+				return err
+			}
+		case pduRouterKey:
+		default:
+			return fmt.Errorf("unexpected PDU type %d", typ)
+		}
+	}
+}
+
+// query sends a serial query for the changes since the last update, or a reset query if the client is not synced.
+func (c *rtrClient) query(w io.Writer) error {
+	var pdu []byte
+	if c.synced {
+		pdu = make([]byte, 12)
+		pdu[1] = pduSerialQuery
+		binary.BigEndian.PutUint16(pdu[2:], c.session)
+		binary.BigEndian.PutUint32(pdu[8:], c.serial)
+	} else {
+		pdu = make([]byte, 8)
+		pdu[1] = pduResetQuery
+	}
+	pdu[0] = c.version
+	binary.BigEndian.PutUint32(pdu[4:], uint32(len(pdu)))
+	c.resetting, c.waiting = !c.synced, true
+	_, err := w.Write(pdu)
+	return err
+}
+
+// publish replaces the ROAs used for validation by the received ones.
+func (c *rtrClient) publish() {
+	roas := make([]ROA, 0, len(c.roas))
+	for r := range c.roas {
+		roas = append(roas, r)
+	}
+	s := newSet(roas)
+	c.mu.Lock()
+	c.set = s
+	c.expires = time.Now().Add(interval(c.expire, c.cacheExpire))
+	c.mu.Unlock()
+	if c.resetting {
+		log.Printf("NOTICE: Loaded %d ROAs from RTR cache %s", s.len, c.address)
+	}
+}
+
+// readPDU reads a PDU, the session field is the error code of error reports.
+func readPDU(r io.Reader) (version uint8, typ uint8, session uint16, body []byte, err error) {
+	var hdr [8]byte
+	if _, err = io.ReadFull(r, hdr[:]); err != nil {
+		return
+	}
+	length := binary.BigEndian.Uint32(hdr[4:])
+	if length < 8 || length > maxPDULength {
+		err = fmt.Errorf("invalid PDU length %d", length)
+		return
+	}
+	body = make([]byte, length-8)
+	if _, err = io.ReadFull(r, body); err != nil {
+		return
+	}
+	return hdr[0], hdr[1], binary.BigEndian.Uint16(hdr[2:]), body, nil
+}
+
+// parsePrefix parses an IPv4 or IPv6 prefix PDU, announce is false if the ROA is withdrawn.
+func parsePrefix(typ uint8, body []byte) (roa ROA, announce bool, err error) {
+	size := 4
+	if typ == pduIPv6Prefix {
+		size = 16
+	}
+	if len(body) != 8+size {
+		return roa, false, fmt.Errorf("invalid prefix PDU length %d", len(body)+8)
+	}
+	addr, _ := netip.AddrFromSlice(body[4 : 4+size])
+	roa.Prefix, err = addr.Prefix(int(body[1]))
+	if err != nil {
+		return roa, false, err
+	}
+	roa.MaxLength = body[2]
+	roa.ASN = binary.BigEndian.Uint32(body[4+size:])
+	return roa, body[0]&1 == 1, nil
+}
+
+// reportError returns the error of an error report.
+func reportError(code uint16, body []byte) error {
+	if code == errVersion {
+		return errUnsupportedVersion
+	}
+	var text string
+	if len(body) >= 4 {
+		n := int(binary.BigEndian.Uint32(body))
+		if len(body) >= 8+n {
+			if m := int(binary.BigEndian.Uint32(body[4+n:])); len(body) >= 8+n+m {
+				text = string(body[8+n : 8+n+m])
+			}
+		}
+	}
+	if code == errNoData {
+		return fmt.Errorf("cache has no data yet: %s", text)
+	}
+	return fmt.Errorf("error report %d: %s", code, text)
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"net"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+type BGPNeighbor struct {
+	Address          string
+	ASN              uint32
+	State            string
+	Uptime           time.Duration
+	PrefixesReceived int64
+	PrefixesSent     int64
+	AddressFamily    string
+	Description      string
+}
+
+// Types of AS path segments, named like FRRouting does.
+const (
+	ASSequence       = "as-sequence"
+	ASSet            = "as-set"
+	ASConfedSequence = "as-confed-sequence"
+	ASConfedSet      = "as-confed-set"
+)
+
+// ASPathSegment is a segment of an AS path, the ASNs of a set are unordered.
+type ASPathSegment struct {
+	Type string
+	ASNs []uint32
+}
+
+type BGPPath struct {
+	Prefix           string
+	NextHop          string
+	ASPath           []uint32 // ASPath is the flattened AS path, see ASPathSegments for its AS sets
+	ASPathSegments   []ASPathSegment
+	Communities      []string
+	LargeCommunities []string
+	LocalPref        uint32
+	MED              uint32
+	Origin           string
+	Best             bool
+	Age              time.Duration
+	Peer             string
+}
+
+// BestPath returns the path marked as best or nil if none is marked.
+func BestPath(paths []*BGPPath) *BGPPath {
+	for _, p := range paths {
+		if p.Best {
+			return p
+		}
+	}
+	return nil
+}
+
+// Normalize returns a copy of the path with sorted, deduplicated communities and a canonical next hop,
+// such that paths received by different routers compare equal.
+func (p *BGPPath) Normalize() *BGPPath {
+	n := *p
+	if ip := net.ParseIP(p.NextHop); ip != nil {
+		n.NextHop = ip.String()
+	}
+	n.Communities = sortedSet(p.Communities)
+	n.LargeCommunities = sortedSet(p.LargeCommunities)
+	return &n
+}
+
+// PathDifferences returns the attributes the normalized paths differ in, named like the fields of the protobuf BGPPath.
+func PathDifferences(paths []*BGPPath) []string {
+	if len(paths) < 2 {
+		return nil
+	}
+	attrs := []struct {
+		name  string
+		equal func(a, b *BGPPath) bool
+	}{
+		{"prefix", func(a, b *BGPPath) bool { return a.Prefix == b.Prefix }},
+		{"next_hop", func(a, b *BGPPath) bool { return a.NextHop == b.NextHop }},
+		{"as_path", func(a, b *BGPPath) bool { return slices.Equal(a.ASPath, b.ASPath) }},
+		{"local_pref", func(a, b *BGPPath) bool { return a.LocalPref == b.LocalPref }},
+		{"med", func(a, b *BGPPath) bool { return a.MED == b.MED }},
+		{"origin", func(a, b *BGPPath) bool { return strings.EqualFold(a.Origin, b.Origin) }},
+		{"communities", func(a, b *BGPPath) bool { return slices.Equal(a.Communities, b.Communities) }},
+		{"large_communities", func(a, b *BGPPath) bool { return slices.Equal(a.LargeCommunities, b.LargeCommunities) }},
+	}
+	var diff []string
+	for _, attr := range attrs {
+		for _, p := range paths[1:] {
+			if !attr.equal(paths[0], p) {
+				diff = append(diff, attr.name)
+				break
+			}
+		}
+	}
+	return diff
+}
+
+func sortedSet(s []string) []string {
+	s = slices.Clone(s)
+	sort.Strings(s)
+	return slices.Compact(s)
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBestPath(t *testing.T) {
+	a := &BGPPath{NextHop: "192.0.2.1"}
+	b := &BGPPath{NextHop: "192.0.2.2", Best: true}
+	c := &BGPPath{NextHop: "192.0.2.3", Best: true}
+	tests := []struct {
+		name  string
+		paths []*BGPPath
+		want  *BGPPath
+	}{
+		{"best path", []*BGPPath{a, b}, b},
+		{"first of several marked best", []*BGPPath{a, c, b}, c},
+		{"no path marked best", []*BGPPath{a}, nil},
+		{"no paths", nil, nil},
+	}
+	for _, tt := range tests {
+		if got := BestPath(tt.paths); got != tt.want {
+			t.Errorf("%s: BestPath = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		path BGPPath
+		want BGPPath
+	}{
+		{
+			"IPv6 next hop",
+			BGPPath{NextHop: "2001:db8::0:1"},
+			BGPPath{NextHop: "2001:db8::1"},
+		},
+		{
+			"uppercase IPv6 next hop",
+			BGPPath{NextHop: "2001:DB8:0:0::1"},
+			BGPPath{NextHop: "2001:db8::1"},
+		},
+		{
+			"IPv4 next hop",
+			BGPPath{NextHop: "192.0.2.1"},
+			BGPPath{NextHop: "192.0.2.1"},
+		},
+		{
+			"next hop that is no address",
+			BGPPath{NextHop: "Null0"},
+			BGPPath{NextHop: "Null0"},
+		},
+		{
+			"unordered and duplicate communities",
+			BGPPath{Communities: []string{"65000:2", "65000:10", "65000:2"}, LargeCommunities: []string{"65000:1:2", "65000:1:1", "65000:1:1"}},
+			BGPPath{Communities: []string{"65000:10", "65000:2"}, LargeCommunities: []string{"65000:1:1", "65000:1:2"}},
+		},
+	}
+	for _, tt := range tests {
+		communities := slices.Clone(tt.path.Communities)
+		got := tt.path.Normalize()
+		if got.NextHop != tt.want.NextHop {
+			t.Errorf("%s: next hop = %s, want %s", tt.name, got.NextHop, tt.want.NextHop)
+		}
+		if !slices.Equal(got.Communities, tt.want.Communities) {
+			t.Errorf("%s: communities = %q, want %q", tt.name, got.Communities, tt.want.Communities)
+		}
+		if !slices.Equal(got.LargeCommunities, tt.want.LargeCommunities) {
+			t.Errorf("%s: large communities = %q, want %q", tt.name, got.LargeCommunities, tt.want.LargeCommunities)
+		}
+		if !slices.Equal(tt.path.Communities, communities) {
+			t.Errorf("%s: Normalize modified the communities of the path to %q", tt.name, tt.path.Communities)
+		}
+	}
+}
+
+func TestPathDifferences(t *testing.T) {
+	base := BGPPath{
+		Prefix:      "192.0.2.0/24",
+		NextHop:     "2001:db8::1",
+		ASPath:      []uint32{65001, 65002},
+		Communities: []string{"65000:1", "65000:2"},
+		LocalPref:   100,
+		Origin:      "IGP",
+	}
+	with := func(f func(p *BGPPath)) *BGPPath {
+		p := base
+		f(&p)
+		return p.Normalize()
+	}
+	tests := []struct {
+		name  string
+		paths []*BGPPath
+		want  []string
+	}{
+		{"single path", []*BGPPath{&base}, nil},
+		{"equal paths", []*BGPPath{&base, with(func(p *BGPPath) {})}, nil},
+		{"next hop written differently", []*BGPPath{base.Normalize(), with(func(p *BGPPath) { p.NextHop = "2001:db8::0:1" })}, nil},
+		{"unordered and duplicate communities", []*BGPPath{base.Normalize(), with(func(p *BGPPath) { p.Communities = []string{"65000:2", "65000:1", "65000:2"} })}, nil},
+		{"origin in other case", []*BGPPath{&base, with(func(p *BGPPath) { p.Origin = "igp" })}, nil},
+		{"other origin", []*BGPPath{&base, with(func(p *BGPPath) { p.Origin = "incomplete" })}, []string{"origin"}},
+		{"other next hop", []*BGPPath{&base, with(func(p *BGPPath) { p.NextHop = "2001:db8::2" })}, []string{"next_hop"}},
+		{"prepended AS path", []*BGPPath{&base, with(func(p *BGPPath) { p.ASPath = []uint32{65001, 65001, 65002} })}, []string{"as_path"}},
+		{
+			"differences of a later path",
+			[]*BGPPath{&base, &base, with(func(p *BGPPath) {
+				p.LocalPref = 200
+				p.MED = 10
+				p.LargeCommunities = []string{"65000:1:1"}
+			})},
+			[]string{"local_pref", "med", "large_communities"},
+		},
+		{"missing community", []*BGPPath{&base, with(func(p *BGPPath) { p.Communities = []string{"65000:1"} })}, []string{"communities"}},
+	}
+	for _, tt := range tests {
+		if got := PathDifferences(tt.paths); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: PathDifferences = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
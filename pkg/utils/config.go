@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"strings"
 	"time"
@@ -25,25 +26,63 @@ type Config struct {
 	Web         WebConfig         `yaml:"web"`
 	SecurityTxt SecurityTxtConfig `yaml:"security.txt"`
 	Redis       RedisConfig       `yaml:"redis"`
+	SSH         SSHConfig         `yaml:"ssh"`
+	Metrics     MetricsConfig     `yaml:"metrics"`
+	Cache       CacheConfig       `yaml:"cache"`
+	RateLimit   RateLimitConfig   `yaml:"ratelimit"`
+	Policy      PolicyConfig      `yaml:"policy"`
+	Abuse       AbuseConfig       `yaml:"abuse"`
+	Challenge   ChallengeConfig   `yaml:"challenge"`
+	Auth        AuthConfig        `yaml:"auth"`
+	Enrichment  EnrichmentConfig  `yaml:"enrichment"`
+	RPKI        RPKIConfig        `yaml:"rpki"`
 }
 
 type RouterConfig struct {
-	Name     string `yaml:"name"`
-	Hostname string `yaml:"hostname"`
-	Username string `yaml:"username"`
-	Password string `yaml:"password"`
-	SSHKey   string `yaml:"ssh_key"`
-	VRF      string `yaml:"vrf"`
-	Location string `yaml:"location"`
-	Source4  *IPNet `yaml:"source4"`
-	Source6  *IPNet `yaml:"source6"`
-	Type     string `yaml:"type"`
+	Name                  string `yaml:"name"`
+	Hostname              string `yaml:"hostname"`
+	Username              string `yaml:"username"`
+	Password              string `yaml:"password"`
+	SSHKey                string `yaml:"ssh_key"`
+	VRF                   string `yaml:"vrf"`
+	Location              string `yaml:"location"`
+	Source4               *IPNet `yaml:"source4"`
+	Source6               *IPNet `yaml:"source6"`
+	Type                  string `yaml:"type"`
+	Keepalive             string `yaml:"keepalive"`
+	IdleTimeout           string `yaml:"idle_timeout"`
+	MaxSessions           int    `yaml:"max_sessions"`
+	MaxConcurrent         int    `yaml:"max_concurrent"`
+	QueueDepth            int    `yaml:"queue_depth"`
+	HostKey               string `yaml:"host_key"`
+	KnownHosts            string `yaml:"known_hosts"`
+	TOFU                  bool   `yaml:"tofu"`
+	InsecureIgnoreHostKey bool   `yaml:"insecure_ignore_host_key"`
+	Hidden                bool   `yaml:"hidden"`
+
+	Timeout TimeoutsConfig `yaml:"timeout"`
+	Policy  PolicyConfig   `yaml:"policy"`
+
+	globalKnownHosts string
+}
+
+type SSHConfig struct {
+	KnownHosts            string `yaml:"known_hosts"`
+	TOFU                  bool   `yaml:"tofu"`
+	InsecureIgnoreHostKey bool   `yaml:"insecure_ignore_host_key"`
+}
+
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Listen  string `yaml:"listen"`
+	Path    string `yaml:"path"`
 }
 
 type GrpcConfig struct {
-	Enabled bool      `yaml:"enabled"`
-	Listen  string    `yaml:"listen"`
-	TLS     TLSConfig `yaml:"tls"`
+	Enabled        bool      `yaml:"enabled"`
+	Listen         string    `yaml:"listen"`
+	TLS            TLSConfig `yaml:"tls"`
+	TrustedProxies []string  `yaml:"trusted_proxies"`
 }
 
 type TLSConfig struct {
@@ -54,9 +93,156 @@ type TLSConfig struct {
 }
 
 type RedisConfig struct {
+	Enabled    bool     `yaml:"enabled"`
+	URI        string   `yaml:"uri"`
+	TTL        string   `yaml:"ttl"`
+	Addrs      []string `yaml:"addrs"`
+	MasterName string   `yaml:"master_name"`
+	Cluster    bool     `yaml:"cluster"`
+	Username   string   `yaml:"username"`
+	Password   string   `yaml:"password"`
+	DB         int      `yaml:"db"`
+}
+
+type CacheConfig struct {
+	Backend    string            `yaml:"backend"`
+	TTL        string            `yaml:"ttl"`
+	Operations map[string]string `yaml:"operations"`
+	MaxEntries int               `yaml:"max_entries"`
+	Path       string            `yaml:"path"`
+	AdminToken string            `yaml:"admin_token"`
+}
+
+type RateLimitConfig struct {
+	Enabled    bool                 `yaml:"enabled"`
+	Default    RateLimit            `yaml:",inline"`
+	Operations map[string]RateLimit `yaml:"operations"`
+}
+
+type RateLimit struct {
+	Rate     int    `yaml:"rate"`
+	Interval string `yaml:"interval"`
+	Burst    int    `yaml:"burst"`
+}
+
+type PolicyConfig struct {
+	PolicyRules `yaml:",inline"`
+	Operations  map[string]PolicyRules `yaml:"operations"`
+}
+
+type PolicyRules struct {
+	Bogons          *bool        `yaml:"bogons"`
+	Deny            []string     `yaml:"deny"`
+	Allow           []string     `yaml:"allow"`
+	MinPrefixLength PrefixLength `yaml:"min_prefix_length"`
+}
+
+type PrefixLength struct {
+	IPv4 int `yaml:"ipv4"`
+	IPv6 int `yaml:"ipv6"`
+}
+
+type AbuseConfig struct {
+	Enabled       bool         `yaml:"enabled"`
+	Operations    []string     `yaml:"operations"`
+	Limit         int          `yaml:"limit"`
+	Window        string       `yaml:"window"`
+	PrefixLength  PrefixLength `yaml:"prefix_length"`
+	BlockAfter    int          `yaml:"block_after"`
+	BlockWindow   string       `yaml:"block_window"`
+	BlockDuration string       `yaml:"block_duration"`
+	Log           bool         `yaml:"log"`
+	Redis         bool         `yaml:"redis"`
+}
+
+type ChallengeConfig struct {
+	Enabled       bool     `yaml:"enabled"`
+	Secret        string   `yaml:"secret"`
+	Operations    []string `yaml:"operations"`
+	Difficulty    int      `yaml:"difficulty"`
+	MaxDifficulty int      `yaml:"max_difficulty"`
+	Step          int      `yaml:"step"`
+	Window        string   `yaml:"window"`
+	TTL           string   `yaml:"ttl"`
+}
+
+type AuthConfig struct {
+	Tokens    []TokenConfig   `yaml:"tokens"`
+	TokenFile string          `yaml:"token_file"`
+	OIDC      OIDCConfig      `yaml:"oidc"`
+	Anonymous AnonymousConfig `yaml:"anonymous"`
+}
+
+type OIDCConfig struct {
+	Enabled     bool                  `yaml:"enabled"`
+	Issuer      string                `yaml:"issuer"`
+	Audience    []string              `yaml:"audience"`
+	JWKS        string                `yaml:"jwks"`
+	JWKSRefresh string                `yaml:"jwks_refresh"`
+	RolesClaim  string                `yaml:"roles_claim"`
+	NameClaim   string                `yaml:"name_claim"`
+	Leeway      string                `yaml:"leeway"`
+	Roles       map[string]RoleConfig `yaml:"roles"`
+}
+
+type RoleConfig struct {
+	Operations []string `yaml:"operations"`
+	Routers    []string `yaml:"routers"`
+	Hidden     bool     `yaml:"hidden"`
+}
+
+type AnonymousConfig struct {
+	Operations []string `yaml:"operations"`
+}
+
+type EnrichmentConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	DNS     DNSConfig     `yaml:"dns"`
+	ASN     DatasetConfig `yaml:"asn"`
+	IXP     DatasetConfig `yaml:"ixp"`
+	ASNames DatasetConfig `yaml:"as_names"`
+}
+
+type DNSConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	Resolver    string `yaml:"resolver"`
+	Timeout     string `yaml:"timeout"`
+	CacheTTL    string `yaml:"cache_ttl"`
+	NegativeTTL string `yaml:"negative_ttl"`
+	CacheSize   int    `yaml:"cache_size"`
+}
+
+type DatasetConfig struct {
+	File   string `yaml:"file"`
+	Reload string `yaml:"reload"`
+}
+
+type RPKIConfig struct {
+	Enabled bool      `yaml:"enabled"`
+	File    string    `yaml:"file"`
+	Reload  string    `yaml:"reload"`
+	RTR     RTRConfig `yaml:"rtr"`
+}
+
+type RTRConfig struct {
 	Enabled bool   `yaml:"enabled"`
-	URI     string `yaml:"uri"`
-	TTL     string `yaml:"ttl"`
+	Address string `yaml:"address"`
+	Refresh string `yaml:"refresh"`
+	Retry   string `yaml:"retry"`
+	Expire  string `yaml:"expire"`
+}
+
+type TokenConfig struct {
+	Name        string     `yaml:"name"`
+	Token       string     `yaml:"token"`
+	SHA256      string     `yaml:"sha256"`
+	Operations  []string   `yaml:"operations"`
+	Routers     []string   `yaml:"routers"`
+	Hidden      bool       `yaml:"hidden"`
+	Quota       int        `yaml:"quota"`
+	QuotaPeriod string     `yaml:"quota_period"`
+	RateLimit   *RateLimit `yaml:"ratelimit"`
+	Expires     string     `yaml:"expires"`
 }
 
 type WebConfig struct {
@@ -152,5 +338,14 @@ func ValidateConfig(c *Config) {
 		if v.Source6 == nil {
 			c.Devices[k].Source6, _ = NewIPNET("::1")
 		}
+		c.Devices[k].globalKnownHosts = c.SSH.KnownHosts
+		c.Devices[k].TOFU = v.TOFU || c.SSH.TOFU
+		c.Devices[k].InsecureIgnoreHostKey = v.InsecureIgnoreHostKey || c.SSH.InsecureIgnoreHostKey
+		switch {
+		case c.Devices[k].InsecureIgnoreHostKey:
+			log.Printf("WARNING: Host key of %s is not verified, insecure_ignore_host_key is set", v.Name)
+		case v.HostKey == "" && v.KnownHosts == "" && c.SSH.KnownHosts == "":
+			log.Printf("NOTICE: Host key of %s is verified against %s, configure host_key or known_hosts to change this", v.Name, defaultKnownHosts())
+		}
 	}
 }
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Dataset is data parsed from a local file, the file is checked for changes periodically and reloaded.
+// The data is loaded in the background, Get returns the zero value until the first load succeeded.
+type Dataset[T any] struct {
+	name  string
+	file  string
+	parse func([]byte) (T, error)
+
+	mu    sync.RWMutex
+	data  T
+	mtime time.Time // mtime is only used by watch
+}
+
+// NewDataset loads the file and reloads it when it changed every interval until the context is done.
+// The file is only loaded once if interval is zero.
+func NewDataset[T any](ctx context.Context, name string, file string, interval time.Duration, parse func([]byte) (T, error)) *Dataset[T] {
+	d := &Dataset[T]{name: name, file: file, parse: parse}
+	go d.watch(ctx, interval)
+	return d
+}
+
+// Get returns the data of the last successful load.
+func (d *Dataset[T]) Get() T {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.data
+}
+
+func (d *Dataset[T]) watch(ctx context.Context, interval time.Duration) {
+	d.reload()
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.reload()
+		}
+	}
+}
+
+// reload loads the file if it changed, the previous data is kept if it is invalid.
+func (d *Dataset[T]) reload() {
+	fi, err := os.Stat(d.file)
+	if err != nil {
+		log.Printf("ERROR: Failed to read %s: %s", d.name, err)
+		return
+	}
+	if fi.ModTime().Equal(d.mtime) {
+		return
+	}
+	d.mtime = fi.ModTime()
+	start := time.Now()
+	b, err := os.ReadFile(d.file)
+	if err != nil {
+		log.Printf("ERROR: Failed to read %s: %s", d.name, err)
+		return
+	}
+	data, err := d.parse(b)
+	if err != nil {
+		log.Printf("ERROR: Failed to parse %s: %s", d.name, err)
+		return
+	}
+	d.mu.Lock()
+	d.data = data
+	d.mu.Unlock()
+	log.Printf("NOTICE: Loaded %s from %s in %s", d.name, d.file, time.Since(start).Round(time.Millisecond))
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// knownHostsMu serializes trust-on-first-use writes to known_hosts files.
+var knownHostsMu sync.Mutex
+
+// sshHostKeyCallback returns the host key verification for the router.
+// A pinned host_key takes precedence over the known_hosts files, if neither is configured ~/.ssh/known_hosts is used.
+// Host keys are only ignored if insecure_ignore_host_key is set explicitly.
+func sshHostKeyCallback(router *RouterConfig) (ssh.HostKeyCallback, error) {
+	if router.InsecureIgnoreHostKey {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+	if router.HostKey != "" {
+		pinned, _, _, _, err := ssh.ParseAuthorizedKey([]byte(router.HostKey))
+		if err != nil {
+			return nil, errs.HostKeyInvalid
+		}
+		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			if !bytes.Equal(pinned.Marshal(), key.Marshal()) {
+				log.Printf("ERROR: Host key of %s (%s) does not match the pinned key, got %s", router.Name, hostname, ssh.FingerprintSHA256(key))
+				return errs.HostKeyMismatch
+			}
+			return nil
+		}, nil
+	}
+	files := router.knownHostsFiles()
+	if len(files) == 0 {
+		log.Printf("ERROR: No known_hosts file to verify the host key of %s, configure host_key or known_hosts", router.Name)
+		return nil, errs.HostKeyUnknown
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		knownHostsMu.Lock()
+		defer knownHostsMu.Unlock()
+		var existing []string
+		for _, f := range files {
+			if _, err := os.Stat(f); err == nil {
+				existing = append(existing, f)
+			}
+		}
+		cb, err := knownhosts.New(existing...)
+		if err != nil {
+			log.Printf("ERROR: Failed to read known_hosts %s: %s", strings.Join(existing, ", "), err)
+			return errs.HostKeyInvalid
+		}
+		err = cb(hostname, remote, key)
+		var kerr *knownhosts.KeyError
+		switch {
+		case err == nil:
+			return nil
+		case errors.As(err, &kerr) && len(kerr.Want) > 0:
+			log.Printf("ERROR: Host key of %s (%s) has changed, got %s", router.Name, hostname, ssh.FingerprintSHA256(key))
+			return errs.HostKeyMismatch
+		case errors.As(err, &kerr) && router.TOFU:
+			return trustOnFirstUse(router, files[0], hostname, key)
+		case errors.As(err, &kerr):
+			log.Printf("ERROR: Host key of %s (%s) is unknown, got %s", router.Name, hostname, ssh.FingerprintSHA256(key))
+			return errs.HostKeyUnknown
+		default:
+			return err
+		}
+	}, nil
+}
+
+// trustOnFirstUse records a previously unknown host key, the caller must hold knownHostsMu.
+func trustOnFirstUse(router *RouterConfig, file string, hostname string, key ssh.PublicKey) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		log.Printf("ERROR: Failed to create the directory of known_hosts %s: %s", file, err)
+		return errs.HostKeyUnknown
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		log.Printf("ERROR: Failed to open known_hosts %s: %s", file, err)
+		return errs.HostKeyUnknown
+	}
+	defer f.Close()
+	if _, err := f.WriteString(knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key) + "\n"); err != nil {
+		log.Printf("ERROR: Failed to write known_hosts %s: %s", file, err)
+		return errs.HostKeyUnknown
+	}
+	log.Printf("NOTICE: Trusting host key %s of %s (%s) on first use", ssh.FingerprintSHA256(key), router.Name, hostname)
+	return nil
+}
+
+// knownHostsFiles returns the device specific known_hosts followed by the global one, or ~/.ssh/known_hosts if neither is configured.
+func (rc *RouterConfig) knownHostsFiles() []string {
+	var ret []string
+	for _, f := range []string{rc.KnownHosts, rc.globalKnownHosts} {
+		if f != "" {
+			ret = append(ret, f)
+		}
+	}
+	if len(ret) == 0 {
+		if f := defaultKnownHosts(); f != "" {
+			ret = append(ret, f)
+		}
+	}
+	return ret
+}
+
+// defaultKnownHosts returns ~/.ssh/known_hosts, or an empty string if the home directory is unknown.
+func defaultKnownHosts() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".ssh", "known_hosts")
+}
@@ -1,19 +1,32 @@
 package utils
 
-import "time"
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/metrics"
+)
 
 type Router interface {
-	Ping(*RouterConfig, *IPNet) ([]string, error)
-	Traceroute(*RouterConfig, *IPNet) ([]string, error)
-	BGPRoute(*RouterConfig, *IPNet) ([]string, error)
-	BGPCommunity(*RouterConfig, string) ([]string, error)
-	BGPASPath(*RouterConfig, string) ([]string, error)
+	Ping(context.Context, *RouterConfig, *IPNet) ([]string, error)
+	Traceroute(context.Context, *RouterConfig, *IPNet) ([]string, error)
+	BGPSummary(context.Context, *RouterConfig) ([]string, error)
+	BGPRoute(context.Context, *RouterConfig, *IPNet) ([]string, error)
+	BGPCommunity(context.Context, *RouterConfig, string) ([]string, error)
+	BGPASPath(context.Context, *RouterConfig, string) ([]string, error)
+	Parser(string) Parser
+	Operations() map[string]*Operation
+	Execute(context.Context, *RouterConfig, string, map[string]any) ([]string, error)
 }
 
 type RouterInstance struct {
 	Router      Router
 	Config      *RouterConfig
 	HealthCheck *HealthCheck
+	Limiter     *Limiter
+	Policy      *Policy
 }
 
 type HealthCheck struct {
@@ -21,55 +34,97 @@ type HealthCheck struct {
 	Healthy bool
 }
 
-func (rt *RouterInstance) Healthcheck() error {
-	_, err := SSHExec(rt.Config, []string{})
+func (rt *RouterInstance) Healthcheck(ctx context.Context) error {
+	_, err := SSHExec(ctx, rt.Config, "healthcheck", []string{})
 	rt.HealthCheck.Checked = time.Now()
 	if err == nil {
 		rt.HealthCheck.Healthy = true
 	} else {
 		rt.HealthCheck.Healthy = false
 	}
+	healthy := 0.0
+	if rt.HealthCheck.Healthy {
+		healthy = 1
+	}
+	metrics.RouterHealthy.WithLabelValues(rt.Config.Name, rt.Config.Location).Set(healthy)
+	metrics.RouterHealthcheckTimestamp.WithLabelValues(rt.Config.Name, rt.Config.Location).Set(float64(rt.HealthCheck.Checked.Unix()))
 	return err
 }
 
-func (rt *RouterInstance) Ping(param *IPNet) ([]string, error) {
-	cmd, err := rt.Router.Ping(rt.Config, param)
+func (rt *RouterInstance) Ping(ctx context.Context, param *IPNet) ([]string, error) {
+	cmd, err := rt.Router.Ping(ctx, rt.Config, param)
 	if err != nil {
 		return nil, err
 	}
-	return SSHExec(rt.Config, cmd)
+	return SSHExec(ctx, rt.Config, "ping", cmd)
 }
 
-func (rt *RouterInstance) Traceroute(param *IPNet) ([]string, error) {
-	cmd, err := rt.Router.Traceroute(rt.Config, param)
+func (rt *RouterInstance) Traceroute(ctx context.Context, param *IPNet) ([]string, error) {
+	cmd, err := rt.Router.Traceroute(ctx, rt.Config, param)
 	if err != nil {
 		return nil, err
 	}
-	return SSHExec(rt.Config, cmd)
+	return SSHExec(ctx, rt.Config, "traceroute", cmd)
+}
+
+func (rt *RouterInstance) StreamPing(ctx context.Context, param *IPNet, w io.Writer) error {
+	cmd, err := rt.Router.Ping(ctx, rt.Config, param)
+	if err != nil {
+		return err
+	}
+	return SSHStream(ctx, rt.Config, "ping", cmd, w)
 }
 
-func (rt *RouterInstance) BGPRoute(param *IPNet) ([]string, error) {
-	cmd, err := rt.Router.BGPRoute(rt.Config, param)
+func (rt *RouterInstance) StreamTraceroute(ctx context.Context, param *IPNet, w io.Writer) error {
+	cmd, err := rt.Router.Traceroute(ctx, rt.Config, param)
+	if err != nil {
+		return err
+	}
+	return SSHStream(ctx, rt.Config, "traceroute", cmd, w)
+}
+
+func (rt *RouterInstance) BGPSummary(ctx context.Context) ([]string, error) {
+	cmd, err := rt.Router.BGPSummary(ctx, rt.Config)
 	if err != nil {
 		return nil, err
 	}
-	return SSHExec(rt.Config, cmd)
+	return SSHExec(ctx, rt.Config, "bgp.summary", cmd)
 }
 
-func (rt *RouterInstance) BGPCommunity(param string) ([]string, error) {
-	cmd, err := rt.Router.BGPCommunity(rt.Config, param)
+func (rt *RouterInstance) BGPRoute(ctx context.Context, param *IPNet) ([]string, error) {
+	cmd, err := rt.Router.BGPRoute(ctx, rt.Config, param)
 	if err != nil {
 		return nil, err
 	}
-	return SSHExec(rt.Config, cmd)
+	return SSHExec(ctx, rt.Config, "bgp.route", cmd)
 }
 
-func (rt *RouterInstance) BGPASPath(param string) ([]string, error) {
-	cmd, err := rt.Router.BGPASPath(rt.Config, param)
+func (rt *RouterInstance) BGPCommunity(ctx context.Context, param string) ([]string, error) {
+	cmd, err := rt.Router.BGPCommunity(ctx, rt.Config, param)
+	if err != nil {
+		return nil, err
+	}
+	return SSHExec(ctx, rt.Config, "bgp.community", cmd)
+}
+
+func (rt *RouterInstance) BGPASPath(ctx context.Context, param string) ([]string, error) {
+	cmd, err := rt.Router.BGPASPath(ctx, rt.Config, param)
+	if err != nil {
+		return nil, err
+	}
+	return SSHExec(ctx, rt.Config, "bgp.aspath", cmd)
+}
+
+// Execute runs the named operation with parameters validated by Operation.Validate.
+func (rt *RouterInstance) Execute(ctx context.Context, name string, values map[string]any) ([]string, error) {
+	if _, ok := rt.Router.Operations()[name]; !ok {
+		return nil, errs.OperationUnknown
+	}
+	cmd, err := rt.Router.Execute(ctx, rt.Config, name, values)
 	if err != nil {
 		return nil, err
 	}
-	return SSHExec(rt.Config, cmd)
+	return SSHExec(ctx, rt.Config, name, cmd)
 }
 
 type RouterMap []*RouterInstance
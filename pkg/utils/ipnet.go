@@ -14,6 +14,8 @@ const (
 	IPv6 IPFamily = "ipv6"
 )
 
+// IPNet is an IP address or prefix.
+// Its methods are safe to call on nil, which templates get for omitted optional parameters.
 type IPNet struct {
 	IP     string
 	CIDR   string
@@ -21,27 +23,39 @@ type IPNet struct {
 }
 
 func (ip *IPNet) IsIPv4() bool {
-	return ip.Family == IPv4
+	return ip != nil && ip.Family == IPv4
 }
 
 func (ip *IPNet) IsIPv6() bool {
-	return ip.Family == IPv6
+	return ip != nil && ip.Family == IPv6
 }
 
 func (ip *IPNet) FamilyString() string {
+	if ip == nil {
+		return ""
+	}
 	return string(ip.Family)
 }
 
 func (ip *IPNet) String() string {
+	if ip == nil {
+		return ""
+	}
 	return ip.IP + "/" + ip.CIDR
 }
 
 func (ip *IPNet) ToIPNet() *net.IPNet {
+	if ip == nil {
+		return nil
+	}
 	_, ipnet, _ := net.ParseCIDR(ip.String())
 	return ipnet
 }
 
 func (ip *IPNet) ToIP() net.IP {
+	if ip == nil {
+		return nil
+	}
 	return net.ParseIP(ip.IP)
 }
 
@@ -66,12 +80,6 @@ func NewIPNET(ip string) (*IPNet, error) {
 		}
 		ret.CIDR = strings.Split(ip, "/")[1]
 		ret.IP = strings.Split(ip, "/")[0]
-	} else {
-		if ret.Family == IPv4 {
-			ret.CIDR = "32"
-		} else {
-			ret.CIDR = "128"
-		}
 	}
 	if net.ParseIP(ret.IP) == nil {
 		return nil, errs.IPInvalid
@@ -81,9 +89,24 @@ func NewIPNET(ip string) (*IPNet, error) {
 	} else {
 		ret.Family = IPv4
 	}
+	if ret.CIDR == "" {
+		if ret.Family == IPv4 {
+			ret.CIDR = "32"
+		} else {
+			ret.CIDR = "128"
+		}
+	}
 	return ret, nil
 }
 
+// NewHostFromProtobuf parses the target of a probe, an address or hostname without prefix length.
+func NewHostFromProtobuf(target string) (*IPNet, error) {
+	if strings.Contains(target, "/") {
+		return nil, errs.IPInvalid
+	}
+	return NewIPNetFromProtobuf(target)
+}
+
 func NewIPNetFromProtobuf(target string) (*IPNet, error) {
 	if len(target) == 0 {
 		return nil, errs.IPInvalid
@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"context"
+	"sync"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/metrics"
+)
+
+const defaultQueueDepth = 16
+
+// Limiter bounds the number of concurrent operations on a router.
+// Operations beyond the limit wait in a queue that serves clients round-robin, each client's operations in order.
+type Limiter struct {
+	name    string
+	max     int
+	depth   int
+	mu      sync.Mutex
+	running int
+	waiting int
+	clients []string // clients holds the clients with waiting operations, the next to be served first.
+	queues  map[string][]*waiter
+}
+
+type waiter struct {
+	ready    chan struct{}
+	granted  bool
+	position chan int // position holds the latest queue position, older ones are replaced.
+	last     int
+}
+
+// NewLimiter returns a limiter for the router, it does not limit anything if max is not positive.
+func NewLimiter(name string, max int, depth int) *Limiter {
+	if depth <= 0 {
+		depth = defaultQueueDepth
+	}
+	return &Limiter{
+		name:   name,
+		max:    max,
+		depth:  depth,
+		queues: make(map[string][]*waiter),
+	}
+}
+
+// Acquire waits for a free slot and returns a function to release it.
+// While waiting, position is called with the 1-based queue position whenever it changes, it may be nil.
+// It returns errs.QueueFull if the queue is full or the context's error if it is done first.
+func (l *Limiter) Acquire(ctx context.Context, client string, position func(int)) (func(), error) {
+	if l == nil || l.max <= 0 {
+		return func() {}, nil
+	}
+	l.mu.Lock()
+	if l.running < l.max && l.waiting == 0 {
+		l.running++
+		l.mu.Unlock()
+		return l.release, nil
+	}
+	if l.waiting >= l.depth {
+		l.mu.Unlock()
+		return nil, errs.QueueFull
+	}
+	w := &waiter{ready: make(chan struct{}), position: make(chan int, 1)}
+	if len(l.queues[client]) == 0 {
+		l.clients = append(l.clients, client)
+	}
+	l.queues[client] = append(l.queues[client], w)
+	l.waiting++
+	l.update()
+	l.mu.Unlock()
+
+	for {
+		select {
+		case <-w.ready:
+			return l.release, nil
+		case p := <-w.position:
+			if position != nil {
+				position(p)
+			}
+		case <-ctx.Done():
+			l.mu.Lock()
+			if w.granted {
+				l.mu.Unlock()
+				l.release()
+			} else {
+				l.remove(client, w)
+				l.update()
+				l.mu.Unlock()
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// release frees a slot and hands it to the next waiting operation.
+func (l *Limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.running--
+	for l.running < l.max && l.waiting > 0 {
+		client := l.clients[0]
+		w := l.queues[client][0]
+		l.remove(client, w)
+		// Move on to the next client, this one is queued again behind all others
+		if len(l.queues[client]) > 0 {
+			l.clients = append(l.clients[1:], client)
+		}
+		w.granted = true
+		close(w.ready)
+		l.running++
+	}
+	l.update()
+}
+
+// remove drops the waiter from the queue of the client, the client keeps its place in the rotation if it has more waiters.
+// The caller must hold the lock.
+func (l *Limiter) remove(client string, w *waiter) {
+	q := l.queues[client]
+	for i := range q {
+		if q[i] == w {
+			q = append(q[:i], q[i+1:]...)
+			break
+		}
+	}
+	l.waiting--
+	if len(q) > 0 {
+		l.queues[client] = q
+		return
+	}
+	delete(l.queues, client)
+	for i, c := range l.clients {
+		if c == client {
+			l.clients = append(l.clients[:i], l.clients[i+1:]...)
+			break
+		}
+	}
+}
+
+// update publishes the queue position of every waiter whose position changed, the caller must hold the lock.
+// The n-th operation of a client is served in the n-th round, after the clients ahead of it in the rotation.
+func (l *Limiter) update() {
+	metrics.RouterQueued.WithLabelValues(l.name).Set(float64(l.waiting))
+	for k, client := range l.clients {
+		for i, w := range l.queues[client] {
+			pos := 1
+			for j, c := range l.clients {
+				n := len(l.queues[c])
+				pos += min(n, i)
+				if j < k && n > i {
+					pos++
+				}
+			}
+			if pos == w.last {
+				continue
+			}
+			w.last = pos
+			select {
+			case <-w.position:
+			default:
+			}
+			w.position <- pos
+		}
+	}
+}
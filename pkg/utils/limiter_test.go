@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+)
+
+type grant struct {
+	id      string
+	release func()
+}
+
+// enqueue starts an operation of the client that reports its id on granted once it runs.
+// It returns after the operation is queued.
+func enqueue(t *testing.T, l *Limiter, ctx context.Context, client string, id string, granted chan<- grant) <-chan error {
+	queued := make(chan struct{})
+	ret := make(chan error, 1)
+	go func() {
+		once := false
+		release, err := l.Acquire(ctx, client, func(int) {
+			if !once {
+				once = true
+				close(queued)
+			}
+		})
+		ret <- err
+		if err == nil {
+			granted <- grant{id, release}
+		}
+	}()
+	select {
+	case <-queued:
+	case <-time.After(time.Second):
+		t.Fatalf("%s was not queued", id)
+	}
+	return ret
+}
+
+func TestLimiterFairness(t *testing.T) {
+	l := NewLimiter("test", 1, 0)
+	release, err := l.Acquire(context.Background(), "a", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	granted := make(chan grant)
+	for _, op := range []struct{ client, id string }{{"a", "a1"}, {"a", "a2"}, {"a", "a3"}, {"b", "b1"}, {"c", "c1"}, {"b", "b2"}} {
+		enqueue(t, l, context.Background(), op.client, op.id, granted)
+	}
+
+	// The clients are served round-robin in the order they queued, each client's operations in order
+	want := []string{"a1", "b1", "c1", "a2", "b2", "a3"}
+	positions := map[string]int{"a1": 1, "a2": 4, "a3": 6, "b1": 2, "b2": 5, "c1": 3}
+	l.mu.Lock()
+	for client, q := range l.queues {
+		for i, w := range q {
+			id := client + string(rune('1'+i))
+			if w.last != positions[id] {
+				t.Errorf("%s: position = %d, want %d", id, w.last, positions[id])
+			}
+		}
+	}
+	l.mu.Unlock()
+
+	for _, id := range want {
+		release()
+		select {
+		case g := <-granted:
+			if g.id != id {
+				t.Errorf("granted %s, want %s", g.id, id)
+			}
+			release = g.release
+		case <-time.After(time.Second):
+			t.Fatalf("%s was not granted", id)
+		}
+	}
+	release()
+	if l.running != 0 || l.waiting != 0 {
+		t.Errorf("%d running and %d waiting after all releases, want none", l.running, l.waiting)
+	}
+}
+
+func TestLimiterConcurrency(t *testing.T) {
+	l := NewLimiter("test", 2, 0)
+	var releases []func()
+	for i := 0; i < 2; i++ {
+		release, err := l.Acquire(context.Background(), "a", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		releases = append(releases, release)
+	}
+	granted := make(chan grant, 1)
+	enqueue(t, l, context.Background(), "b", "b1", granted)
+	select {
+	case <-granted:
+		t.Fatal("third operation ran with a limit of 2")
+	case <-time.After(10 * time.Millisecond):
+	}
+	releases[0]()
+	select {
+	case g := <-granted:
+		g.release()
+	case <-time.After(time.Second):
+		t.Fatal("queued operation was not granted after a release")
+	}
+	releases[1]()
+}
+
+func TestLimiterQueueFull(t *testing.T) {
+	l := NewLimiter("test", 1, 1)
+	release, err := l.Acquire(context.Background(), "a", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	enqueue(t, l, ctx, "a", "a1", make(chan grant, 1))
+	if _, err := l.Acquire(context.Background(), "b", nil); !errors.Is(err, errs.QueueFull) {
+		t.Errorf("Acquire with a full queue = %v, want %v", err, errs.QueueFull)
+	}
+}
+
+func TestLimiterCancel(t *testing.T) {
+	l := NewLimiter("test", 1, 0)
+	release, err := l.Acquire(context.Background(), "a", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	granted := make(chan grant, 2)
+	ret := enqueue(t, l, ctx, "a", "a1", granted)
+	enqueue(t, l, context.Background(), "b", "b1", granted)
+	cancel()
+	if err := <-ret; !errors.Is(err, context.Canceled) {
+		t.Errorf("Acquire after cancel = %v, want %v", err, context.Canceled)
+	}
+
+	// The cancelled operation gives up its place in the queue
+	release()
+	select {
+	case g := <-granted:
+		if g.id != "b1" {
+			t.Errorf("granted %s, want b1", g.id)
+		}
+		g.release()
+	case <-time.After(time.Second):
+		t.Fatal("b1 was not granted")
+	}
+	if l.running != 0 || l.waiting != 0 {
+		t.Errorf("%d running and %d waiting after all releases, want none", l.running, l.waiting)
+	}
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+	for _, l := range []*Limiter{nil, NewLimiter("test", 0, 0)} {
+		for i := 0; i < 100; i++ {
+			if _, err := l.Acquire(context.Background(), "a", nil); err != nil {
+				t.Fatalf("Acquire without a limit = %v", err)
+			}
+		}
+	}
+}
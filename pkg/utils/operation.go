@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+)
+
+type ParamType string
+
+const (
+	ParamIP        ParamType = "ip"        // An IP address or hostname, passed as *IPNet.
+	ParamPrefix    ParamType = "prefix"    // An IP address or prefix, passed as *IPNet.
+	ParamASN       ParamType = "asn"       // An AS number with optional AS prefix, passed as uint32.
+	ParamCommunity ParamType = "community" // A standard or large BGP community, passed as string.
+	ParamASPath    ParamType = "aspath"    // An AS path regex, see SanitizeASPathRegex, passed as string.
+	ParamEnum      ParamType = "enum"      // One of Values, passed as string.
+	ParamInt       ParamType = "int"       // An integer between Min and Max, passed as int64.
+)
+
+// OperationParam describes a single parameter of an operation.
+type OperationParam struct {
+	Name        string    `yaml:"name"`        // Name of the parameter, used as key in templates.
+	Type        ParamType `yaml:"type"`        // Type of the parameter, the value is validated against it.
+	Description string    `yaml:"description"` // Description shown to users.
+	Optional    bool      `yaml:"optional"`    // Optional parameters may be omitted and are the zero value of their type in templates, see Zero.
+	Default     string    `yaml:"default"`     // Default is used if the parameter is omitted.
+	Values      []string  `yaml:"values"`      // Values lists the allowed values of an enum.
+	Min         *int64    `yaml:"min"`         // Min is the lower bound of an int, inclusive.
+	Max         *int64    `yaml:"max"`         // Max is the upper bound of an int, inclusive.
+}
+
+// Operation is a command that can be executed on a router with typed parameters.
+type Operation struct {
+	Description string           `yaml:"description"` // Description shown to users.
+	Params      []OperationParam `yaml:"params"`      // Params accepted by the operation.
+	Commands    []string         `yaml:"commands"`    // Commands are templates executed with .Cfg and .Params.
+	Builtin     bool             `yaml:"-"`           // Builtin operations also have a dedicated RPC.
+}
+
+// Check returns an error if the operation definition is not usable.
+func (op *Operation) Check() error {
+	seen := make(map[string]bool)
+	for _, p := range op.Params {
+		if p.Name == "" {
+			return fmt.Errorf("%w: parameter without name", errs.OperationInvalid)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("%w: duplicate parameter %s", errs.OperationInvalid, p.Name)
+		}
+		seen[p.Name] = true
+		switch p.Type {
+		case ParamIP, ParamPrefix, ParamASN, ParamCommunity, ParamASPath:
+		case ParamEnum:
+			if len(p.Values) == 0 {
+				return fmt.Errorf("%w: enum %s without values", errs.OperationInvalid, p.Name)
+			}
+		case ParamInt:
+			if p.Min != nil && p.Max != nil && *p.Min > *p.Max {
+				return fmt.Errorf("%w: int %s with min > max", errs.OperationInvalid, p.Name)
+			}
+		default:
+			return fmt.Errorf("%w: parameter %s has unknown type %q", errs.OperationInvalid, p.Name, p.Type)
+		}
+		if p.Default != "" {
+			if _, err := p.Parse(p.Default); err != nil {
+				return fmt.Errorf("%w: default of %s: %s", errs.OperationInvalid, p.Name, err)
+			}
+		}
+	}
+	if !op.Builtin && len(op.Commands) == 0 {
+		return fmt.Errorf("%w: no commands", errs.OperationInvalid)
+	}
+	return nil
+}
+
+// Validate checks the given parameters against the operation and returns their typed values.
+// Omitted parameters take their default, unknown or missing ones are rejected.
+func (op *Operation) Validate(params map[string]string) (map[string]any, error) {
+	ret := make(map[string]any, len(op.Params))
+	for k := range params {
+		if !slices.ContainsFunc(op.Params, func(p OperationParam) bool { return p.Name == k }) {
+			return nil, fmt.Errorf("%w: %s", errs.ParamUnknown, k)
+		}
+	}
+	for _, p := range op.Params {
+		v, ok := params[p.Name]
+		if !ok || v == "" {
+			v = p.Default
+		}
+		if v == "" {
+			if p.Optional {
+				continue
+			}
+			return nil, fmt.Errorf("%w: %s", errs.ParamMissing, p.Name)
+		}
+		val, err := p.Parse(v)
+		if errors.Is(err, errs.ParamInvalid) {
+			return nil, fmt.Errorf("%w: %s", err, p.Name)
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %s: %s", errs.ParamInvalid, p.Name, err)
+		}
+		ret[p.Name] = val
+	}
+	return ret, nil
+}
+
+// Parse converts the value to the type of the parameter.
+func (p *OperationParam) Parse(v string) (any, error) {
+	v = strings.TrimSpace(v)
+	switch p.Type {
+	case ParamIP:
+		return NewHostFromProtobuf(v)
+	case ParamPrefix:
+		return NewIPNET(v)
+	case ParamASN:
+		asn, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(v), "AS"), 10, 32)
+		if err != nil {
+			return nil, errs.ParamInvalid
+		}
+		return uint32(asn), nil
+	case ParamCommunity:
+		return parseCommunity(v)
+	case ParamASPath:
+		return SanitizeASPathRegex(v)
+	case ParamEnum:
+		if !slices.Contains(p.Values, v) {
+			return nil, errs.ParamInvalid
+		}
+		return v, nil
+	case ParamInt:
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, errs.ParamInvalid
+		}
+		if (p.Min != nil && i < *p.Min) || (p.Max != nil && i > *p.Max) {
+			return nil, errs.ParamInvalid
+		}
+		return i, nil
+	}
+	return nil, errs.ParamInvalid
+}
+
+// Zero returns the value templates get for the parameter if it was omitted.
+// It has the type of the parameter so that its methods can be used, and is false in {{if}}.
+func (p *OperationParam) Zero() any {
+	switch p.Type {
+	case ParamIP, ParamPrefix:
+		return (*IPNet)(nil)
+	case ParamASN:
+		return uint32(0)
+	case ParamInt:
+		return int64(0)
+	}
+	return ""
+}
+
+// parseCommunity accepts a standard (asn:value) or large (asn:value:value) community.
+func parseCommunity(v string) (string, error) {
+	parts := strings.Split(v, ":")
+	bits := 16
+	if len(parts) == 3 {
+		bits = 32
+	} else if len(parts) != 2 {
+		return "", errs.ParamInvalid
+	}
+	for i, s := range parts {
+		n, err := strconv.ParseUint(s, 10, bits)
+		if err != nil {
+			return "", errs.ParamInvalid
+		}
+		parts[i] = strconv.FormatUint(n, 10)
+	}
+	return strings.Join(parts, ":"), nil
+}
@@ -0,0 +1,43 @@
+package utils
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Parser selects how the output of an operation is decoded.
+type Parser struct {
+	Format   string   `yaml:"format"`   // Format of the output, FormatText if empty.
+	Decoder  string   `yaml:"decoder"`  // Decoder is the name of a registered parser, the output is not parsed if empty.
+	Commands []string `yaml:"commands"` // Commands are run after those of the operation, only their output is parsed and it is not part of the result.
+}
+
+// Split splits the output of an operation into the result shown to clients and the output to parse.
+// Without commands of its own the parser parses the result.
+func (p Parser) Split(out []string) (result []string, parse []string) {
+	n := len(p.Commands)
+	if p.Decoder == "" || n == 0 || n > len(out) {
+		return out, out
+	}
+	return out[:len(out)-n], out[len(out)-n:]
+}
+
+// UnmarshalYAML accepts either a mapping or a plain decoder name for text output.
+func (p *Parser) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		p.Format = FormatText
+		p.Decoder = name
+		return nil
+	}
+	type raw Parser
+	var tmp raw
+	if err := unmarshal(&tmp); err != nil {
+		return err
+	}
+	*p = Parser(tmp)
+	if p.Format == "" {
+		p.Format = FormatText
+	}
+	return nil
+}
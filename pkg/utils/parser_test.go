@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParserSplit(t *testing.T) {
+	out := []string{"text", "json"}
+	tests := []struct {
+		name   string
+		parser Parser
+		result []string
+		parse  []string
+	}{
+		{"no commands", Parser{Decoder: "frrouting"}, out, out},
+		{"commands", Parser{Decoder: "frrouting", Commands: []string{"show json"}}, []string{"text"}, []string{"json"}},
+		{"no decoder", Parser{Commands: []string{"show json"}}, out, out},
+		{"more commands than output", Parser{Decoder: "frrouting", Commands: []string{"a", "b", "c"}}, out, out},
+	}
+	for _, tt := range tests {
+		result, parse := tt.parser.Split(out)
+		if !slices.Equal(result, tt.result) || !slices.Equal(parse, tt.parse) {
+			t.Errorf("%s: Split = %q, %q, want %q, %q", tt.name, result, parse, tt.result, tt.parse)
+		}
+	}
+}
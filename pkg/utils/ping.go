@@ -0,0 +1,23 @@
+package utils
+
+import "time"
+
+type PingStats struct {
+	Sent     uint32
+	Received uint32
+	Loss     float64 // Loss is the packet loss in percent.
+	Min      time.Duration
+	Avg      time.Duration
+	Max      time.Duration
+	Mdev     time.Duration
+	Probes   []*PingProbe
+}
+
+type PingProbe struct {
+	Sequence uint32
+	Success  bool
+	RTT      time.Duration
+	TTL      uint32
+	Source   string
+	Message  string // Message holds the reason of a failed probe if the router reports one.
+}
@@ -0,0 +1,231 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+)
+
+// _bogons are the default denied destinations, unallocated, private and reserved address space.
+var _bogons = parseNets(
+	// IPv4
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	// IPv6
+	"::/8",
+	"100::/64",
+	"2001:2::/48",
+	"2001:10::/28",
+	"2001:db8::/32",
+	"2002::/16",
+	"3ffe::/16",
+	"3fff::/20",
+	"fc00::/7",
+	"fe80::/10",
+	"fec0::/10",
+	"ff00::/8",
+)
+
+// Default minimum prefix lengths, shorter route lookups are denied unless configured otherwise.
+const (
+	defaultMinPrefixLength4 = 8
+	defaultMinPrefixLength6 = 16
+)
+
+// Policy decides which targets operations may be run against.
+// Rules are evaluated from the most specific scope to the least specific one:
+// the operation of the router, the router, the operation globally and globally.
+type Policy struct {
+	scopes []*policyScope
+}
+
+type policyScope struct {
+	op     string // op is the operation the rules apply to, all operations if empty.
+	bogons *bool
+	deny   []*net.IPNet
+	allow  []*net.IPNet
+	min4   int
+	min6   int
+}
+
+// NewPolicy combines the global policy and the policy of a router.
+func NewPolicy(global PolicyConfig, router PolicyConfig) (*Policy, error) {
+	p := &Policy{}
+	for _, cfg := range []PolicyConfig{router, global} {
+		for op, rules := range cfg.Operations {
+			if err := p.add(op, rules); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.add("", cfg.PolicyRules); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *Policy) add(op string, rules PolicyRules) error {
+	sc := &policyScope{
+		op:     op,
+		bogons: rules.Bogons,
+		min4:   rules.MinPrefixLength.IPv4,
+		min6:   rules.MinPrefixLength.IPv6,
+	}
+	for _, v := range rules.Deny {
+		n, err := ParseNet(v)
+		if err != nil {
+			return fmt.Errorf("deny %q: %w", v, err)
+		}
+		sc.deny = append(sc.deny, n)
+	}
+	for _, v := range rules.Allow {
+		n, err := ParseNet(v)
+		if err != nil {
+			return fmt.Errorf("allow %q: %w", v, err)
+		}
+		sc.allow = append(sc.allow, n)
+	}
+	p.scopes = append(p.scopes, sc)
+	return nil
+}
+
+// Check returns errs.TargetDenied if the operation may not be run against the target.
+// Hostnames must be checked by the address they resolved to.
+// Deny and bogon prefixes match if they contain the target address, allow prefixes only if they contain the whole target prefix.
+// Within a scope the longest matching allow or deny prefix wins, the first scope with a match decides.
+// Without a match, bogons are denied unless disabled, and prefixes shorter than the minimum length are denied.
+func (p *Policy) Check(op string, target *IPNet) error {
+	if p == nil {
+		return nil
+	}
+	ip := target.ToIP()
+	if ip == nil {
+		return errs.IPInvalid
+	}
+	length, err := strconv.Atoi(target.CIDR)
+	if err != nil {
+		return errs.NetInvalid
+	}
+	maxLength := 32
+	if target.IsIPv6() {
+		maxLength = 128
+	}
+	allowed := false
+	for _, sc := range p.scopes {
+		if sc.op != "" && sc.op != op {
+			continue
+		}
+		deny := longestMatch(sc.deny, ip, maxLength)
+		allow := longestMatch(sc.allow, ip, length)
+		if deny == nil && allow == nil {
+			continue
+		}
+		if deny != nil && (allow == nil || ones(deny) > ones(allow)) {
+			return fmt.Errorf("%w: %s is in %s", errs.TargetDenied, target.IP, deny)
+		}
+		allowed = true
+		break
+	}
+	if !allowed && p.bogons(op) {
+		if n := longestMatch(_bogons, ip, maxLength); n != nil {
+			return fmt.Errorf("%w: %s is in reserved or private range %s", errs.TargetDenied, target.IP, n)
+		}
+	}
+	if min := p.minLength(op, target.IsIPv6()); length < min {
+		return fmt.Errorf("%w: prefix length /%d is shorter than /%d", errs.TargetDenied, length, min)
+	}
+	return nil
+}
+
+func (p *Policy) bogons(op string) bool {
+	for _, sc := range p.scopes {
+		if (sc.op == "" || sc.op == op) && sc.bogons != nil {
+			return *sc.bogons
+		}
+	}
+	return true
+}
+
+func (p *Policy) minLength(op string, ipv6 bool) int {
+	for _, sc := range p.scopes {
+		if sc.op != "" && sc.op != op {
+			continue
+		}
+		if ipv6 && sc.min6 > 0 {
+			return sc.min6
+		}
+		if !ipv6 && sc.min4 > 0 {
+			return sc.min4
+		}
+	}
+	if ipv6 {
+		return defaultMinPrefixLength6
+	}
+	return defaultMinPrefixLength4
+}
+
+// longestMatch returns the most specific network that contains the address and is not longer than length.
+func longestMatch(nets []*net.IPNet, ip net.IP, length int) *net.IPNet {
+	var best *net.IPNet
+	for _, n := range nets {
+		if !n.Contains(ip) || ones(n) > length {
+			continue
+		}
+		if best == nil || ones(n) > ones(best) {
+			best = n
+		}
+	}
+	return best
+}
+
+func ones(n *net.IPNet) int {
+	o, _ := n.Mask.Size()
+	return o
+}
+
+// ParseNet parses a network in CIDR notation or a single address.
+func ParseNet(v string) (*net.IPNet, error) {
+	v = strings.TrimSpace(v)
+	if !strings.Contains(v, "/") {
+		ip := net.ParseIP(v)
+		if ip == nil {
+			return nil, errs.IPInvalid
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+	_, n, err := net.ParseCIDR(v)
+	if err != nil {
+		return nil, errs.NetInvalid
+	}
+	return n, nil
+}
+
+func parseNets(v ...string) []*net.IPNet {
+	ret := make([]*net.IPNet, 0, len(v))
+	for _, s := range v {
+		n, err := ParseNet(s)
+		if err != nil {
+			panic(err)
+		}
+		ret = append(ret, n)
+	}
+	return ret
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	no := false
+	global := PolicyConfig{
+		PolicyRules: PolicyRules{
+			Deny:  []string{"8.8.4.0/24", "10.10.5.0/24", "2001:db9::/32"},
+			Allow: []string{"10.10.0.0/16"},
+		},
+		Operations: map[string]PolicyRules{
+			"bgp.route": {Bogons: &no},
+		},
+	}
+	router := PolicyConfig{
+		Operations: map[string]PolicyRules{
+			"bgp.community": {MinPrefixLength: PrefixLength{IPv4: 16}},
+		},
+	}
+	p, err := NewPolicy(global, router)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		op     string
+		target string
+		denied bool
+	}{
+		{"ping", "8.8.8.8", false},
+		{"ping", "2001:4860:4860::8888", false},
+		// bogons
+		{"ping", "10.0.0.1", true},
+		{"ping", "127.0.0.1", true},
+		{"ping", "fe80::1", true},
+		{"ping", "::1", true},
+		{"ping", "::ffff:127.0.0.1", true},
+		// bogons and denied prefixes match the address of CIDR targets of any length
+		{"ping", "10.0.0.1/0", true},
+		{"ping", "127.0.0.1/7", true},
+		{"ping", "fe80::1/0", true},
+		{"ping", "8.8.4.4/0", true},
+		{"traceroute", "2001:db9::1/16", true},
+		// denied prefixes
+		{"ping", "8.8.4.4", true},
+		{"ping", "8.8.5.5", false},
+		{"bgp.route", "8.8.0.0/16", false},
+		// allow overrides bogons, but only if it contains the whole target
+		{"ping", "10.10.1.1", false},
+		{"bgp.route", "10.10.0.0/16", false},
+		{"ping", "10.11.0.1", true},
+		{"traceroute", "10.10.1.1/8", true},
+		// the longest matching prefix wins
+		{"ping", "10.10.5.1", true},
+		// bogons disabled for the operation
+		{"bgp.route", "10.0.0.0/8", false},
+		{"bgp.route", "fd00::/16", false},
+		// minimum prefix length, /8 and /16 by default
+		{"bgp.route", "0.0.0.0/0", true},
+		{"bgp.route", "8.0.0.0/0", true},
+		{"bgp.route", "8.0.0.0/7", true},
+		{"bgp.route", "8.0.0.0/8", false},
+		{"bgp.route", "2000::/3", true},
+		{"bgp.route", "2001::/16", false},
+		{"bgp.community", "8.0.0.0/8", true},
+		{"bgp.community", "8.8.0.0/16", false},
+	}
+	for _, tt := range tests {
+		target, err := NewIPNET(tt.target)
+		if err != nil {
+			t.Fatalf("NewIPNET(%q): %s", tt.target, err)
+		}
+		err = p.Check(tt.op, target)
+		if denied := errors.Is(err, errs.TargetDenied); denied != tt.denied {
+			t.Errorf("Check(%q, %q) = %v, want denied %t", tt.op, tt.target, err, tt.denied)
+		}
+		if err != nil && !errors.Is(err, errs.TargetDenied) {
+			t.Errorf("Check(%q, %q) = %v, want %v", tt.op, tt.target, err, errs.TargetDenied)
+		}
+	}
+}
+
+func TestNilPolicy(t *testing.T) {
+	var p *Policy
+	target, _ := NewIPNET("127.0.0.1")
+	if err := p.Check("ping", target); err != nil {
+		t.Errorf("Check on nil policy = %v, want nil", err)
+	}
+}
+
+func TestNewHostFromProtobuf(t *testing.T) {
+	tests := []struct {
+		target string
+		err    error
+	}{
+		{"192.0.2.1", nil},
+		{"2001:db8::1", nil},
+		{"192.0.2.1/32", errs.IPInvalid},
+		{"10.0.0.1/0", errs.IPInvalid},
+		{"fe80::1/0", errs.IPInvalid},
+		{"", errs.IPInvalid},
+	}
+	for _, tt := range tests {
+		if _, err := NewHostFromProtobuf(tt.target); !errors.Is(err, tt.err) {
+			t.Errorf("NewHostFromProtobuf(%q) = %v, want %v", tt.target, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"log"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/redis/go-redis/v9"
+)
+
+// NewRedisClient connects to a single server by URI or to the configured addresses, using Sentinel if a master name is set.
+// Several addresses without master name or cluster are rejected, they do not tell which client to use.
+func NewRedisClient(cfg RedisConfig) (redis.UniversalClient, error) {
+	if len(cfg.Addrs) == 0 {
+		if cfg.Cluster {
+			opts, err := redis.ParseClusterURL(cfg.URI)
+			if err != nil {
+				return nil, err
+			}
+			log.Println("NOTICE: Connecting to Redis Cluster at", cfg.URI)
+			return redis.NewClusterClient(opts), nil
+		}
+		opts, err := redis.ParseURL(cfg.URI)
+		if err != nil {
+			return nil, err
+		}
+		log.Println("NOTICE: Connecting to Redis at", cfg.URI)
+		return redis.NewClient(opts), nil
+	}
+	opts := &redis.UniversalOptions{
+		Addrs:      cfg.Addrs,
+		MasterName: cfg.MasterName,
+		Username:   cfg.Username,
+		Password:   cfg.Password,
+		DB:         cfg.DB,
+	}
+	if cfg.Cluster {
+		log.Println("NOTICE: Connecting to Redis Cluster at", cfg.Addrs)
+		return redis.NewClusterClient(opts.Cluster()), nil
+	}
+	if cfg.MasterName != "" {
+		log.Printf("NOTICE: Connecting to Redis master %s via Sentinels %v", cfg.MasterName, cfg.Addrs)
+		return redis.NewFailoverClient(opts.Failover()), nil
+	}
+	if len(cfg.Addrs) > 1 {
+		return nil, errs.RedisAddrsAmbiguous
+	}
+	log.Println("NOTICE: Connecting to Redis at", cfg.Addrs[0])
+	return redis.NewClient(opts.Simple()), nil
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestNewRedisClient(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  RedisConfig
+		want string
+		err  error
+	}{
+		{"URI", RedisConfig{URI: "redis://localhost:6379/0"}, "client", nil},
+		{"cluster URI", RedisConfig{URI: "redis://localhost:6379", Cluster: true}, "cluster", nil},
+		{"single address", RedisConfig{Addrs: []string{"localhost:6379"}}, "client", nil},
+		{"Sentinels", RedisConfig{Addrs: []string{"sentinel-1:26379", "sentinel-2:26379"}, MasterName: "mymaster"}, "client", nil},
+		{"cluster nodes", RedisConfig{Addrs: []string{"node-1:6379", "node-2:6379"}, Cluster: true}, "cluster", nil},
+		{"several addresses", RedisConfig{Addrs: []string{"node-1:6379", "node-2:6379"}}, "", errs.RedisAddrsAmbiguous},
+		{"invalid URI", RedisConfig{URI: "http://localhost"}, "", nil},
+	}
+	for _, tt := range tests {
+		c, err := NewRedisClient(tt.cfg)
+		if tt.want == "" {
+			if err == nil || tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("%s: NewRedisClient = %T, %v, want error %v", tt.name, c, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: NewRedisClient: %s", tt.name, err)
+			continue
+		}
+		defer c.Close()
+		var got string
+		switch c.(type) {
+		case *redis.Client:
+			got = "client"
+		case *redis.ClusterClient:
+			got = "cluster"
+		}
+		if got != tt.want {
+			t.Errorf("%s: NewRedisClient = %T, want %s", tt.name, c, tt.want)
+		}
+	}
+}
@@ -1,13 +1,48 @@
 package utils
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/AS203038/looking-glass/pkg/errs"
+	"github.com/AS203038/looking-glass/pkg/metrics"
 	"golang.org/x/crypto/ssh"
 )
 
-func SSHExec(router *RouterConfig, cmd []string) ([]string, error) {
+const (
+	defaultSSHKeepalive   = 30 * time.Second
+	defaultSSHIdleTimeout = 5 * time.Minute
+	defaultSSHMaxSessions = 4
+)
+
+// sshConn is a pooled SSH client which multiplexes sessions.
+type sshConn struct {
+	client   *ssh.Client
+	sessions int
+	lastUsed time.Time
+	closed   bool
+}
+
+// sshPool holds long-lived SSH clients per router.
+type sshPool struct {
+	mu      sync.Mutex
+	conns   map[*RouterConfig][]*sshConn
+	dialing map[*RouterConfig]*sync.Mutex
+}
+
+var _sshPool = &sshPool{
+	conns:   make(map[*RouterConfig][]*sshConn),
+	dialing: make(map[*RouterConfig]*sync.Mutex),
+}
+
+func sshClientConfig(router *RouterConfig) (*ssh.ClientConfig, error) {
 	auths := []ssh.AuthMethod{ssh.Password(router.Password)}
 	if router.SSHKey != "" {
 		k, err := os.ReadFile(router.SSHKey)
@@ -20,28 +55,329 @@ func SSHExec(router *RouterConfig, cmd []string) ([]string, error) {
 		}
 		auths = append(auths, ssh.PublicKeys(key))
 	}
-	config := &ssh.ClientConfig{
+	hostKeyCallback, err := sshHostKeyCallback(router)
+	if err != nil {
+		return nil, err
+	}
+	return &ssh.ClientConfig{
 		User:            router.Username,
 		Auth:            auths,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: hostKeyCallback,
+	}, nil
+}
+
+// maxSessions returns the maximum concurrent sessions per SSH client, zero or less means unlimited.
+// Many platforms refuse sessions beyond a small limit, so it defaults to a conservative value.
+func (rc *RouterConfig) maxSessions() int {
+	if rc.MaxSessions == 0 {
+		return defaultSSHMaxSessions
 	}
-	client, err := ssh.Dial("tcp", router.Hostname, config)
+	return rc.MaxSessions
+}
+
+// acquire reserves a session slot on a pooled client, the caller must hold p.mu.
+func (p *sshPool) acquire(router *RouterConfig) *sshConn {
+	limit := router.maxSessions()
+	for _, c := range p.conns[router] {
+		if !c.closed && (limit <= 0 || c.sessions < limit) {
+			c.sessions++
+			c.lastUsed = time.Now()
+			return c
+		}
+	}
+	return nil
+}
+
+// sshDial connects to the router, the timeout bounds both the TCP connect and the SSH handshake.
+func sshDial(ctx context.Context, router *RouterConfig, timeout time.Duration) (*ssh.Client, error) {
+	config, err := sshClientConfig(router)
 	if err != nil {
+		return nil, err
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", router.Hostname)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return nil, errs.ConnectionFailed
 	}
-	defer client.Close()
-	ret := make([]string, len(cmd))
-	for i, c := range cmd {
-		session, err := client.NewSession()
-		if err != nil {
-			return nil, errs.ExecFailed
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	// Abort the handshake if the request goes away
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	sc, chans, reqs, err := ssh.NewClientConn(conn, router.Hostname, config)
+	stopped := stop()
+	if err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		for _, e := range []error{errs.HostKeyMismatch, errs.HostKeyUnknown, errs.HostKeyInvalid} {
+			if errors.Is(err, e) {
+				return nil, e
+			}
+		}
+		return nil, errs.ConnectionFailed
+	}
+	if !stopped {
+		sc.Close()
+		return nil, ctx.Err()
+	}
+	conn.SetDeadline(time.Time{})
+	return ssh.NewClient(sc, chans, reqs), nil
+}
+
+// get returns a client with a free session slot, dialing a new one if required.
+// Dials are serialized per router so that concurrent requests share a fresh client instead of each dialing their own.
+func (p *sshPool) get(ctx context.Context, router *RouterConfig, timeout time.Duration) (*sshConn, error) {
+	p.mu.Lock()
+	if c := p.acquire(router); c != nil {
+		p.mu.Unlock()
+		return c, nil
+	}
+	dm, ok := p.dialing[router]
+	if !ok {
+		dm = &sync.Mutex{}
+		p.dialing[router] = dm
+	}
+	p.mu.Unlock()
+
+	dm.Lock()
+	defer dm.Unlock()
+	p.mu.Lock()
+	c := p.acquire(router)
+	p.mu.Unlock()
+	if c != nil {
+		return c, nil
+	}
+
+	start := time.Now()
+	client, err := sshDial(ctx, router, timeout)
+	if err != nil {
+		return nil, err
+	}
+	metrics.SSHDialDuration.WithLabelValues(router.Name).Observe(time.Since(start).Seconds())
+	c = &sshConn{client: client, sessions: 1, lastUsed: time.Now()}
+	p.mu.Lock()
+	p.conns[router] = append(p.conns[router], c)
+	p.mu.Unlock()
+	go func() {
+		// Evict the client as soon as the remote end goes away
+		client.Wait()
+		p.drop(router, c)
+	}()
+	go p.keepalive(router, c)
+	return c, nil
+}
+
+// put releases a session slot of the client.
+func (p *sshPool) put(c *sshConn) {
+	p.mu.Lock()
+	c.sessions--
+	c.lastUsed = time.Now()
+	p.mu.Unlock()
+}
+
+// drop closes the client and removes it from the pool, it is safe to call more than once.
+func (p *sshPool) drop(router *RouterConfig, c *sshConn) {
+	p.mu.Lock()
+	if c.closed {
+		p.mu.Unlock()
+		return
+	}
+	c.closed = true
+	conns := p.conns[router]
+	for i, v := range conns {
+		if v == c {
+			p.conns[router] = append(conns[:i], conns[i+1:]...)
+			break
 		}
-		output, err := session.Output(c)
+	}
+	p.mu.Unlock()
+	c.client.Close()
+}
+
+// alive sends a keepalive request and waits at most the given timeout for the reply.
+func (c *sshConn) alive(timeout time.Duration) bool {
+	res := make(chan error, 1)
+	go func() {
+		_, _, err := c.client.SendRequest("keepalive@openssh.com", true, nil)
+		res <- err
+	}()
+	select {
+	case err := <-res:
+		return err == nil
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// keepalive probes the client periodically and evicts it once it failed or has been idle for too long.
+func (p *sshPool) keepalive(router *RouterConfig, c *sshConn) {
+	interval := ParseDuration(router.Keepalive, defaultSSHKeepalive)
+	idle := ParseDuration(router.IdleTimeout, defaultSSHIdleTimeout)
+	ticker := time.NewTicker(min(interval, idle))
+	defer ticker.Stop()
+	for range ticker.C {
+		p.mu.Lock()
+		closed := c.closed
+		expired := c.sessions == 0 && time.Since(c.lastUsed) >= idle
+		p.mu.Unlock()
+		if closed {
+			return
+		}
+		if expired {
+			p.drop(router, c)
+			return
+		}
+		if !c.alive(interval) {
+			log.Printf("WARNING: SSH keepalive to %s failed, closing connection", router.Name)
+			p.drop(router, c)
+			return
+		}
+	}
+}
+
+// run executes a single command in its own session and writes its output to w.
+// The command is interrupted and its session closed once the context is done or the timeout expired.
+// It returns errs.SessionRefused if the router refused to open the session and errs.ConnectionFailed if the client is stale.
+func (c *sshConn) run(ctx context.Context, cmd string, timeout time.Duration, w io.Writer) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	session, err := c.client.NewSession()
+	if err != nil {
+		var cerr *ssh.OpenChannelError
+		if errors.As(err, &cerr) {
+			return errs.SessionRefused
+		}
+		return errs.ConnectionFailed
+	}
+	defer session.Close()
+	session.Stdout = w
+	if err := session.Start(cmd); err != nil {
+		return errs.ExecFailed
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Wait()
+	}()
+	select {
+	case err := <-done:
 		if err != nil {
-			return nil, errs.ExecFailed
+			return errs.ExecFailed
 		}
-		ret[i] = string(output)
+		return nil
+	case <-ctx.Done():
+		// Not every platform implements signals, closing the session ends the command regardless
+		session.Signal(ssh.SIGINT)
 		session.Close()
+		// Wait for the output copy to finish so nothing is written to w after returning
+		<-done
+		return ctx.Err()
+	}
+}
+
+// countingWriter counts the bytes written to the underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += n
+	return n, err
+}
+
+// exec runs each command in its own session on the client and calls out with the writer for the n-th command.
+// retry reports whether the operation may be retried, which is only the case if the first command failed without any output.
+func (c *sshConn) exec(ctx context.Context, cmd []string, timeout time.Duration, out func(int) io.Writer) (retry bool, err error) {
+	if len(cmd) == 0 {
+		if !c.alive(min(defaultSSHKeepalive, max(timeout, time.Second))) {
+			return true, errs.ConnectionFailed
+		}
+		return false, nil
+	}
+	for i, cm := range cmd {
+		w := &countingWriter{w: out(i)}
+		if err := c.run(ctx, cm, timeout, w); err != nil {
+			return i == 0 && w.n == 0, err
+		}
+	}
+	return false, nil
+}
+
+// sshExec runs the commands of the operation on the router using a pooled SSH client.
+// The timeouts of the operation are applied on top of the context's deadline.
+// A stale client is dropped, the commands are retried once on a fresh connection if the first one failed before any output was written.
+// A refused session leaves the client and its other sessions alone.
+func sshExec(ctx context.Context, router *RouterConfig, op string, cmd []string, out func(int) io.Writer) (err error) {
+	inflight := metrics.SSHInFlight.WithLabelValues(router.Name, op)
+	inflight.Inc()
+	start := time.Now()
+	defer func() {
+		inflight.Dec()
+		metrics.SSHExecDuration.WithLabelValues(router.Name, op).Observe(time.Since(start).Seconds())
+		if err != nil {
+			metrics.SSHErrors.WithLabelValues(router.Name, op, metrics.ErrorLabel(err)).Inc()
+		}
+	}()
+	t := router.Timeouts(op)
+	if t.Total > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Total)
+		defer cancel()
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		var c *sshConn
+		c, err = _sshPool.get(ctx, router, t.Dial)
+		if err != nil {
+			return err
+		}
+		var retry bool
+		retry, err = c.exec(ctx, cmd, t.Exec, out)
+		_sshPool.put(c)
+		if err != errs.ConnectionFailed {
+			return err
+		}
+		_sshPool.drop(router, c)
+		if !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// SSHExec runs the commands of the operation on the router and returns the output of every command.
+// Running it without commands checks that the router is reachable.
+func SSHExec(ctx context.Context, router *RouterConfig, op string, cmd []string) ([]string, error) {
+	bufs := make([]bytes.Buffer, len(cmd))
+	err := sshExec(ctx, router, op, cmd, func(i int) io.Writer {
+		bufs[i].Reset()
+		return &bufs[i]
+	})
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]string, len(cmd))
+	for i := range bufs {
+		ret[i] = bufs[i].String()
 	}
 	return ret, nil
 }
+
+// SSHStream runs the commands of the operation on the router and writes their output to w as it arrives.
+func SSHStream(ctx context.Context, router *RouterConfig, op string, cmd []string, w io.Writer) error {
+	return sshExec(ctx, router, op, cmd, func(int) io.Writer {
+		return w
+	})
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"log"
+	"time"
+)
+
+const (
+	defaultDialTimeout = 10 * time.Second
+	defaultExecTimeout = 2 * time.Minute
+)
+
+type TimeoutConfig struct {
+	Dial  string `yaml:"dial"`
+	Exec  string `yaml:"exec"`
+	Total string `yaml:"total"`
+}
+
+type TimeoutsConfig struct {
+	TimeoutConfig `yaml:",inline"`
+	Operations    map[string]TimeoutConfig `yaml:"operations"`
+}
+
+// Timeouts are the resolved timeouts of an operation, zero means unbounded.
+type Timeouts struct {
+	Dial  time.Duration // Dial bounds connecting and the SSH handshake.
+	Exec  time.Duration // Exec bounds every single command.
+	Total time.Duration // Total bounds the whole operation including dialing.
+}
+
+// ParseDuration parses a duration of the configuration, it returns def if s is empty, invalid or negative.
+// Zero is returned as is, callers that cannot use it must replace it.
+func ParseDuration(s string, def time.Duration) time.Duration {
+	if s == "" {
+		return def
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		log.Printf("WARNING: Failed to parse duration %q: %s, using default of %s", s, err, def)
+		return def
+	}
+	if d < 0 {
+		log.Printf("WARNING: Negative duration %q, using default of %s", s, def)
+		return def
+	}
+	return d
+}
+
+// Timeouts returns the timeouts of the operation, operation specific values override the device wide ones.
+func (rc *RouterConfig) Timeouts(op string) Timeouts {
+	ret := Timeouts{
+		Dial:  ParseDuration(rc.Timeout.Dial, defaultDialTimeout),
+		Exec:  ParseDuration(rc.Timeout.Exec, defaultExecTimeout),
+		Total: ParseDuration(rc.Timeout.Total, 0),
+	}
+	if o, ok := rc.Timeout.Operations[op]; ok {
+		ret.Dial = ParseDuration(o.Dial, ret.Dial)
+		ret.Exec = ParseDuration(o.Exec, ret.Exec)
+		ret.Total = ParseDuration(o.Total, ret.Total)
+	}
+	return ret
+}
@@ -0,0 +1,26 @@
+package utils
+
+import "time"
+
+type TracerouteHop struct {
+	Number    uint32
+	Addresses []string // Addresses holds every distinct address that responded, in order of appearance.
+	Probes    []*TracerouteProbe
+	MPLS      []*MPLSLabel
+	MTU       uint32 // MTU is set if the path MTU changed at this hop.
+}
+
+type TracerouteProbe struct {
+	Address    string
+	Hostname   string
+	RTT        time.Duration
+	Timeout    bool
+	Annotation string // Annotation holds flags such as !H or !N printed after the probe.
+}
+
+type MPLSLabel struct {
+	Label         uint32
+	TrafficClass  uint32
+	BottomOfStack bool
+	TTL           uint32
+}
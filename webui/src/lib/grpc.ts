@@ -1,11 +1,94 @@
 import { LookingGlassService } from "@as203038/lg-protobuf/lookingglass/v0/lookingglass_connect";
 export type * as Pb from "@as203038/lg-protobuf/lookingglass/v0/lookingglass_pb";
 import { createGrpcWebTransport } from "@connectrpc/connect-web";
-import { createPromiseClient, type PromiseClient } from "@connectrpc/connect";
+import {
+  Code,
+  ConnectError,
+  createPromiseClient,
+  type Interceptor,
+  type PromiseClient,
+} from "@connectrpc/connect";
 import { env } from "$env/dynamic/public";
 
 let client: PromiseClient<typeof LookingGlassService> | null = null;
 
+// leadingZeros counts the leading zero bits of a hash.
+function leadingZeros(hash: Uint8Array): number {
+  let zeros = 0;
+  for (const b of hash) {
+    if (b !== 0) {
+      return zeros + Math.clz32(b) - 24;
+    }
+    zeros += 8;
+  }
+  return zeros;
+}
+
+// solve finds a number such that the SHA-256 hash of "<challenge>:<number>"
+// starts with difficulty zero bits and returns "<challenge>:<number>".
+async function solve(challenge: string, difficulty: number): Promise<string> {
+  const encoder = new TextEncoder();
+  for (let n = 0; ; n++) {
+    const solution = `${challenge}:${n}`;
+    const hash = await crypto.subtle.digest("SHA-256", encoder.encode(solution));
+    if (leadingZeros(new Uint8Array(hash)) >= difficulty) {
+      return solution;
+    }
+  }
+}
+
+// challengeOf returns the challenge and its difficulty sent with the error of a
+// request that needs a solved challenge, undefined for other errors.
+function challengeOf(err: unknown): [string, number] | undefined {
+  if (!(err instanceof ConnectError) || err.code !== Code.Unauthenticated) {
+    return undefined;
+  }
+  const challenge = err.metadata.get("X-LG-Challenge-Issued");
+  const difficulty = Number(err.metadata.get("X-LG-Challenge-Difficulty"));
+  if (!challenge || !Number.isInteger(difficulty)) {
+    return undefined;
+  }
+  return [challenge, difficulty];
+}
+
+// challengeSolver solves the challenge the server sends with the error of a
+// request that needs one and retries the request once.
+const challengeSolver: Interceptor = (next) => async (req) => {
+  const retry = async (err: unknown) => {
+    const challenge = challengeOf(err);
+    if (!challenge) {
+      throw err;
+    }
+    req.header.set("X-LG-Challenge", await solve(...challenge));
+    return await next(req);
+  };
+  let res;
+  try {
+    res = await next(req);
+  } catch (err) {
+    return await retry(err);
+  }
+  if (!res.stream) {
+    return res;
+  }
+  // Errors of streams may only surface with their first message
+  const it = res.message[Symbol.asyncIterator]();
+  let first: IteratorResult<any>;
+  try {
+    first = await it.next();
+  } catch (err) {
+    return await retry(err);
+  }
+  return {
+    ...res,
+    message: (async function* () {
+      for (let r = first; !r.done; r = await it.next()) {
+        yield r.value;
+      }
+    })(),
+  };
+};
+
 export const LookingGlassClient = () => {
   if (!client) {
     client = createPromiseClient<typeof LookingGlassService>(
@@ -13,6 +96,7 @@ export const LookingGlassClient = () => {
       createGrpcWebTransport({
         baseUrl: env.PUBLIC_GRPC_URL,
         useBinaryFormat: true,
+        interceptors: [challengeSolver],
       }) as any,
     ); // Add 'as any' to bypass type checking
   }